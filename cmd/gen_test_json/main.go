@@ -67,7 +67,7 @@ func main() {
 		pos := xionghan.NewInitialPosition()
 		maxMoves := 500 // 一局通常没这么多步，主要是防死循环
 		for moveCount := 0; moveCount < maxMoves; moveCount++ {
-			legalMoves := pos.GenerateLegalMoves(false)
+			legalMoves := pos.GenerateLegalMoves()
 			if len(legalMoves) == 0 {
 				break
 			}
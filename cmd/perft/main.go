@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+	"xionghan/internal/xionghan"
+)
+
+func main() {
+	fen := flag.String("fen", "", "start position (default: initial position)")
+	depth := flag.Int("depth", 3, "perft depth")
+	divide := flag.Bool("divide", false, "print node counts per root move")
+	suite := flag.String("suite", "", "verify against a reference table, e.g. internal/xionghan/testdata/perft.txt")
+	flag.Parse()
+
+	if *suite != "" {
+		if !runSuite(*suite, *depth) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	pos := xionghan.NewInitialPosition()
+	if *fen != "" {
+		var err error
+		pos, err = xionghan.DecodePosition(*fen)
+		if err != nil {
+			log.Fatalf("invalid FEN: %v", err)
+		}
+	}
+
+	start := time.Now()
+	var nodes uint64
+	if *divide {
+		entries := pos.Divide(*depth)
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].Move.From != entries[j].Move.From {
+				return entries[i].Move.From < entries[j].Move.From
+			}
+			return entries[i].Move.To < entries[j].Move.To
+		})
+		for _, e := range entries {
+			fmt.Printf("%s: %d\n", xionghan.CoordinateNotation{}.FormatMove(pos, e.Move), e.Nodes)
+			nodes += e.Nodes
+		}
+		fmt.Printf("\nMoves: %d\n", len(entries))
+	} else {
+		nodes = pos.Perft(*depth)
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("Nodes: %d\nTime: %v\nNPS: %d\n", nodes, elapsed, int64(float64(nodes)/elapsed.Seconds()))
+}
+
+// runSuite 对参考表中深度不超过 maxDepth 的条目逐一校验
+func runSuite(path string, maxDepth int) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open suite: %v", err)
+	}
+	defer f.Close()
+	cases, err := xionghan.ParsePerftSuite(f)
+	if err != nil {
+		log.Fatalf("parse suite: %v", err)
+	}
+
+	ok := true
+	for i, tc := range cases {
+		pos, err := xionghan.DecodePosition(tc.FEN)
+		if err != nil {
+			fmt.Printf("#%d invalid FEN %q: %v\n", i+1, tc.FEN, err)
+			ok = false
+			continue
+		}
+		for d := 1; d <= maxDepth; d++ {
+			want, has := tc.Nodes[d]
+			if !has {
+				continue
+			}
+			got := pos.Perft(d)
+			status := "ok"
+			if got != want {
+				status = "FAIL"
+				ok = false
+			}
+			fmt.Printf("#%d D%d %d (want %d) %s\n", i+1, d, got, want, status)
+		}
+	}
+	return ok
+}
@@ -4,7 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strings"
+	"time"
 	"xionghan/internal/engine"
+	"xionghan/internal/record"
 	"xionghan/internal/xionghan"
 )
 
@@ -19,8 +23,17 @@ func main() {
 	totalGames := flag.Int("games", 10, "number of games to play")
 	abDepth := flag.Int("ab-depth", 2, "Alpha-Beta search depth")
 	mctsSims := flag.Int("mcts-sims", 2000, "MCTS simulation count")
+	noCaptureLimit := flag.Int("nocapture-limit", xionghan.DefaultNoCaptureLimit, "draw after this many plies without a capture (0 = unlimited)")
+	recordPath := flag.String("record", "", "append every game record to this file")
+	rulesName := flag.String("rules", xionghan.OnlineRules.Name, "rule set: online or training")
+	without := flag.String("without", "", "play alpha-beta against alpha-beta with these heuristics off instead of MCTS (comma-separated: pvs,aspiration,killers,history,lmr,nullmove)")
 	flag.Parse()
 
+	rules, ok := xionghan.RulesByName(*rulesName)
+	if !ok {
+		log.Fatalf("Unknown rules %q", *rulesName)
+	}
+
 	e := engine.NewEngine()
 	if err := e.InitNN(*modelPath, *libPath); err != nil {
 		log.Fatalf("Failed to initialize NN: %v", err)
@@ -33,43 +46,76 @@ func main() {
 			UseMCTS:  false,
 		},
 	}
+	playerAB.Cfg.EnableAllHeuristics()
 
-	playerMCTS := PlayerConfig{
+	opponent := PlayerConfig{
 		Name: fmt.Sprintf("MCTS (%d Sims)", *mctsSims),
 		Cfg: engine.SearchConfig{
 			UseMCTS:         true,
 			MCTSSimulations: *mctsSims,
 		},
 	}
+	if *without != "" {
+		// 同深度的 alpha-beta 关掉部分启发，用来测这些启发的 Elo
+		opponent = PlayerConfig{
+			Name: fmt.Sprintf("Alpha-Beta (Depth %d, without %s)", *abDepth, *without),
+			Cfg:  playerAB.Cfg,
+		}
+		if err := disableHeuristics(&opponent.Cfg, *without); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var recordFile *os.File
+	if *recordPath != "" {
+		f, err := os.Create(*recordPath)
+		if err != nil {
+			log.Fatalf("Failed to create record file: %v", err)
+		}
+		defer f.Close()
+		recordFile = f
+	}
 
 	abWins := 0
-	mctsWins := 0
+	oppWins := 0
 	draws := 0
 
 	for g := 0; g < *totalGames; g++ {
 		var red, black PlayerConfig
 		if g%2 == 0 {
-			red, black = playerAB, playerMCTS
+			red, black = playerAB, opponent
 		} else {
-			red, black = playerMCTS, playerAB
+			red, black = opponent, playerAB
 		}
 
 		fmt.Printf("\n=== Game %d: Red [%s] vs Black [%s] ===\n", g+1, red.Name, black.Name)
-		winner := playGame(e, red, black)
-		
-		switch winner {
-		case "Red":
+		rec := record.NewGame(red.Name, black.Name, time.Now().Format("2006.01.02"), "")
+		rec.SetTag("Event", "benchmark")
+		rec.SetTag("Rules", rules.Name)
+		rec.SetTag("Round", fmt.Sprint(g+1))
+		result := playGame(e, red, black, rules, *noCaptureLimit, rec)
+		if recordFile != nil {
+			if err := record.Write(recordFile, rec); err != nil {
+				log.Printf("Failed to write record: %v", err)
+			}
+		}
+		if result.Reason != xionghan.ReasonNone {
+			fmt.Printf("Reason: %s\n", result.Reason)
+		}
+
+		switch result.Winner {
+		case xionghan.Red:
 			if g%2 == 0 {
 				abWins++
 				fmt.Printf("Result: %s Wins!\n", playerAB.Name)
 			} else {
-				mctsWins++
-				fmt.Printf("Result: %s Wins!\n", playerMCTS.Name)
+				oppWins++
+				fmt.Printf("Result: %s Wins!\n", opponent.Name)
 			}
-		case "Black":
+		case xionghan.Black:
 			if g%2 == 0 {
-				mctsWins++
-				fmt.Printf("Result: %s Wins!\n", playerMCTS.Name)
+				oppWins++
+				fmt.Printf("Result: %s Wins!\n", opponent.Name)
 			} else {
 				abWins++
 				fmt.Printf("Result: %s Wins!\n", playerAB.Name)
@@ -82,14 +128,28 @@ func main() {
 
 	fmt.Printf("\n=== Final Score ===\n")
 	fmt.Printf("%s: %d\n", playerAB.Name, abWins)
-	fmt.Printf("%s: %d\n", playerMCTS.Name, mctsWins)
+	fmt.Printf("%s: %d\n", opponent.Name, oppWins)
 	fmt.Printf("Draws: %d\n", draws)
 }
 
-func playGame(e *engine.Engine, red, black PlayerConfig) string {
+// playGame 下完一局并把着法、结果写入 rec
+func playGame(e *engine.Engine, red, black PlayerConfig, rules *xionghan.RuleSet, noCaptureLimit int, rec *record.Game) xionghan.GameResult {
+	result := playMoves(e, red, black, rules, noCaptureLimit, rec)
+	rec.SetResult(result)
+	return result
+}
+
+func playMoves(e *engine.Engine, red, black PlayerConfig, rules *xionghan.RuleSet, noCaptureLimit int, rec *record.Game) xionghan.GameResult {
 	pos := xionghan.NewInitialPosition()
+	pos.Rules = rules
 	maxMoves := 400 // 防止死循环
-	
+	// 双方各用一个引擎，置换表不共用
+	engines := [2]*engine.Engine{e.CloneForGame(), e.CloneForGame()}
+	history := &xionghan.GameHistory{
+		HashCount:      map[uint64]int{pos.EnsureHash(): 1},
+		NoCaptureLimit: noCaptureLimit,
+	}
+
 	for i := 0; i < maxMoves; i++ {
 		var currentCfg engine.SearchConfig
 		if pos.SideToMove == xionghan.Red {
@@ -98,29 +158,52 @@ func playGame(e *engine.Engine, red, black PlayerConfig) string {
 			currentCfg = black.Cfg
 		}
 
-		res := e.Search(pos, currentCfg)
+		res := engines[pos.SideToMove].Search(pos, currentCfg)
 		if res.BestMove.From == 0 && res.BestMove.To == 0 {
-			// 无子可动，当前方输
+			// 引擎给不出走法，按无子可动处理，当前方输
+			winner := xionghan.Red
+			status := xionghan.StatusRedWin
 			if pos.SideToMove == xionghan.Red {
-				return "Black"
+				winner, status = xionghan.Black, xionghan.StatusBlackWin
 			}
-			return "Red"
+			return xionghan.GameResult{Status: status, Winner: winner, Reason: xionghan.ReasonNoLegalMoves}
 		}
 
 		nextPos, ok := pos.ApplyMove(res.BestMove)
 		if !ok {
-			fmt.Printf("Error: invalid move %v\n", res.BestMove)
-			return "Error"
+			fmt.Printf("Error: invalid move %s\n", xionghan.CoordinateNotation{}.FormatMove(pos, res.BestMove))
+			return xionghan.GameResult{Status: xionghan.StatusDraw, Winner: xionghan.NoSide}
 		}
 		pos = nextPos
+		history.HashCount[pos.EnsureHash()]++
+		rec.AddMoveWithEval(res.BestMove, res.Score, "")
 
-		// 检查吃王
-		if !pos.KingExists(xionghan.Red) {
-			return "Black"
+		if result := pos.Outcome(history); result.IsOver() {
+			return result
 		}
-		if !pos.KingExists(xionghan.Black) {
-			return "Red"
+	}
+	return xionghan.GameResult{Status: xionghan.StatusDraw, Winner: xionghan.NoSide, Reason: xionghan.ReasonMoveLimit}
+}
+
+// disableHeuristics 按名字关掉 alpha-beta 启发
+func disableHeuristics(cfg *engine.SearchConfig, names string) error {
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "pvs":
+			cfg.EnablePVS = false
+		case "aspiration":
+			cfg.EnableAspiration = false
+		case "killers":
+			cfg.EnableKillers = false
+		case "history":
+			cfg.EnableHistory = false
+		case "lmr":
+			cfg.EnableLMR = false
+		case "nullmove":
+			cfg.EnableNullMove = false
+		default:
+			return fmt.Errorf("unknown heuristic %q", name)
 		}
 	}
-	return "Draw"
+	return nil
 }
@@ -7,8 +7,10 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"strings"
 	"time"
 	"xionghan/internal/engine"
+	"xionghan/internal/record"
 	"xionghan/internal/xionghan"
 )
 
@@ -17,8 +19,16 @@ func main() {
 	libPath := flag.String("lib", "onnxruntime.dll", "path to onnxruntime.dll")
 	depth := flag.Int("depth", 4, "search depth")
 	maxMoves := flag.Int("maxmoves", 20, "max moves to play")
+	noCaptureLimit := flag.Int("nocapture-limit", xionghan.DefaultNoCaptureLimit, "draw after this many plies without a capture (0 = unlimited)")
+	recordPath := flag.String("record", "", "write the game record to this file")
+	rulesName := flag.String("rules", xionghan.OnlineRules.Name, "rule set: online or training")
 	flag.Parse()
 
+	rules, ok := xionghan.RulesByName(*rulesName)
+	if !ok {
+		log.Fatalf("Unknown rules %q", *rulesName)
+	}
+
 	// Start pprof for profiling
 	go func() {
 		log.Println("pprof listening on :6060")
@@ -34,14 +44,27 @@ func main() {
 	}
 
 	pos := xionghan.NewInitialPosition()
-	
+	pos.Rules = rules
+	history := &xionghan.GameHistory{
+		HashCount:      map[uint64]int{pos.EnsureHash(): 1},
+		NoCaptureLimit: *noCaptureLimit,
+	}
+	player := fmt.Sprintf("xionghan depth %d", *depth)
+	rec := record.NewGame(player, player, time.Now().Format("2006.01.02"), "")
+	rec.SetTag("Event", "selfplay")
+	rec.SetTag("Rules", rules.Name)
+	rec.SetTag("Engine", fmt.Sprintf("model=%s depth=%d", *modelPath, *depth))
+
 	for i := 0; i < *maxMoves; i++ {
 		log.Printf("--- Move %d, Side: %v ---", i+1, pos.SideToMove)
 		
 		start := time.Now()
-		res := e.Search(pos, engine.SearchConfig{
+		cfg := engine.SearchConfig{
 			MaxDepth: *depth,
-		})
+		}
+		cfg.EnableAllHeuristics()
+		cfg.Listener = printSearchInfo
+		res := e.Search(pos, cfg)
 		duration := time.Since(start)
 
 		if res.BestMove.From == 0 && res.BestMove.To == 0 {
@@ -49,21 +72,58 @@ func main() {
 			break
 		}
 
-		fmt.Printf("BestMove: %v, Score: %d, Nodes: %d, Time: %v, NPS: %d\n", 
-			res.BestMove, res.Score, res.Nodes, duration, int64(float64(res.Nodes)/duration.Seconds()))
+		fmt.Printf("BestMove: %s (%s), Score: %d, Nodes: %d, Time: %v, NPS: %d\n",
+			xionghan.ChineseNotation{}.FormatMove(pos, res.BestMove),
+			xionghan.CoordinateNotation{}.FormatMove(pos, res.BestMove), res.Score, res.Nodes, duration, int64(float64(res.Nodes)/duration.Seconds()))
+		if len(res.PV) > 0 {
+			fmt.Printf("PV (%d): %s, PV score: %d\n", len(res.PV), formatPV(res.PV), res.PVScore)
+		}
 
 		newPos, ok := pos.ApplyMove(res.BestMove)
 		if !ok {
-			log.Fatalf("Failed to apply move %v", res.BestMove)
+			log.Fatalf("Failed to apply move %s", xionghan.CoordinateNotation{}.FormatMove(pos, res.BestMove))
 		}
 		pos = newPos
-		
-		if !pos.KingExists(xionghan.Red) || !pos.KingExists(xionghan.Black) {
-			log.Printf("Game over: king captured.")
+		history.HashCount[pos.EnsureHash()]++
+		rec.AddMoveWithEval(res.BestMove, res.Score, fmt.Sprintf("depth %d", res.Depth))
+
+		if result := pos.Outcome(history); result.IsOver() {
+			log.Printf("Game over: %s (%s).", result.Status, result.Reason)
+			rec.SetResult(result)
 			break
 		}
 	}
-	
+
+	if *recordPath != "" {
+		if err := os.WriteFile(*recordPath, []byte(rec.String()), 0o644); err != nil {
+			log.Fatalf("Failed to write record: %v", err)
+		}
+		log.Printf("Record written to %s", *recordPath)
+	}
+
 	log.Println("Selfplay finished.")
 	os.Exit(0)
 }
+
+// printSearchInfo 打印搜索进度：alpha-beta 每层一行，MCTS 另列访问最多的根着法
+func printSearchInfo(info engine.SearchInfo) {
+	if !info.MCTS {
+		fmt.Printf("  depth %d score %d nodes %d nps %d time %v pv %s\n",
+			info.Depth, info.Score, info.Nodes, info.NPS, info.TimeUsed.Round(time.Millisecond), formatPV(info.PV))
+		return
+	}
+	top := make([]string, 0, len(info.TopMoves))
+	for _, m := range info.TopMoves {
+		top = append(top, fmt.Sprintf("%s %d %.1f%%", xionghan.CoordinateNotation{}.FormatMove(nil, m.Move), m.Visits, m.WinProb*100))
+	}
+	fmt.Printf("  visits %d red %.1f%% nps %d time %v top [%s] pv %s\n",
+		info.Nodes, info.WinProb*100, info.NPS, info.TimeUsed.Round(time.Millisecond), strings.Join(top, ", "), formatPV(info.PV))
+}
+
+func formatPV(pv []xionghan.Move) string {
+	parts := make([]string, 0, len(pv))
+	for _, mv := range pv {
+		parts = append(parts, xionghan.CoordinateNotation{}.FormatMove(nil, mv))
+	}
+	return strings.Join(parts, " ")
+}
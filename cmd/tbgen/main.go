@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"xionghan/internal/tablebase"
+	"xionghan/internal/xionghan"
+)
+
+func main() {
+	sigs := flag.String("sig", "", "comma-separated material signatures, red first, FEN letters with kings, e.g. EAvED,EFGvE")
+	out := flag.String("out", "tablebases", "output directory")
+	rulesName := flag.String("rules", xionghan.OnlineRules.Name, "rule set: online or training")
+	workers := flag.Int("workers", 0, "worker goroutines (0 = all CPUs)")
+	verbose := flag.Bool("v", false, "print progress for every ply")
+	flag.Parse()
+
+	if *sigs == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	rules, ok := xionghan.RulesByName(*rulesName)
+	if !ok {
+		log.Fatalf("unknown rules %q", *rulesName)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	g := tablebase.NewGenerator(rules)
+	g.Workers = *workers
+	if *verbose {
+		g.Logf = log.Printf
+	}
+	for _, s := range strings.Split(*sigs, ",") {
+		sig, err := tablebase.ParseSignature(strings.TrimSpace(s))
+		if err != nil {
+			log.Fatal(err)
+		}
+		start := time.Now()
+		if _, err := g.Generate(sig); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%s generated in %v", sig, time.Since(start).Round(time.Millisecond))
+	}
+
+	// 子表也一起写出：查表时吃子后的局面要用到
+	for _, t := range g.Tables() {
+		if err := t.Save(*out); err != nil {
+			log.Fatal(err)
+		}
+		wins, draws, losses := t.Stats()
+		fmt.Printf("%-24s %10d positions  win %d  draw %d  loss %d\n", tablebase.FileName(t.Rules, t.Sig), t.Size(), wins, draws, losses)
+	}
+}
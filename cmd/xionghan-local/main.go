@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"xionghan/internal/engine"
 	httpserver "xionghan/internal/server/http"
 )
 
@@ -71,6 +72,8 @@ func main() {
 	webMobileDir := flag.String("web-mobile", "./web_mobile", "directory with mobile index.html / js / svg")
 	modelPath := flag.String("model", "xionghan.onnx", "path to ONNX model file")
 	libPath := flag.String("lib", "onnxruntime.dll", "path to onnxruntime.dll")
+	tbDir := flag.String("tb", "", "directory with endgame tablebases (*.xtb), empty to disable")
+	hashMB := flag.Int("hash", engine.DefaultHashMB, "transposition table size per game in MB")
 	flag.Parse()
 
 	mux := http.NewServeMux()
@@ -93,6 +96,15 @@ func main() {
 		}
 	}
 
+	h.Engine().SetHashSize(*hashMB)
+
+	if *tbDir != "" {
+		if err := h.Engine().LoadTablebases(*tbDir); err != nil {
+			log.Fatalf("Failed to load tablebases: %v", err)
+		}
+		log.Printf("Loaded %d tablebases from %s", h.Engine().Tablebases.Len(), *tbDir)
+	}
+
 	mux.Handle("/api/", h)
 	httpserver.RegisterStaticRoutes(mux, *webDir, *webMobileDir)
 
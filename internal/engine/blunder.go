@@ -21,23 +21,20 @@ const (
 	blunderReplySalt uint64 = 0xc2b2ae3d27d4eb4f
 )
 
-// FilterBlunderMoves 过滤“纯送子”弱智步
-func (e *Engine) FilterBlunderMoves(pos *xionghan.Position, moves []xionghan.Move) []xionghan.Move {
-	if len(moves) <= 1 {
-		return moves
-	}
+// BlunderFilter 过滤“纯送子”弱智步：静态交换评估（SEE）为负，且对方吃掉后我方没有将军
+type BlunderFilter struct{}
+
+func (BlunderFilter) Name() string { return "blunder" }
 
+func (f BlunderFilter) Apply(e *Engine, pos *xionghan.Position, moves []xionghan.Move, report *FilterReport) []xionghan.Move {
 	safeMoves := make([]xionghan.Move, 0, len(moves))
 	for _, mv := range moves {
 		if e.shouldPruneBlunderMove(pos, mv) {
+			report.remove(f.Name(), mv, "loses material in the exchange with no check in return")
 			continue
 		}
 		safeMoves = append(safeMoves, mv)
 	}
-
-	if len(safeMoves) == 0 {
-		return moves
-	}
 	return safeMoves
 }
 
@@ -71,47 +68,35 @@ func (e *Engine) computeBlunderPrune(pos *xionghan.Position, mv xionghan.Move) b
 	if moving == 0 || moving.Side() != pos.SideToMove {
 		return false
 	}
-	if !isBlunderFilterPiece(moving.Type()) {
+	// 王走到被攻击的格子由启发式过滤的“送将”一条管
+	if moving.Type() == xionghan.PieceKing {
 		return false
 	}
-
-	target := pos.Board.Squares[mv.To]
-	if target != 0 {
+	if SEE(pos, mv) >= 0 {
 		return false
 	}
 
-	nextPos, ok := pos.ApplyMove(mv)
-	if !ok {
+	if !pos.MakeMove(mv) {
 		return false
 	}
-	if nextPos.IsInCheck(nextPos.SideToMove) {
+	defer pos.UnmakeMove()
+	if pos.IsInCheck(pos.SideToMove) {
 		return false
 	}
 
-	oppMoves := nextPos.GenerateLegalMoves(false)
-	for _, reply := range oppMoves {
-		if reply.To != mv.To {
-			continue
-		}
-		attacker := nextPos.Board.Squares[reply.From]
-		if attacker == 0 || attacker.Side() != nextPos.SideToMove {
-			continue
-		}
-
-		afterCapture, ok := nextPos.ApplyMove(reply)
-		if !ok {
-			continue
-		}
-		if !e.hasRecaptureOrCheck(afterCapture, mv.To) {
-			return true
-		}
+	// 交换会亏子：对方先用最便宜的子吃掉，我方若有将军（或吃王）可以反击就不算纯送子
+	from := leastValuableAttacker(pos, mv.To, pos.SideToMove)
+	if from < 0 || !pos.MakeMove(xionghan.Move{From: from, To: mv.To}) {
+		return false
 	}
-
-	return false
+	hasComp := e.hasCheckReply(pos)
+	pos.UnmakeMove()
+	return !hasComp
 }
 
-func (e *Engine) hasRecaptureOrCheck(pos *xionghan.Position, targetSq int) bool {
-	key := blunderReplyKey(pos, targetSq)
+// hasCheckReply 轮走方能否将军或直接吃王
+func (e *Engine) hasCheckReply(pos *xionghan.Position) bool {
+	key := blunderReplyKey(pos)
 	mask := uint64(len(e.blunderReplyTT) - 1)
 	idx := key & mask
 
@@ -120,31 +105,7 @@ func (e *Engine) hasRecaptureOrCheck(pos *xionghan.Position, targetSq int) bool
 		return uint8(entry&0xFF) == blunderReplyHasComp
 	}
 
-	moves := pos.GenerateLegalMoves(false)
-	hasComp := false
-	for _, mv := range moves {
-		if mv.To == targetSq {
-			dst := pos.Board.Squares[targetSq]
-			if dst != 0 && dst.Side() != pos.SideToMove {
-				hasComp = true
-				break
-			}
-		}
-
-		after, ok := pos.ApplyMove(mv)
-		if !ok {
-			continue
-		}
-		target := pos.Board.Squares[mv.To]
-		if target != 0 && target.Type() == xionghan.PieceKing {
-			hasComp = true
-			break
-		}
-		if after.IsInCheck(after.SideToMove) {
-			hasComp = true
-			break
-		}
-	}
+	hasComp := len(pos.GenerateChecks()) > 0
 
 	// 存入 (无锁写入)
 	val := blunderReplyNoComp
@@ -153,17 +114,8 @@ func (e *Engine) hasRecaptureOrCheck(pos *xionghan.Position, targetSq int) bool
 	}
 	newEntry := (key & 0xFFFFFFFFFFFFFF00) | uint64(val)
 	atomic.StoreUint64(&e.blunderReplyTT[idx], newEntry)
-	
-	return hasComp
-}
 
-func isBlunderFilterPiece(pt xionghan.PieceType) bool {
-	switch pt {
-	case xionghan.PieceRook, xionghan.PieceCannon, xionghan.PieceKnight, xionghan.PieceLei, xionghan.PiecePawn:
-		return true
-	default:
-		return false
-	}
+	return hasComp
 }
 
 func blunderMoveKey(pos *xionghan.Position, mv xionghan.Move) uint64 {
@@ -171,6 +123,6 @@ func blunderMoveKey(pos *xionghan.Position, mv xionghan.Move) uint64 {
 	return hashPosition(pos) ^ blunderMoveSalt ^ (moveBits * 0x9ddfea08eb382d69)
 }
 
-func blunderReplyKey(pos *xionghan.Position, targetSq int) uint64 {
-	return hashPosition(pos) ^ blunderReplySalt ^ (uint64(targetSq+1) * 0x517cc1b727220a95)
+func blunderReplyKey(pos *xionghan.Position) uint64 {
+	return hashPosition(pos) ^ blunderReplySalt
 }
@@ -3,6 +3,9 @@ package engine
 import (
 	"sync"
 	"sync/atomic"
+
+	"xionghan/internal/tablebase"
+	"xionghan/internal/xionghan"
 )
 
 const nnEvalCacheCap = 500_000
@@ -13,9 +16,26 @@ type nnEvalCache struct {
 }
 
 type Engine struct {
-	tt    map[uint64]ttEntry // TT 移到 tt.go定义
+	tt    *TransTable // 共享置换表（tt.go），对局内跨步保留
 	nodes int64
 
+	// 静态搜索：设置在每次 Search 开始时按 SearchConfig 更新；qnodes 单独计数
+	qs     qsSettings
+	qnodes int64
+
+	// 搜索线程数（每次 Search 按 SearchConfig 更新）；stop 由主线程置位，叫停辅助线程
+	threads int
+	stop    *uint32
+
+	// 取消标志：Stop 或 SearchContext 的 ctx 取消时置位，所有搜索线程共享
+	cancel *uint32
+
+	// alpha-beta 启发开关（每次 Search 更新）；杀手着法、历史分、空着标记是每个搜索线程自己的
+	heur    searchHeuristics
+	killers [][2]xionghan.Move
+	history *[2][xionghan.NumSquares][xionghan.NumSquares]int32
+	inNull  bool
+
 	// 无锁置换表：使用固定大小数组代替 map
 	blunderTT      []uint64
 	blunderReplyTT []uint64
@@ -23,25 +43,43 @@ type Engine struct {
 	UseNN bool
 	nn    *NNEvaluator
 
+	// 着法过滤管线：Filters 用于搜索主干，QuickFilters 用于 VCF 和 MCTS 内部节点
+	Filters      FilterPipeline
+	QuickFilters FilterPipeline
+
 	// Shared per-search abort flag. Set to 1 when any NN eval fails.
 	nnAbort *uint32
 
 	// Shared NN value cache keyed by position hash.
 	nnCache *nnEvalCache
 
-	// MCTS 持久化状态
-	mctsRoot *MCTSNode
-	mctsPool map[uint64]*MCTSNode
-	poolMu   sync.Mutex
+	// 残局库（可以为 nil），命中时直接给出精确胜负
+	Tablebases *tablebase.Prober
+
+	// MCTS 持久化状态（mcts_tree.go）：mctsNodes 是树的节点数，mctsGen 每次 MCTS 搜索加一
+	mctsRoot  *MCTSNode
+	mctsPool  map[uint64]*MCTSNode
+	poolMu    sync.Mutex
+	mctsNodes int64
+	mctsGen   uint32
+
+	// 后台思考（ponder.go）：ponder 是正在进行的，lastPonder 是刚停下、等 PonderMove 处理的
+	ponderMu   sync.Mutex
+	ponder     *ponderState
+	lastPonder *ponderState
 }
 
 func NewEngine() *Engine {
 	abort := uint32(0)
+	cancel := uint32(0)
 	return &Engine{
-		tt:             make(map[uint64]ttEntry, 1<<18),
+		tt:             NewTransTable(DefaultHashMB),
 		blunderTT:      make([]uint64, 1<<18),
 		blunderReplyTT: make([]uint64, 1<<18),
 		nnAbort:        &abort,
+		cancel:         &cancel,
+		Filters:        DefaultFilters(),
+		QuickFilters:   DefaultQuickFilters(),
 		nnCache: &nnEvalCache{
 			m: make(map[uint64]int, 1<<18),
 		},
@@ -55,8 +93,14 @@ func (e *Engine) CloneForGame() *Engine {
 	if e == nil {
 		return cloned
 	}
+	if size := e.tt.SizeMB(); size != cloned.tt.SizeMB() {
+		cloned.tt = NewTransTable(size)
+	}
 	cloned.UseNN = e.UseNN
 	cloned.nn = e.nn
+	cloned.Filters = e.Filters
+	cloned.QuickFilters = e.QuickFilters
+	cloned.Tablebases = e.Tablebases
 	return cloned
 }
 
@@ -70,6 +114,21 @@ func (e *Engine) InitNN(modelPath, libPath string) error {
 	return nil
 }
 
+// SetHashSize 按大小（MB）重新分配置换表，原有条目丢弃；CloneForGame 出来的引擎沿用该大小
+func (e *Engine) SetHashSize(mb int) {
+	e.tt = NewTransTable(mb)
+}
+
+// LoadTablebases 加载目录下的全部残局库文件（由 cmd/tbgen 生成）
+func (e *Engine) LoadTablebases(dir string) error {
+	p, err := tablebase.LoadDir(dir)
+	if err != nil {
+		return err
+	}
+	e.Tablebases = p
+	return nil
+}
+
 func (e *Engine) resetNNAbort() {
 	if e.nnAbort == nil {
 		abort := uint32(0)
@@ -90,6 +149,26 @@ func (e *Engine) hasNNFailure() bool {
 	return e.nnAbort != nil && atomic.LoadUint32(e.nnAbort) != 0
 }
 
+// Stop 叫停正在进行的搜索（可以在其它 goroutine 调用）；Search 尽快返回已完成部分的结果
+func (e *Engine) Stop() {
+	if e.cancel != nil {
+		atomic.StoreUint32(e.cancel, 1)
+	}
+}
+
+func (e *Engine) resetCancel() {
+	if e.cancel == nil {
+		cancel := uint32(0)
+		e.cancel = &cancel
+	}
+	atomic.StoreUint32(e.cancel, 0)
+}
+
+// cancelled 本次搜索是否已被取消
+func (e *Engine) cancelled() bool {
+	return e.cancel != nil && atomic.LoadUint32(e.cancel) != 0
+}
+
 func (e *Engine) getNNEvalFromCache(key uint64) (int, bool) {
 	if e.nnCache == nil {
 		return 0, false
@@ -0,0 +1,109 @@
+package engine
+
+import "xionghan/internal/xionghan"
+
+// HeuristicFilter 开局与送子启发式（原先写在 GenerateLegalMoves(isAI) 里）。
+// 各阈值都是盘面总子数，0 表示关闭该条。
+type HeuristicFilter struct {
+	NoBackwardAbove   int  // 多于此数时，车马炮檑不后退
+	NoHangingAbove    int  // 多于此数时，不走交换会亏子的着法
+	NoKingAdvisorFrom int  // 不少于此数且未被将时，不动王和士
+	NoPawnTradeAbove  int  // 多于此数时，不把子送到兵口上换亏
+	NoSelfCheck       bool // 不主动送将（只剩王时除外）
+}
+
+// DefaultHeuristicFilter 默认阈值
+var DefaultHeuristicFilter = HeuristicFilter{
+	NoBackwardAbove:   42,
+	NoHangingAbove:    42,
+	NoKingAdvisorFrom: 44,
+	NoPawnTradeAbove:  30,
+	NoSelfCheck:       true,
+}
+
+func (HeuristicFilter) Name() string { return "heuristic" }
+
+func (f HeuristicFilter) Apply(_ *Engine, pos *xionghan.Position, moves []xionghan.Move, report *FilterReport) []xionghan.Move {
+	side := pos.SideToMove
+	opp := oppositeSide(side)
+
+	totalPieces, myPieceCount := 0, 0
+	for _, pc := range pos.Board.Squares {
+		if pc != 0 {
+			totalPieces++
+			if pc.Side() == side {
+				myPieceCount++
+			}
+		}
+	}
+	currentlyInCheck := pos.IsInCheck(side)
+
+	// 在副本上走子，不改动 pos（pos 可能正被别的 goroutine 读取）
+	np := pos.Clone()
+	out := make([]xionghan.Move, 0, len(moves))
+	for _, mv := range moves {
+		// 直接吃王的走法一律保留
+		target := pos.Board.Squares[mv.To]
+		if target != 0 && target.Type() == xionghan.PieceKing {
+			out = append(out, mv)
+			continue
+		}
+		if !np.MakeMove(mv) {
+			continue
+		}
+		reason := f.reject(pos, np, mv, target, side, opp, totalPieces, myPieceCount, currentlyInCheck)
+		np.UnmakeMove()
+		if reason != "" {
+			report.remove(f.Name(), mv, reason)
+			continue
+		}
+		out = append(out, mv)
+	}
+	return out
+}
+
+// reject 返回过滤原因，空串表示保留；np 是走完 mv 之后的局面
+func (f HeuristicFilter) reject(pos, np *xionghan.Position, mv xionghan.Move, target xionghan.Piece, side, opp xionghan.Side, totalPieces, myPieceCount int, currentlyInCheck bool) string {
+	pt := pos.Board.Squares[mv.From].Type()
+
+	// ① 开局方向限制：子力很多时，四类大子不搜索“反方向”走法。
+	if f.NoBackwardAbove > 0 && totalPieces > f.NoBackwardAbove {
+		if pt == xionghan.PieceKnight || pt == xionghan.PieceCannon || pt == xionghan.PieceRook || pt == xionghan.PieceLei {
+			fromRow, toRow := mv.From/xionghan.Cols, mv.To/xionghan.Cols
+			if (side == xionghan.Red && toRow > fromRow) || (side == xionghan.Black && toRow < fromRow) {
+				return "backward move in the opening"
+			}
+		}
+	}
+
+	// loss 为走子方在 mv.To 上交换的净得失（SEE），② ⑤ 共用；王不参与（由 ④ 管）
+	hanging := f.NoHangingAbove > 0 && totalPieces > f.NoHangingAbove
+	pawnTrade := f.NoPawnTradeAbove > 0 && totalPieces > f.NoPawnTradeAbove
+	loss := 0
+	if pt != xionghan.PieceKing && (hanging || pawnTrade) {
+		loss = seeValue(target) - seeExchange(np, mv.To)
+	}
+
+	// ② 开局额外过滤：子力很多时，不搜索“走过去之后在交换中亏子”的走法。
+	if hanging && loss < 0 {
+		return "loses material on the destination square in the opening"
+	}
+
+	// ③ 开局限制：禁止 AI 在早期乱动王和士
+	if f.NoKingAdvisorFrom > 0 && totalPieces >= f.NoKingAdvisorFrom && !currentlyInCheck {
+		if pt == xionghan.PieceKing || pt == xionghan.PieceAdvisor {
+			return "king or advisor move in the opening"
+		}
+	}
+
+	// ④ 送王拦截：禁止主动送将（除非只剩下王）
+	if f.NoSelfCheck && myPieceCount > 1 && np.IsInCheck(side) {
+		return "leaves own king in check"
+	}
+
+	// ⑤ 避免弱智送子：走到对方兵口上，交换下来亏子
+	if pawnTrade && loss < 0 && np.IsAttackedByPawn(mv.To, opp) {
+		return "loses material to a pawn"
+	}
+	return ""
+}
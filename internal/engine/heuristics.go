@@ -0,0 +1,228 @@
+package engine
+
+import (
+	"sort"
+
+	"xionghan/internal/xionghan"
+)
+
+const (
+	// 空着剪枝：至少剩这么多层才试，减去 nullMoveReduction（深度够时再多减一层）
+	nullMoveMinDepth  = 3
+	nullMoveReduction = 2
+
+	// 后期着法减层：至少剩 lmrMinDepth 层、前 lmrFullMoves 个着法不减；
+	// NN 策略概率低于 lmrLowPrior 的再多减一层
+	lmrMinDepth  = 3
+	lmrFullMoves = 3
+	lmrLowPrior  = 0.01
+
+	// 期望窗口：上一层分数 ± aspirationDelta，落在窗口外时放宽到 4 倍，超过 aspirationMaxDelta 用全窗口
+	aspirationDelta    = 250
+	aspirationMaxDelta = 4000
+
+	// 分数绝对值超过它视为已分胜负（吃王、VCF、残局库），不做空着和期望窗口
+	decisiveScore = 700000
+
+	maxKillerPly = 64
+)
+
+// searchHeuristics alpha-beta 的剪枝和排序开关，由 SearchConfig 得到
+type searchHeuristics struct {
+	pvs        bool
+	aspiration bool
+	killers    bool
+	history    bool
+	lmr        bool
+	nullMove   bool
+}
+
+func newSearchHeuristics(cfg SearchConfig) searchHeuristics {
+	return searchHeuristics{
+		pvs:        cfg.EnablePVS,
+		aspiration: cfg.EnableAspiration,
+		killers:    cfg.EnableKillers,
+		history:    cfg.EnableHistory,
+		lmr:        cfg.EnableLMR,
+		nullMove:   cfg.EnableNullMove,
+	}
+}
+
+// EnableAllHeuristics 打开全部剪枝和排序启发（PVS、期望窗口、杀手、历史、LMR、空着）
+func (cfg *SearchConfig) EnableAllHeuristics() {
+	cfg.EnablePVS = true
+	cfg.EnableAspiration = true
+	cfg.EnableKillers = true
+	cfg.EnableHistory = true
+	cfg.EnableLMR = true
+	cfg.EnableNullMove = true
+}
+
+// policyPriors 两阶段 NN 策略给出每个着法的先验概率：P(from) * P(to | from)。
+// 同一 from 的着法共用一次第二阶段推理。推理失败时标记 NN 故障并返回 false。
+func (e *Engine) policyPriors(pos *xionghan.Position, moves []xionghan.Move) ([]float32, bool) {
+	res0, err := e.nn.EvaluateWithStage(pos, 0, -1)
+	if err != nil || res0 == nil {
+		e.markNNFailure()
+		return nil, false
+	}
+	fromGroups := make(map[int][]int) // From -> indices in moves
+	for i, mv := range moves {
+		fromGroups[mv.From] = append(fromGroups[mv.From], i)
+	}
+
+	type stage1Result struct {
+		from int
+		res  *NNResult
+		err  error
+	}
+	stage1Ch := make(chan stage1Result, len(fromGroups))
+	for from := range fromGroups {
+		from := from
+		go func() {
+			res1, err := e.nn.EvaluateWithStage(pos, 1, from)
+			stage1Ch <- stage1Result{from: from, res: res1, err: err}
+		}()
+	}
+	priors := make([]float32, len(moves))
+	failed := false
+	for i := 0; i < len(fromGroups); i++ {
+		r := <-stage1Ch
+		if r.err != nil || r.res == nil {
+			failed = true
+			continue
+		}
+		fromProb := res0.Policy[r.from]
+		for _, idx := range fromGroups[r.from] {
+			priors[idx] = fromProb * r.res.Policy[moves[idx].To]
+		}
+	}
+	if failed {
+		e.markNNFailure()
+		return nil, false
+	}
+	return priors, true
+}
+
+// sortMovesByPriors 按先验概率从大到小排序（priors 与 moves 一起重排）
+func sortMovesByPriors(moves []xionghan.Move, priors []float32) {
+	sort.Stable(movesByPrior{moves, priors})
+}
+
+type movesByPrior struct {
+	moves  []xionghan.Move
+	priors []float32
+}
+
+func (m movesByPrior) Len() int           { return len(m.moves) }
+func (m movesByPrior) Less(i, j int) bool { return m.priors[i] > m.priors[j] }
+func (m movesByPrior) Swap(i, j int) {
+	m.moves[i], m.moves[j] = m.moves[j], m.moves[i]
+	m.priors[i], m.priors[j] = m.priors[j], m.priors[i]
+}
+
+// orderQuietMoves 吃子保持在前、顺序不变；不吃子的按杀手着法、NN 先验（有的话）、历史分排序。
+// priors 可以为 nil，否则与 moves 一起重排。
+func (e *Engine) orderQuietMoves(pos *xionghan.Position, moves []xionghan.Move, priors []float32, ply int) {
+	keys := make([]int64, len(moves))
+	for i, mv := range moves {
+		switch {
+		case pos.Board.Squares[mv.To] != 0:
+			keys[i] = 3 << 40
+		case e.isKiller(mv, ply) == 1:
+			keys[i] = 2<<40 + 1
+		case e.isKiller(mv, ply) == 2:
+			keys[i] = 2 << 40
+		case priors != nil:
+			keys[i] = int64(priors[i] * (1 << 30))
+		case e.heur.history && e.history != nil:
+			keys[i] = int64(e.history[pos.SideToMove][mv.From][mv.To])
+		}
+	}
+	sort.Stable(movesByKey{moves, priors, keys})
+}
+
+type movesByKey struct {
+	moves  []xionghan.Move
+	priors []float32
+	keys   []int64
+}
+
+func (m movesByKey) Len() int           { return len(m.moves) }
+func (m movesByKey) Less(i, j int) bool { return m.keys[i] > m.keys[j] }
+func (m movesByKey) Swap(i, j int) {
+	m.moves[i], m.moves[j] = m.moves[j], m.moves[i]
+	m.keys[i], m.keys[j] = m.keys[j], m.keys[i]
+	if m.priors != nil {
+		m.priors[i], m.priors[j] = m.priors[j], m.priors[i]
+	}
+}
+
+// isKiller 是第几个杀手着法（1 或 2），不是返回 0
+func (e *Engine) isKiller(mv xionghan.Move, ply int) int {
+	if !e.heur.killers || e.killers == nil || ply >= maxKillerPly {
+		return 0
+	}
+	switch mv {
+	case e.killers[ply][0]:
+		return 1
+	case e.killers[ply][1]:
+		return 2
+	}
+	return 0
+}
+
+// recordCutoff 不吃子的着法引起截断：记为杀手着法并加历史分
+func (e *Engine) recordCutoff(side xionghan.Side, mv xionghan.Move, ply, depth int) {
+	if e.heur.killers && ply < maxKillerPly {
+		if e.killers == nil {
+			e.killers = make([][2]xionghan.Move, maxKillerPly)
+		}
+		if e.killers[ply][0] != mv {
+			e.killers[ply][1] = e.killers[ply][0]
+			e.killers[ply][0] = mv
+		}
+	}
+	if e.heur.history {
+		if e.history == nil {
+			e.history = new([2][xionghan.NumSquares][xionghan.NumSquares]int32)
+		}
+		h := &e.history[side][mv.From][mv.To]
+		*h += int32(depth * depth)
+		if *h > 1<<24 {
+			// 防止溢出：整体减半
+			for s := range e.history {
+				for f := range e.history[s] {
+					for t := range e.history[s][f] {
+						e.history[s][f][t] /= 2
+					}
+				}
+			}
+		}
+	}
+}
+
+// lmrReduction 后期不吃子着法的减层数；prior 为负表示没有 NN 先验
+func lmrReduction(depth, searched int, prior float32) int {
+	if depth < lmrMinDepth || searched < lmrFullMoves {
+		return 0
+	}
+	r := 1
+	if prior >= 0 && prior < lmrLowPrior && depth >= lmrMinDepth+1 {
+		r++
+	}
+	return r
+}
+
+// hasNullMoveMaterial 空着剪枝的保护：只剩王、士、相、兵时容易出现“谁走谁亏”，不做空着
+func hasNullMoveMaterial(pos *xionghan.Position, side xionghan.Side) bool {
+	for _, pt := range []xionghan.PieceType{
+		xionghan.PieceRook, xionghan.PieceKnight, xionghan.PieceCannon,
+		xionghan.PieceLei, xionghan.PieceFeng, xionghan.PieceWei,
+	} {
+		if !pos.PieceBitboard(side, pt).IsZero() {
+			return true
+		}
+	}
+	return false
+}
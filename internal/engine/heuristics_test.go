@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"xionghan/internal/tablebase"
+	"xionghan/internal/xionghan"
+)
+
+func TestOrderQuietMovesKillersAndHistory(t *testing.T) {
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(6, 0): red(xionghan.PieceRook),
+		sq(3, 0): black(xionghan.PiecePawn),
+	})
+	capture := xionghan.Move{From: sq(6, 0), To: sq(3, 0)}
+	quiet := []xionghan.Move{
+		{From: sq(6, 0), To: sq(6, 1)},
+		{From: sq(6, 0), To: sq(6, 2)},
+		{From: sq(6, 0), To: sq(6, 3)},
+		{From: sq(6, 0), To: sq(6, 4)},
+	}
+
+	e := NewEngine()
+	e.heur = newSearchHeuristics(SearchConfig{EnableKillers: true, EnableHistory: true})
+	const ply = 2
+	e.recordCutoff(xionghan.Red, quiet[3], ply, 1)
+	e.recordCutoff(xionghan.Red, quiet[2], ply, 1) // quiet[2] 成为第一杀手，quiet[3] 第二
+	e.recordCutoff(xionghan.Red, quiet[1], ply+1, 5)
+
+	moves := append([]xionghan.Move{capture}, quiet...)
+	e.orderQuietMoves(pos, moves, nil, ply)
+	want := []xionghan.Move{capture, quiet[2], quiet[3], quiet[1], quiet[0]}
+	for i := range want {
+		if moves[i] != want[i] {
+			t.Fatalf("order = %v, want %v", moves, want)
+		}
+	}
+
+	// 有 NN 先验时，不吃子的非杀手着法按先验排序，先验跟着着法一起重排
+	moves = []xionghan.Move{quiet[0], quiet[1], capture}
+	priors := []float32{0.3, 0.5, 0.2}
+	e.orderQuietMoves(pos, moves, priors, ply+5)
+	if moves[0] != capture || moves[1] != quiet[1] || priors[1] != 0.5 || priors[2] != 0.3 {
+		t.Fatalf("order with priors = %v %v", moves, priors)
+	}
+}
+
+func TestLMRReduction(t *testing.T) {
+	cases := []struct {
+		depth, searched int
+		prior           float32
+		want            int
+	}{
+		{depth: 2, searched: 10, prior: -1, want: 0},
+		{depth: 3, searched: 2, prior: -1, want: 0},
+		{depth: 3, searched: 3, prior: -1, want: 1},
+		{depth: 3, searched: 3, prior: 0.001, want: 1},
+		{depth: 4, searched: 5, prior: 0.001, want: 2},
+		{depth: 4, searched: 5, prior: 0.2, want: 1},
+	}
+	for _, c := range cases {
+		if got := lmrReduction(c.depth, c.searched, c.prior); got != c.want {
+			t.Errorf("lmrReduction(%d, %d, %v) = %d, want %d", c.depth, c.searched, c.prior, got, c.want)
+		}
+	}
+}
+
+func TestNullMoveMaterialGuard(t *testing.T) {
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(10, 6): red(xionghan.PieceAdvisor),
+		sq(7, 3):  red(xionghan.PiecePawn),
+		sq(2, 7):  black(xionghan.PieceKnight),
+	})
+	if hasNullMoveMaterial(pos, xionghan.Red) {
+		t.Error("king, advisor and pawn only should not allow a null move")
+	}
+	if !hasNullMoveMaterial(pos, xionghan.Black) {
+		t.Error("a knight should allow a null move")
+	}
+}
+
+// 根着法的子局面都在残局库里：打开全部启发后根节点的分数和着法不变，
+// 期望窗口从错误的中心出发也要放宽到正确的分数
+func TestHeuristicsKeepRootScore(t *testing.T) {
+	prober := testProber(t, "EAvE")
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(6, 0): red(xionghan.PieceRook),
+	})
+
+	search := func(cfg SearchConfig, prev int) (int, xionghan.Move) {
+		e := NewEngine()
+		e.Tablebases = prober
+		e.threads = 1
+		e.heur = newSearchHeuristics(cfg)
+		rep := newRepetitionState(SearchConfig{})
+		if cfg.EnableAspiration {
+			return e.aspirationRoot(pos.Clone(), 3, prev, time.Time{}, rep)
+		}
+		return e.alphaBetaRoot(pos.Clone(), 3, -scoreInf, scoreInf, time.Time{}, rep)
+	}
+
+	wantScore, wantMove := search(SearchConfig{}, 0)
+	var all SearchConfig
+	all.EnableAllHeuristics()
+	for _, prev := range []int{0, wantScore, -wantScore} {
+		score, mv := search(all, prev)
+		if score != wantScore || mv != wantMove {
+			t.Fatalf("prev=%d: got %d %v, want %d %v", prev, score, mv, wantScore, wantMove)
+		}
+	}
+	if want, _ := prober.Probe(pos); want.WDL != tablebase.Win {
+		t.Fatalf("test position should be a win, got %v", want.WDL)
+	}
+}
@@ -53,13 +53,12 @@ func pieceAtIs(pos *xionghan.Position, sq int, side xionghan.Side, pt xionghan.P
 	return pc != 0 && pc.Side() == side && pc.Type() == pt
 }
 
-// FilterLeiLockedMoves:
-// 在子力>=42时，如果同侧某一边的“马+车”仍与初始位一致，则该边的檑不能移动。
-func (e *Engine) FilterLeiLockedMoves(pos *xionghan.Position, moves []xionghan.Move) []xionghan.Move {
-	_ = e
-	if len(moves) <= 1 {
-		return moves
-	}
+// LeiLockFilter 在子力>=42时，如果同侧某一边的“马+车”仍与初始位一致，则该边的檑不能移动。
+type LeiLockFilter struct{}
+
+func (LeiLockFilter) Name() string { return "lei_lock" }
+
+func (f LeiLockFilter) Apply(_ *Engine, pos *xionghan.Position, moves []xionghan.Move, report *FilterReport) []xionghan.Move {
 	if pos.TotalPieces() < 42 {
 		return moves
 	}
@@ -86,9 +85,11 @@ func (e *Engine) FilterLeiLockedMoves(pos *xionghan.Position, moves []xionghan.M
 			continue
 		}
 		if leftLocked && mv.From == setup.leftLei {
+			report.remove(f.Name(), mv, "left rook and knight have not moved yet")
 			continue
 		}
 		if rightLocked && mv.From == setup.rightLei {
+			report.remove(f.Name(), mv, "right rook and knight have not moved yet")
 			continue
 		}
 		filtered = append(filtered, mv)
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"xionghan/internal/xionghan"
+)
+
+const (
+	// MCTS 默认每多少次仿真报告一次进度
+	mctsReportDefaultInterval = 200
+	// MCTS 进度里列出访问最多的几个根着法
+	mctsReportTopK = 5
+)
+
+// SearchListener 接收搜索进度（SearchConfig.Listener）；同一次搜索里不会被并发调用
+type SearchListener func(SearchInfo)
+
+// SearchInfo 搜索进度：alpha-beta 每搜完一层报告一次，MCTS 每 ReportInterval 次仿真报告一次
+type SearchInfo struct {
+	MCTS     bool            // true 为 MCTS 的进度
+	Depth    int             // alpha-beta 搜完的深度
+	Score    int             // 评估分（红方视角）
+	WinProb  float32         // 红方胜率
+	Nodes    int64           // alpha-beta 为节点数，MCTS 为根节点访问次数
+	NPS      int64           // 每秒节点数（MCTS 为每秒仿真次数）
+	TimeUsed time.Duration   // 已用时间
+	PV       []xionghan.Move // 当前主变
+	PVScore  int             // 主变末端局面的评估（红方视角）
+	TopMoves []RootMoveInfo  // MCTS：访问最多的几个根着法
+}
+
+// RootMoveInfo MCTS 根着法的统计
+type RootMoveInfo struct {
+	Move    xionghan.Move
+	Visits  int64
+	WinProb float32 // 走这步之后的红方胜率
+}
+
+// scoreWinProb 把红方视角的分数换成红方胜率
+func scoreWinProb(score int) float32 {
+	p := (float32(score)/10000.0 + 1.0) / 2.0
+	if p < 0 {
+		return 0
+	}
+	if p > 1 {
+		return 1
+	}
+	return p
+}
+
+func perSecond(n int64, d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(float64(n) / d.Seconds())
+}
+
+// reportIteration alpha-beta 搜完一层后报告进度
+func (e *Engine) reportIteration(listener SearchListener, pos *xionghan.Position, rep *repetitionState, depth, score int, move xionghan.Move, start time.Time) {
+	pv, pvScore := e.principalVariation(pos, move, rep, depth, score)
+	nodes := atomic.LoadInt64(&e.nodes)
+	elapsed := time.Since(start)
+	listener(SearchInfo{
+		Depth:    depth,
+		Score:    score,
+		WinProb:  scoreWinProb(score),
+		Nodes:    nodes,
+		NPS:      perSecond(nodes, elapsed),
+		TimeUsed: elapsed,
+		PV:       pv,
+		PVScore:  pvScore,
+	})
+}
+
+// mctsReporter 各 MCTS 线程每做完一次仿真计一次数，够 interval 次时由当时的线程报告
+type mctsReporter struct {
+	listener SearchListener
+	interval int64
+	start    time.Time
+	count    int64
+	mu       sync.Mutex
+}
+
+// newMCTSReporter 没有 Listener 时返回 nil
+func newMCTSReporter(cfg SearchConfig, start time.Time) *mctsReporter {
+	if cfg.Listener == nil {
+		return nil
+	}
+	interval := int64(cfg.ReportInterval)
+	if interval <= 0 {
+		interval = mctsReportDefaultInterval
+	}
+	return &mctsReporter{listener: cfg.Listener, interval: interval, start: start}
+}
+
+func (r *mctsReporter) playoutDone(root *MCTSNode) {
+	if r == nil || atomic.AddInt64(&r.count, 1)%r.interval != 0 {
+		return
+	}
+	// 在锁内取快照，保证报告按时间顺序
+	r.mu.Lock()
+	r.listener(mctsSnapshot(root, r.start, atomic.LoadInt64(&r.count)))
+	r.mu.Unlock()
+}
+
+// mctsSnapshot 根节点当前的统计；playouts 为本次搜索已做的仿真次数
+func mctsSnapshot(root *MCTSNode, start time.Time, playouts int64) SearchInfo {
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+
+	top := make([]RootMoveInfo, 0, len(root.Children))
+	for mv, child := range root.Children {
+		v := root.EdgeVisits[mv]
+		if v == 0 || child == nil {
+			continue
+		}
+		child.mu.RLock()
+		u := child.UtilityAvg
+		child.mu.RUnlock()
+		top = append(top, RootMoveInfo{Move: mv, Visits: v, WinProb: float32((u + 1) / 2)})
+	}
+	sort.Slice(top, func(i, j int) bool { return top[i].Visits > top[j].Visits })
+	if len(top) > mctsReportTopK {
+		top = top[:mctsReportTopK]
+	}
+
+	elapsed := time.Since(start)
+	info := SearchInfo{
+		MCTS:     true,
+		Score:    int(root.UtilityAvg * 10000),
+		WinProb:  float32((root.UtilityAvg + 1) / 2),
+		Nodes:    root.Visits,
+		NPS:      perSecond(playouts, elapsed),
+		TimeUsed: elapsed,
+		TopMoves: top,
+	}
+	if len(top) > 0 {
+		pv, u := mctsPV(root, top[0].Move)
+		info.PV, info.PVScore = pv, int(u*10000)
+	}
+	return info
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"xionghan/internal/xionghan"
+)
+
+func TestReportIteration(t *testing.T) {
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(6, 0): red(xionghan.PieceRook),
+	})
+	e := NewEngine()
+	e.nodes = 1234
+	rep := newRepetitionState(SearchConfig{})
+	first := firstQuietMove(t, pos)
+	child, _ := pos.ApplyMove(first)
+	reply := firstQuietMove(t, child)
+	e.tt.store(ttKeyForPosition(child, rep), 1, 0, ttExact, reply)
+
+	var got []SearchInfo
+	e.reportIteration(func(info SearchInfo) { got = append(got, info) }, pos, rep, 2, 500, first, time.Now().Add(-time.Second))
+	if len(got) != 1 {
+		t.Fatalf("got %d reports", len(got))
+	}
+	info := got[0]
+	if info.MCTS || info.Depth != 2 || info.Score != 500 || info.Nodes != 1234 || info.NPS <= 0 || info.NPS > 1234 {
+		t.Fatalf("info = %+v", info)
+	}
+	if len(info.PV) != 2 || info.PV[0] != first || info.PV[1] != reply {
+		t.Fatalf("pv = %v", info.PV)
+	}
+	if info.WinProb != scoreWinProb(500) {
+		t.Fatalf("win prob = %v", info.WinProb)
+	}
+}
+
+func TestMCTSReporter(t *testing.T) {
+	root := NewMCTSNode(xionghan.Move{}, xionghan.Red, 1, false)
+	root.Visits = 60
+	root.UtilityAvg = 0.2
+	for i := 0; i < mctsReportTopK+2; i++ {
+		mv := xionghan.Move{From: i + 1, To: i + 20}
+		child := NewMCTSNode(mv, xionghan.Black, uint64(i+2), false)
+		child.UtilityAvg = -0.5
+		root.Children[mv] = child
+		root.EdgeVisits[mv] = int64(i)
+	}
+
+	var got []SearchInfo
+	cfg := SearchConfig{Listener: func(info SearchInfo) { got = append(got, info) }, ReportInterval: 3}
+	r := newMCTSReporter(cfg, time.Now())
+	for i := 0; i < 7; i++ {
+		r.playoutDone(root)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d reports after 7 playouts with interval 3", len(got))
+	}
+	info := got[1]
+	if !info.MCTS || info.Nodes != 60 || info.WinProb != 0.6 {
+		t.Fatalf("info = %+v", info)
+	}
+	// 访问次数为 0 的着法不列出，其余按访问次数从多到少取前 mctsReportTopK 个
+	if len(info.TopMoves) != mctsReportTopK || info.TopMoves[0].Visits != mctsReportTopK+1 || info.TopMoves[0].WinProb != 0.25 {
+		t.Fatalf("top moves = %+v", info.TopMoves)
+	}
+	if len(info.PV) != 1 || info.PV[0] != info.TopMoves[0].Move {
+		t.Fatalf("pv = %v", info.PV)
+	}
+
+	var none *mctsReporter
+	none.playoutDone(root) // 没有 Listener 时为 nil，调用无效
+	if newMCTSReporter(SearchConfig{}, time.Now()) != nil {
+		t.Fatal("reporter without a listener should be nil")
+	}
+}
@@ -35,6 +35,14 @@ type MCTSNode struct {
 	IsTerminal int32
 	Hash       uint64
 	GivesCheck bool
+
+	// 最近一次访问它的搜索（Engine.mctsGen），树满时按它近似 LRU 剪枝
+	lastUsed uint32
+
+	// MCTS-Solver（mcts_solver.go）：proof 是已证明的胜负；fullWidth 表示子节点覆盖了全部合法着法，
+	// 只有这样全部子节点必胜时才能证明本节点必败
+	proof     int32
+	fullWidth bool
 }
 
 func NewMCTSNode(mv xionghan.Move, pla xionghan.Side, hash uint64, givesCheck bool) *MCTSNode {
@@ -70,9 +78,14 @@ func (e *Engine) runMCTS(pos *xionghan.Position, cfg SearchConfig) SearchResult
 	h := pos.EnsureHash()
 	repBase := newRepetitionState(cfg)
 
+	// 残局库：局面在库中时直接按库走
+	if res, ok := e.probeRootTablebase(pos, repBase); ok {
+		res.TimeUsed = time.Since(start)
+		return res
+	}
+
 	// 0. 绝杀判定：直接吃王
-	moves := pos.GenerateLegalMoves(true)
-	moves = e.FilterLeiLockedMoves(pos, moves)
+	moves := e.quickMoves(pos)
 	for _, mv := range moves {
 		targetPiece := pos.Board.Squares[mv.To]
 		if targetPiece != 0 && targetPiece.Type() == xionghan.PieceKing {
@@ -90,6 +103,8 @@ func (e *Engine) runMCTS(pos *xionghan.Position, cfg SearchConfig) SearchResult
 				Nodes:    1,
 				TimeUsed: 0,
 				PV:       []xionghan.Move{mv},
+				PVScore:  scoreInf,
+				MateIn:   1,
 			}
 		}
 	}
@@ -104,6 +119,7 @@ func (e *Engine) runMCTS(pos *xionghan.Position, cfg SearchConfig) SearchResult
 					goto skipMCTSVCF
 				}
 			}
+			pv, pvScore := e.vcfPV(pos, vcfRes.Move, repBase, vcfDepthRoot, 900000)
 			return SearchResult{
 				BestMove: vcfRes.Move,
 				Score:    900000,
@@ -111,35 +127,39 @@ func (e *Engine) runMCTS(pos *xionghan.Position, cfg SearchConfig) SearchResult
 				Depth:    vcfDepthRoot,
 				Nodes:    100,
 				TimeUsed: 0,
-				PV:       []xionghan.Move{vcfRes.Move},
+				PV:       pv,
+				PVScore:  pvScore,
+				MateIn:   vcfRes.Depth,
 			}
 		}
 	}
 skipMCTSVCF:
 
+	// Repetition constraints are path-dependent, so disable transposition sharing.
 	allowTransposition := !repBase.enabled
 
 	var root *MCTSNode
 	if allowTransposition {
 		e.poolMu.Lock()
-		if e.mctsPool == nil {
-			e.mctsPool = make(map[uint64]*MCTSNode, 1<<16)
-		}
-		if len(e.mctsPool) > 300000 {
-			e.mctsPool = make(map[uint64]*MCTSNode, 1<<16)
-		}
-		var ok bool
-		root, ok = e.mctsPool[h]
-		if !ok {
-			root = NewMCTSNode(xionghan.Move{}, pos.SideToMove, h, false)
-			e.mctsPool[h] = root
-		}
-		e.mctsRoot = root
+		root = e.mctsPool[h]
 		e.poolMu.Unlock()
-	} else {
-		// Repetition constraints are path-dependent, so disable transposition sharing.
+	} else if prev := e.mctsRoot; prev != nil && prev.Hash == h {
+		// 不共享置换时，沿用同一局面上次留下的树（后台思考后由 PonderMove 提升的子树）
+		root = prev
+	}
+	reused := root != nil
+	if root == nil {
 		root = NewMCTSNode(xionghan.Move{}, pos.SideToMove, h, false)
-		e.mctsRoot = root
+	}
+	e.mctsRoot = root
+	e.mctsGen++
+
+	// 对局往前走了：只留下新根下面的子树，其余的释放
+	maxNodes := mctsMaxNodes(cfg)
+	kept, pruned := e.retainMCTSTree(root, maxNodes, allowTransposition)
+	var reusedNodes int64
+	if reused {
+		reusedNodes = int64(kept)
 	}
 
 	// 1. 根节点展开：这里保留专家过滤，保证“起手不弱智”
@@ -164,43 +184,112 @@ skipMCTSVCF:
 		simsPerThread = 1
 	}
 
-	var wg sync.WaitGroup
-	for t := 0; t < numThreads; t++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			localRep := repBase.clone()
-			for i := 0; i < simsPerThread; i++ {
-				if cfg.TimeLimit > 0 && time.Since(start) > cfg.TimeLimit {
-					break
+	tm := newTimeManager(cfg, start)
+	reporter := newMCTSReporter(cfg, start)
+	remaining := int64(simsPerThread * numThreads)
+	var playouts int64
+	var halt uint32 // 时间管理器判定可以提前停止
+	for {
+		var full uint32 // 树的节点数到了上限
+		var wg sync.WaitGroup
+		for t := 0; t < numThreads; t++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				localRep := repBase.clone()
+				// 每个线程一份局面副本，playout 内就地走子/撤销
+				localPos := pos.Clone()
+				for {
+					// 根已证明胜负时不必再搜
+					if e.cancelled() || atomic.LoadUint32(&halt) != 0 || tm.hardExceeded() ||
+						atomic.LoadInt32(&root.proof) != 0 {
+						break
+					}
+					if atomic.LoadInt64(&e.mctsNodes) > int64(maxNodes) {
+						atomic.StoreUint32(&full, 1)
+						break
+					}
+					if atomic.AddInt64(&remaining, -1) < 0 {
+						break
+					}
+					e.mctsPlayout(root, localPos, cfg, localRep, allowTransposition)
+					atomic.AddInt64(&playouts, 1)
+					reporter.playoutDone(root)
+				}
+			}()
+		}
+		if tm.adaptive {
+			// 定期检查软上限和最佳着法是否已不会被超过
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+			ticker := time.NewTicker(tmCheckInterval)
+		monitor:
+			for {
+				select {
+				case <-done:
+					break monitor
+				case <-ticker.C:
+					if tm.mctsShouldStop(root, atomic.LoadInt64(&playouts)) {
+						atomic.StoreUint32(&halt, 1)
+					}
 				}
-				e.mctsPlayout(root, pos, cfg, localRep, allowTransposition)
 			}
-		}()
+			ticker.Stop()
+		}
+		wg.Wait()
+
+		if atomic.LoadUint32(&full) == 0 || atomic.LoadInt64(&remaining) <= 0 ||
+			e.cancelled() || atomic.LoadUint32(&halt) != 0 || tm.hardExceeded() ||
+			atomic.LoadInt32(&root.proof) != 0 {
+			break
+		}
+		// 树满了：线程都已停下，剪掉不常用的分支后继续
+		_, p := e.retainMCTSTree(root, maxNodes, allowTransposition)
+		if p == 0 {
+			break
+		}
+		pruned += p
 	}
-	wg.Wait()
 
 	root.mu.RLock()
 	defer root.mu.RUnlock()
 
-	bestMove := xionghan.Move{}
-	maxVisits := int64(-1)
-	for mv := range root.Children {
-		v := root.EdgeVisits[mv]
-		if v > maxVisits {
-			maxVisits = v
-			bestMove = mv
-		}
+	bestMove := mctsRootMove(root, repBase)
+
+	var pv []xionghan.Move
+	pvUtility := root.UtilityAvg
+	if bestMove.From != 0 || bestMove.To != 0 {
+		pv, pvUtility = mctsPV(root, bestMove)
 	}
 
 	redWinProb := (root.UtilityAvg + 1.0) / 2.0
+	score := int((redWinProb*2.0 - 1.0) * 10000)
+	mateIn := 0
+	if r, d := root.proven(); r != 0 {
+		// 已证明胜负：越快赢越好、越慢输越好
+		mateIn = r * d
+		score, redWinProb = scoreInf-d, 1.0
+		if proofUtility(r, pos.SideToMove) < 0 {
+			score, redWinProb = -score, 0.0
+		}
+	}
 	return SearchResult{
 		BestMove: bestMove,
-		Score:    int((redWinProb*2.0 - 1.0) * 10000),
+		Score:    score,
 		WinProb:  float32(redWinProb),
 		Nodes:    root.Visits,
 		TimeUsed: time.Since(start),
-		PV:       []xionghan.Move{bestMove},
+		PV:       pv,
+		PVScore:  int(pvUtility * 10000),
+		Stopped:  e.cancelled(),
+
+		TreeNodes:   atomic.LoadInt64(&e.mctsNodes),
+		ReusedNodes: reusedNodes,
+		PrunedNodes: int64(pruned),
+		MateIn:      mateIn,
 	}
 }
 
@@ -214,15 +303,23 @@ func applyMCTSContempt(utility float64, sideToMove xionghan.Side) float64 {
 	return utility
 }
 
+// mctsPlayout 在 pos 上就地沿树走子，结束前全部撤销，pos 必须是当前线程独占的。
 func (e *Engine) mctsPlayout(root *MCTSNode, pos *xionghan.Position, cfg SearchConfig, rep *repetitionState, allowTransposition bool) {
 	node := root
 	currPos := pos
+	startPly := pos.Ply()
+	defer func() {
+		for pos.Ply() > startPly {
+			pos.UnmakeMove()
+		}
+	}()
 	var path []*MCTSNode
 	var edgePath []xionghan.Move
 	path = append(path, node)
 
 	for {
-		if atomic.LoadInt32(&node.State) != StateExpanded || atomic.LoadInt32(&node.IsTerminal) != 0 {
+		if atomic.LoadInt32(&node.State) != StateExpanded || atomic.LoadInt32(&node.IsTerminal) != 0 ||
+			atomic.LoadInt32(&node.proof) != 0 {
 			break
 		}
 
@@ -240,15 +337,15 @@ func (e *Engine) mctsPlayout(root *MCTSNode, pos *xionghan.Position, cfg SearchC
 			rep.push(node.Hash)
 		}
 
-		nextPos, ok := currPos.ApplyMove(mv)
-		if !ok {
+		if !currPos.MakeMove(mv) {
 			break
 		}
-		currPos = nextPos
 	}
 
 	var utility float64
-	if atomic.LoadInt32(&node.IsTerminal) != 0 {
+	if r, _ := node.proven(); r != 0 {
+		utility = proofUtility(r, currPos.SideToMove)
+	} else if atomic.LoadInt32(&node.IsTerminal) != 0 {
 		if currPos.SideToMove == xionghan.Red {
 			utility = -1.0
 		} else {
@@ -256,6 +353,10 @@ func (e *Engine) mctsPlayout(root *MCTSNode, pos *xionghan.Position, cfg SearchC
 		}
 	} else {
 		for {
+			if r, _ := node.proven(); r != 0 {
+				utility = proofUtility(r, currPos.SideToMove)
+				break
+			}
 			if atomic.LoadInt32(&node.IsTerminal) != 0 {
 				if currPos.SideToMove == xionghan.Red {
 					utility = -1.0
@@ -275,6 +376,12 @@ func (e *Engine) mctsPlayout(root *MCTSNode, pos *xionghan.Position, cfg SearchC
 			}
 
 			if state == StateUnevaluated && atomic.CompareAndSwapInt32(&node.State, StateUnevaluated, StateEvaluating) {
+				if r := e.solveMCTSLeaf(node, currPos); r != 0 {
+					// 已证明的叶子不用 NN，也不展开
+					atomic.StoreInt32(&node.State, StateUnevaluated)
+					utility = proofUtility(r, currPos.SideToMove)
+					break
+				}
 				res, err := e.nn.Evaluate(currPos)
 				if err != nil {
 					node.mu.RLock()
@@ -286,6 +393,13 @@ func (e *Engine) mctsPlayout(root *MCTSNode, pos *xionghan.Position, cfg SearchC
 					// 2. 内部节点展开：禁用沉重的 Blunder/VCF 过滤，恢复速度
 					e.expandMCTSNodeFromEvaluating(node, currPos, res, false, allowTransposition)
 					utility = float64(res.LossProb*2.0 - 1.0)
+					// 残局库命中时用精确胜负代替 NN 价值，之后再访问也用它
+					if r, ok := e.Tablebases.Probe(currPos); ok {
+						utility = tbUtility(r, currPos.SideToMove)
+						node.mu.Lock()
+						node.NNValue = utility
+						node.mu.Unlock()
+					}
 				}
 				utility = applyMCTSContempt(utility, currPos.SideToMove)
 				break
@@ -296,10 +410,12 @@ func (e *Engine) mctsPlayout(root *MCTSNode, pos *xionghan.Position, cfg SearchC
 		}
 	}
 
-	// Backprop
+	// Backprop；叶子已证明时顺路尝试证明上面的节点
+	solved := atomic.LoadInt32(&node.proof) != 0
 	for i := len(path) - 1; i >= 0; i-- {
 		n := path[i]
 		n.mu.Lock()
+		n.lastUsed = e.mctsGen
 		n.Visits++
 		n.WeightSum += 1.0
 		n.UtilityAvg += (utility - n.UtilityAvg) / float64(n.Visits)
@@ -317,6 +433,10 @@ func (e *Engine) mctsPlayout(root *MCTSNode, pos *xionghan.Position, cfg SearchC
 			if rep.enabled {
 				rep.pop(n.Hash)
 			}
+			// rep 此时回到 parent 处的状态，和选择时一样判断哪些子节点能走
+			if solved {
+				solved = updateMCTSProof(parent, rep)
+			}
 		}
 	}
 }
@@ -350,6 +470,11 @@ func (e *Engine) selectMCTSChild(node *MCTSNode, rep *repetitionState, isRoot bo
 	}
 	fpuValue := fpuBase - fpuReduction
 
+	// MCTS-Solver：有已证明的必胜步直接走
+	if mv, child, _ := provenChild(node, rep, true); child != nil {
+		return mv, child
+	}
+
 	// For root LCB gating, compute max edge visits among selectable children.
 	maxEdgeVisits := 0.0
 	if isRoot && mctsRootLCBEnabled {
@@ -368,6 +493,10 @@ func (e *Engine) selectMCTSChild(node *MCTSNode, rep *repetitionState, isRoot bo
 		if rep.enabled && !rep.canEnter(child.Hash, child.GivesCheck) {
 			continue
 		}
+		if r, _ := child.proven(); r > 0 {
+			// 对方已证明必胜，不再花仿真
+			continue
+		}
 
 		edgeVisits := float64(node.EdgeVisits[mv])
 		vLoss := float64(atomic.LoadInt32(&child.VirtualLosses))
@@ -417,6 +546,10 @@ func (e *Engine) selectMCTSChild(node *MCTSNode, rep *repetitionState, isRoot bo
 			bestChild = child
 		}
 	}
+	if bestChild == nil {
+		// 能走的都已证明必败：拖得最久的一步
+		bestMove, bestChild, _ = provenChild(node, rep, false)
+	}
 	return bestMove, bestChild
 }
 
@@ -433,27 +566,46 @@ func (e *Engine) expandMCTSNodeInternal(node *MCTSNode, pos *xionghan.Position,
 }
 
 func (e *Engine) expandMCTSNodeFromEvaluating(node *MCTSNode, pos *xionghan.Position, res *NNResult, useFullFilter bool, allowTransposition bool) {
-	moves := pos.GenerateLegalMoves(true)
+	legal := pos.GenerateLegalMoves()
+	numLegal := len(legal)
+	var moves []xionghan.Move
 	if useFullFilter {
-		// 只有根节点才跑这些重的过滤
-		moves = e.FilterLeiLockedMoves(pos, moves)
-		moves = e.FilterUrgentPawnThreatMoves(pos, moves)
-		moves = e.FilterBlunderMoves(pos, moves)
-		moves = e.FilterVCFMoves(pos, moves)
+		// 只有根节点才跑完整的过滤管线
+		moves = e.Filters.Apply(e, pos, legal, nil)
 	} else {
 		// 内部节点只跑最轻量的
-		moves = e.FilterLeiLockedMoves(pos, moves)
+		moves = e.QuickFilters.Apply(e, pos, legal, nil)
 	}
 
 	if len(moves) == 0 {
+		// 无着可走：轮到走的一方输
+		atomic.StoreInt32(&node.proof, proofLoss(0))
 		atomic.StoreInt32(&node.IsTerminal, 1)
 		atomic.StoreInt32(&node.State, StateExpanded)
 		return
 	}
 
-	fromGroups := make(map[int][]xionghan.Move)
+	// 先就地走子算出每个子节点的哈希与是否将军（转置表需要），
+	// 必须在启动 Stage 1 推理之前完成：推理 goroutine 会并发读取 pos。
+	type childInfo struct {
+		mv         xionghan.Move
+		hash       uint64
+		givesCheck bool
+		p          float32
+	}
+	childrenInfo := make([]childInfo, 0, len(moves))
+	fromGroups := make(map[int][]int) // From -> indices in childrenInfo
 	for _, mv := range moves {
-		fromGroups[mv.From] = append(fromGroups[mv.From], mv)
+		if !pos.MakeMove(mv) {
+			continue
+		}
+		childrenInfo = append(childrenInfo, childInfo{
+			mv:         mv,
+			hash:       pos.EnsureHash(),
+			givesCheck: moveGivesCheck(pos),
+		})
+		pos.UnmakeMove()
+		fromGroups[mv.From] = append(fromGroups[mv.From], len(childrenInfo)-1)
 	}
 
 	type stage1Res struct {
@@ -483,39 +635,20 @@ func (e *Engine) expandMCTSNodeFromEvaluating(node *MCTSNode, pos *xionghan.Posi
 
 	priorMap := make(map[xionghan.Move]float32)
 	totalP := float32(0)
-	type childInfo struct {
-		mv         xionghan.Move
-		hash       uint64
-		givesCheck bool
-		p          float32
-	}
-	var childrenInfo []childInfo
 
 	for r := range resChan {
-		fromMoves := fromGroups[r.from]
+		fromIdx := fromGroups[r.from]
 		pFrom := res.Policy[r.from]
-		for _, mv := range fromMoves {
+		for _, idx := range fromIdx {
+			ci := &childrenInfo[idx]
 			var p float32
 			if r.res == nil {
-				p = pFrom * (1.0 / float32(len(fromMoves)))
+				p = pFrom * (1.0 / float32(len(fromIdx)))
 			} else {
-				p = pFrom * r.res.Policy[mv.To]
-			}
-
-			// 虽然 ApplyMove 有点慢，但为了转置表（Transposition）这是必须的
-			// 我们已经移除了内部节点的 VCF/Blunder，速度应该能接受了
-			nextPos, ok := pos.ApplyMove(mv)
-			if !ok {
-				continue
+				p = pFrom * r.res.Policy[ci.mv.To]
 			}
-
-			childrenInfo = append(childrenInfo, childInfo{
-				mv:         mv,
-				hash:       nextPos.EnsureHash(),
-				givesCheck: moveGivesCheck(nextPos),
-				p:          p,
-			})
-			priorMap[mv] = p
+			ci.p = p
+			priorMap[ci.mv] = p
 			totalP += p
 		}
 	}
@@ -530,25 +663,32 @@ func (e *Engine) expandMCTSNodeFromEvaluating(node *MCTSNode, pos *xionghan.Posi
 	node.mu.Lock()
 	node.NNValue = float64(res.LossProb*2.0 - 1.0)
 	node.PriorMap = priorMap
+	node.fullWidth = len(childrenInfo) == numLegal
 
 	nextPla := xionghan.Black
 	if node.NextPla == xionghan.Black {
 		nextPla = xionghan.Red
 	}
 
+	created := int64(0)
 	for _, ci := range childrenInfo {
 		var childNode *MCTSNode
 		if allowTransposition {
 			e.poolMu.Lock()
+			if e.mctsPool == nil {
+				e.mctsPool = make(map[uint64]*MCTSNode, 1<<16)
+			}
 			var ok bool
 			childNode, ok = e.mctsPool[ci.hash]
 			if !ok {
 				childNode = NewMCTSNode(ci.mv, nextPla, ci.hash, ci.givesCheck)
 				e.mctsPool[ci.hash] = childNode
+				created++
 			}
 			e.poolMu.Unlock()
 		} else {
 			childNode = NewMCTSNode(ci.mv, nextPla, ci.hash, ci.givesCheck)
+			created++
 		}
 		node.Children[ci.mv] = childNode
 		if _, ok := node.EdgeVisits[ci.mv]; !ok {
@@ -556,6 +696,7 @@ func (e *Engine) expandMCTSNodeFromEvaluating(node *MCTSNode, pos *xionghan.Posi
 		}
 	}
 	node.mu.Unlock()
+	atomic.AddInt64(&e.mctsNodes, created)
 
 	atomic.StoreInt32(&node.State, StateExpanded)
 }
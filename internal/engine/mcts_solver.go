@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"sync/atomic"
+
+	"xionghan/internal/xionghan"
+)
+
+// MCTS-Solver：已证明的胜负（带到吃王的半回合数）沿树回传。
+// 已证明的节点不再往下仿真，选择时直接走必胜的子节点、避开必败的子节点。
+
+const (
+	mctsVCFDepth     = 4  // 树内叶子的连将搜索深度
+	mctsVCFMaxPieces = 43 // 子力不超过它时叶子才做连将搜索
+)
+
+// 证明结果编码在 MCTSNode.proof：0 未证明；+(d+1) 轮到走的一方 d 步（半回合）内吃王获胜，
+// -(d+1) d 步内被吃王（d=0 即已经无着可走或王已被吃）
+func proofWin(dist int) int32  { return int32(dist + 1) }
+func proofLoss(dist int) int32 { return -int32(dist + 1) }
+
+// proven 节点的证明结果：result 为 1 轮到走的一方必胜，-1 必败，0 未证明；dist 为到终局的半回合数
+func (n *MCTSNode) proven() (result, dist int) {
+	p := atomic.LoadInt32(&n.proof)
+	switch {
+	case p > 0:
+		return 1, int(p - 1)
+	case p < 0:
+		return -1, int(-p - 1)
+	}
+	return 0, 0
+}
+
+// proofUtility 已证明节点的价值（红方视角），side 为该节点轮到走的一方
+func proofUtility(result int, side xionghan.Side) float64 {
+	if (result > 0) == (side == xionghan.Red) {
+		return 1.0
+	}
+	return -1.0
+}
+
+// solveMCTSLeaf 叶子展开前先试着证明：王已被吃、能直接吃王、或连将必胜（vcfProve，防守方的应着不过滤）。
+// 证明了就记在节点上，返回结果（1/-1），否则返回 0
+func (e *Engine) solveMCTSLeaf(node *MCTSNode, pos *xionghan.Position) int {
+	if pos.KingSquare(pos.SideToMove) < 0 {
+		atomic.StoreInt32(&node.proof, proofLoss(0))
+		return -1
+	}
+	if e.CanCaptureKingNext(pos) {
+		atomic.StoreInt32(&node.proof, proofWin(1))
+		return 1
+	}
+	if pos.TotalPieces() <= mctsVCFMaxPieces {
+		if res := e.vcfProve(pos, mctsVCFDepth); res.CanWin {
+			atomic.StoreInt32(&node.proof, proofWin(res.Depth))
+			return 1
+		}
+	}
+	return 0
+}
+
+// provenChild 已证明的子节点里对 node 轮到走的一方最好的一步：win 为 true 时找最快的必胜步（子节点必败），
+// 为 false 时找拖得最久的必败步（子节点必胜）。dist 为走这步后 node 的胜负步数。
+// rep 非 nil 时跳过重复规则不允许的子节点。调用方持有 node.mu。
+func provenChild(node *MCTSNode, rep *repetitionState, win bool) (mv xionghan.Move, child *MCTSNode, dist int) {
+	for m, c := range node.Children {
+		if rep != nil && rep.enabled && !rep.canEnter(c.Hash, c.GivesCheck) {
+			continue
+		}
+		r, d := c.proven()
+		if win && r < 0 && (child == nil || d+1 < dist) ||
+			!win && r > 0 && (child == nil || d+1 > dist) {
+			mv, child, dist = m, c, d+1
+		}
+	}
+	return mv, child, dist
+}
+
+// updateMCTSProof 按子节点的证明更新 n：有子节点必败则 n 必胜（取最短）；
+// 子节点覆盖了全部合法着法且全都必胜则 n 必败（取最长）。
+// rep 是搜索在 n 处的重复状态。证明存在节点上，会经置换和树复用被别的路径看到，
+// 所以有子节点被重复规则禁走时（结果只对这条路径成立）不证明。返回 n 是否已证明
+func updateMCTSProof(n *MCTSNode, rep *repetitionState) bool {
+	if atomic.LoadInt32(&n.proof) != 0 {
+		return true
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if atomic.LoadInt32(&n.State) != StateExpanded || len(n.Children) == 0 {
+		return false
+	}
+	if rep != nil && rep.enabled {
+		for _, c := range n.Children {
+			if !rep.canEnter(c.Hash, c.GivesCheck) {
+				return false
+			}
+		}
+	}
+	if _, _, dist := provenChild(n, nil, true); dist > 0 {
+		atomic.StoreInt32(&n.proof, proofWin(dist))
+		return true
+	}
+	if !n.fullWidth {
+		// 有着法被过滤掉了，没搜到的着法可能逃得掉
+		return false
+	}
+	longest := 0
+	for _, c := range n.Children {
+		r, d := c.proven()
+		if r <= 0 {
+			return false
+		}
+		if d+1 > longest {
+			longest = d + 1
+		}
+	}
+	atomic.StoreInt32(&n.proof, proofLoss(longest))
+	return true
+}
+
+// mctsRootMove 根节点最终走的着法：已证明必胜时走最快的必胜步；否则在没被证明必败的着法里取访问最多的，
+// 全都必败时走拖得最久的一步。重复规则不允许的着法不考虑。调用方持有 root.mu。
+func mctsRootMove(root *MCTSNode, rep *repetitionState) xionghan.Move {
+	if mv, child, _ := provenChild(root, rep, true); child != nil {
+		return mv
+	}
+	bestMove := xionghan.Move{}
+	maxVisits := int64(-1)
+	for mv, child := range root.Children {
+		if rep != nil && rep.enabled && !rep.canEnter(child.Hash, child.GivesCheck) {
+			continue
+		}
+		if r, _ := child.proven(); r > 0 {
+			continue
+		}
+		if v := root.EdgeVisits[mv]; v > maxVisits {
+			maxVisits = v
+			bestMove = mv
+		}
+	}
+	if maxVisits < 0 {
+		var child *MCTSNode
+		bestMove, child, _ = provenChild(root, rep, false)
+		if child == nil && rep != nil {
+			// 重复规则不允许任何一步：不再管它，总得走一步
+			return mctsRootMove(root, nil)
+		}
+	}
+	return bestMove
+}
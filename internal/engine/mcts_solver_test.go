@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"testing"
+
+	"xionghan/internal/xionghan"
+)
+
+func TestUpdateMCTSProof(t *testing.T) {
+	a := xionghan.Move{From: 1, To: 2}
+	b := xionghan.Move{From: 3, To: 4}
+	node := expandedTestNode(100, 0, 10, 0)
+	node.Children[a] = NewMCTSNode(a, xionghan.Black, 101, false)
+	node.Children[b] = NewMCTSNode(b, xionghan.Black, 102, false)
+
+	if updateMCTSProof(node, nil) {
+		t.Fatal("proved a node without proven children")
+	}
+	// 子节点都是对方必胜，但有着法被过滤：不能证明必败
+	node.Children[a].proof = proofWin(1)
+	node.Children[b].proof = proofWin(3)
+	if updateMCTSProof(node, nil) {
+		t.Fatal("proved a loss without covering every legal move")
+	}
+	node.fullWidth = true
+	if !updateMCTSProof(node, nil) {
+		t.Fatal("all children win for the opponent, node should be lost")
+	}
+	if r, d := node.proven(); r != -1 || d != 4 {
+		t.Fatalf("proof = %d in %d, want loss in 4", r, d)
+	}
+
+	// 有一个子节点对方必败：本节点必胜，取最短的
+	node.proof = 0
+	node.Children[b].proof = proofLoss(2)
+	updateMCTSProof(node, nil)
+	if r, d := node.proven(); r != 1 || d != 3 {
+		t.Fatalf("proof = %d in %d, want win in 3", r, d)
+	}
+}
+
+func TestUpdateMCTSProofRespectsRepetition(t *testing.T) {
+	a := xionghan.Move{From: 1, To: 2}
+	b := xionghan.Move{From: 3, To: 4}
+	node := expandedTestNode(100, 0, 10, 0)
+	node.fullWidth = true
+	// a 是将军，走过去会形成禁止的长将；b 对方必胜
+	node.Children[a] = NewMCTSNode(a, xionghan.Black, 101, true)
+	node.Children[a].proof = proofLoss(0)
+	node.Children[b] = NewMCTSNode(b, xionghan.Black, 102, false)
+	node.Children[b].proof = proofWin(2)
+	rep := newRepetitionState(SearchConfig{
+		EnableRepetitionFilter: true,
+		RepetitionBanCount:     2,
+		RepetitionCount:        map[uint64]int{101: 1},
+	})
+
+	// 不能走 a：这条路径上能走的只有 b，但别的路径可能能走 a，不能把必败记在节点上
+	if updateMCTSProof(node, rep) || node.proof != 0 {
+		t.Fatal("stored a loss that only holds on this path")
+	}
+	if mctsRootMove(node, rep) != b {
+		t.Fatal("root move entered a banned repetition")
+	}
+	// 不受重复规则限制时 a 直接获胜
+	if !updateMCTSProof(node, nil) {
+		t.Fatal("winning move not proven without repetition")
+	}
+	if r, d := node.proven(); r != 1 || d != 1 {
+		t.Fatalf("proof = %d in %d, want win in 1", r, d)
+	}
+}
+
+func TestSelectMCTSChildSolver(t *testing.T) {
+	a := xionghan.Move{From: 1, To: 2}
+	b := xionghan.Move{From: 3, To: 4}
+	c := xionghan.Move{From: 5, To: 6}
+	node := expandedTestNode(100, 0, 10, 0)
+	node.PriorMap = map[xionghan.Move]float32{a: 0.8, b: 0.1, c: 0.1}
+	for _, mv := range []xionghan.Move{a, b, c} {
+		node.Children[mv] = NewMCTSNode(mv, xionghan.Black, uint64(mv.From), false)
+	}
+	e := NewEngine()
+	rep := newRepetitionState(SearchConfig{})
+
+	// 先验最高的 a 已被证明对方必胜：不选
+	node.Children[a].proof = proofWin(1)
+	if mv, _ := e.selectMCTSChild(node, rep, false); mv == a {
+		t.Fatal("selected a proven loss")
+	}
+	// c 对方必败：直接选
+	node.Children[c].proof = proofLoss(0)
+	if mv, _ := e.selectMCTSChild(node, rep, false); mv != c {
+		t.Fatalf("selected %v, want the proven win %v", mv, c)
+	}
+	if mctsRootMove(node, rep) != c {
+		t.Fatal("root move is not the proven win")
+	}
+	// 全部必败：拖得最久的一步
+	node.Children[b].proof = proofWin(5)
+	node.Children[c].proof = proofWin(3)
+	if mv, _ := e.selectMCTSChild(node, rep, false); mv != b {
+		t.Fatalf("selected %v, want the longest loss %v", mv, b)
+	}
+	if mctsRootMove(node, rep) != b {
+		t.Fatal("root move does not delay the loss")
+	}
+}
+
+func TestSolveMCTSLeaf(t *testing.T) {
+	e := NewEngine()
+	// 车在黑王同列，中间无子：一步吃王
+	pos := seePosition(map[int]xionghan.Piece{sq(6, 7): red(xionghan.PieceRook)})
+	node := NewMCTSNode(xionghan.Move{}, xionghan.Red, pos.EnsureHash(), false)
+	if e.solveMCTSLeaf(node, pos) != 1 {
+		t.Fatal("king capture not proven")
+	}
+	if r, d := node.proven(); r != 1 || d != 1 {
+		t.Fatalf("proof = %d in %d, want win in 1", r, d)
+	}
+
+	quiet := seePosition(nil)
+	if e.solveMCTSLeaf(NewMCTSNode(xionghan.Move{}, xionghan.Red, 1, false), quiet) != 0 {
+		t.Fatal("proved a position with only kings")
+	}
+}
+
+func TestMCTSReportsForcedWin(t *testing.T) {
+	pos := seePosition(nil)
+	mv := firstQuietMove(t, pos)
+	next, _ := pos.ApplyMove(mv)
+
+	// 手工建好的根：mv 走后对方已被证明必败（2 步内）
+	root := expandedTestNode(pos.EnsureHash(), 0, 1, 0)
+	root.Children[mv] = NewMCTSNode(mv, xionghan.Black, next.EnsureHash(), false)
+	root.Children[mv].proof = proofLoss(2)
+	e := NewEngine()
+	e.mctsPool = map[uint64]*MCTSNode{root.Hash: root}
+
+	res := e.Search(pos, SearchConfig{UseMCTS: true, MCTSSimulations: 64})
+	if res.BestMove != mv || res.MateIn != 3 || res.Score != scoreInf-3 || res.WinProb != 1 {
+		t.Fatalf("got move %v mate in %d score %d win prob %v, want %v mate in 3",
+			res.BestMove, res.MateIn, res.Score, res.WinProb, mv)
+	}
+	if res.NNFailed {
+		t.Fatal("solved search should not need the NN")
+	}
+}
+
+func TestSolveMCTSLeafIgnoresFilteredEscapes(t *testing.T) {
+	var b xionghan.Board
+	b.Squares[sq(12, 5)] = red(xionghan.PieceKing)
+	b.Squares[sq(5, 2)] = red(xionghan.PieceRook)
+	b.Squares[sq(0, 6)] = black(xionghan.PieceKing)
+	b.Squares[sq(0, 5)] = black(xionghan.PieceElephant)
+	b.Squares[sq(0, 7)] = black(xionghan.PieceElephant)
+	b.Squares[sq(8, 6)] = black(xionghan.PieceRook)
+	pos := xionghan.NewPosition(b, xionghan.Red, nil)
+
+	// 车平到王的同列将军，黑方唯一的解法是车往回吃车，而启发式过滤不让车后退
+	e := NewEngine()
+	e.QuickFilters = FilterPipeline{HeuristicFilter{NoBackwardAbove: 2}}
+	if !e.VCFSearch(pos, mctsVCFDepth).CanWin {
+		t.Fatal("filtered defence should miss the escape")
+	}
+	node := NewMCTSNode(xionghan.Move{}, xionghan.Red, pos.EnsureHash(), false)
+	if e.solveMCTSLeaf(node, pos) != 0 || node.proof != 0 {
+		t.Fatal("proved a win the defender can escape with a filtered move")
+	}
+}
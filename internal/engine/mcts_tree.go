@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"xionghan/internal/xionghan"
+)
+
+const (
+	// MCTS 树默认的节点上限（每个节点连同子节点表约几百字节）
+	mctsDefaultMaxNodes = 300000
+	// 超过上限时剪到上限的这个比例，留出余量，避免刚剪完又满
+	mctsPruneTarget = 0.75
+)
+
+// mctsMaxNodes 本次搜索的节点上限
+func mctsMaxNodes(cfg SearchConfig) int {
+	if cfg.MCTSMaxNodes > 0 {
+		return cfg.MCTSMaxNodes
+	}
+	return mctsDefaultMaxNodes
+}
+
+// collectMCTSTree 从 root 出发能到达的全部节点（置换共享的节点只算一次），root 在最前
+func collectMCTSTree(root *MCTSNode) []*MCTSNode {
+	seen := map[*MCTSNode]bool{root: true}
+	nodes := []*MCTSNode{root}
+	for i := 0; i < len(nodes); i++ {
+		for _, child := range nodes[i].Children {
+			if !seen[child] {
+				seen[child] = true
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	return nodes
+}
+
+// retainMCTSTree 只保留 root 能到达的子树，其余的交给 GC；超过 maxNodes 时剪掉不常用的分支。
+// 共享置换时按保留下来的节点重建 mctsPool。返回保留和剪掉的节点数。
+// 调用时不能有线程在这棵树上搜索。
+func (e *Engine) retainMCTSTree(root *MCTSNode, maxNodes int, allowTransposition bool) (kept, pruned int) {
+	nodes := collectMCTSTree(root)
+	if len(nodes) > maxNodes {
+		before := len(nodes)
+		nodes = e.pruneMCTSTree(root, nodes, int(float64(maxNodes)*mctsPruneTarget))
+		pruned = before - len(nodes)
+	}
+
+	e.poolMu.Lock()
+	if allowTransposition {
+		pool := make(map[uint64]*MCTSNode, len(nodes))
+		for _, n := range nodes {
+			pool[n.Hash] = n
+		}
+		e.mctsPool = pool
+	} else {
+		e.mctsPool = nil
+	}
+	e.poolMu.Unlock()
+
+	atomic.StoreInt64(&e.mctsNodes, int64(len(nodes)))
+	return len(nodes), pruned
+}
+
+// pruneMCTSTree 把权重最低的已展开节点收起（丢掉子节点，下次访问时重新展开），直到树不超过 target 个节点。
+// 权重是访问次数，每有一次搜索没访问过它就减半，近似 LRU：很久没用、访问又少的分支先剪。
+// 根不收起。nodes 是 collectMCTSTree(root) 的结果，返回剪完后的节点。
+func (e *Engine) pruneMCTSTree(root *MCTSNode, nodes []*MCTSNode, target int) []*MCTSNode {
+	type candidate struct {
+		node   *MCTSNode
+		weight int64
+	}
+	var cands []candidate
+	for _, n := range nodes {
+		if n == root || atomic.LoadInt32(&n.State) != StateExpanded || len(n.Children) == 0 {
+			continue
+		}
+		age := e.mctsGen - n.lastUsed
+		if age > 62 {
+			age = 62
+		}
+		cands = append(cands, candidate{n, n.Visits >> age})
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].weight < cands[j].weight })
+
+	for len(nodes) > target && len(cands) > 0 {
+		// 按直接子节点数估计能释放多少；置换共享的子节点可能还挂在别处，所以收完重新数一遍
+		excess := len(nodes) - target
+		freed, i := 0, 0
+		for ; i < len(cands) && freed < excess; i++ {
+			freed += len(cands[i].node.Children)
+			collapseMCTSNode(cands[i].node)
+		}
+		cands = cands[i:]
+		nodes = collectMCTSTree(root)
+	}
+	return nodes
+}
+
+// collapseMCTSNode 丢掉节点的子树，回到未展开状态；访问次数和价值保留，父节点看到的统计不变
+func collapseMCTSNode(n *MCTSNode) {
+	n.mu.Lock()
+	n.Children = make(map[xionghan.Move]*MCTSNode)
+	n.EdgeVisits = make(map[xionghan.Move]int64)
+	n.PriorMap = nil
+	n.mu.Unlock()
+	atomic.StoreInt32(&n.State, StateUnevaluated)
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"testing"
+
+	"xionghan/internal/xionghan"
+)
+
+// expandedTestNode 造一个已展开、有 n 个未展开子节点的节点，子节点哈希从 hash+1 开始
+func expandedTestNode(hash uint64, n int, visits int64, lastUsed uint32) *MCTSNode {
+	node := NewMCTSNode(xionghan.Move{}, xionghan.Red, hash, false)
+	node.State = StateExpanded
+	node.Visits = visits
+	node.lastUsed = lastUsed
+	for i := 1; i <= n; i++ {
+		mv := xionghan.Move{From: i, To: i + 1}
+		node.Children[mv] = NewMCTSNode(mv, xionghan.Black, hash+uint64(i), false)
+		node.EdgeVisits[mv] = 1
+	}
+	return node
+}
+
+func TestRetainMCTSTreeKeepsReachableSubtree(t *testing.T) {
+	a := xionghan.Move{From: 1, To: 2}
+	b := xionghan.Move{From: 3, To: 4}
+	root := expandedTestNode(100, 0, 10, 1)
+	played := expandedTestNode(200, 3, 6, 1)
+	other := expandedTestNode(300, 3, 4, 1)
+	root.Children[a], root.Children[b] = played, other
+
+	e := NewEngine()
+	e.mctsPool = make(map[uint64]*MCTSNode)
+	for _, n := range collectMCTSTree(root) {
+		e.mctsPool[n.Hash] = n
+	}
+
+	// 对局走了 a：只留 played 和它的 3 个子节点
+	kept, pruned := e.retainMCTSTree(played, 100, true)
+	if kept != 4 || pruned != 0 || e.mctsNodes != 4 {
+		t.Fatalf("kept %d pruned %d nodes %d, want 4 0 4", kept, pruned, e.mctsNodes)
+	}
+	if len(e.mctsPool) != 4 || e.mctsPool[200] != played || e.mctsPool[100] != nil || e.mctsPool[300] != nil {
+		t.Fatalf("pool not rebuilt from the new root: %d entries", len(e.mctsPool))
+	}
+
+	// 不共享置换：不需要 pool
+	if e.retainMCTSTree(played, 100, false); e.mctsPool != nil {
+		t.Fatal("pool kept without transpositions")
+	}
+}
+
+func TestPruneMCTSTreeDropsColdBranches(t *testing.T) {
+	a := xionghan.Move{From: 1, To: 2}
+	b := xionghan.Move{From: 3, To: 4}
+	c := xionghan.Move{From: 5, To: 6}
+	root := expandedTestNode(100, 0, 200, 10)
+	hot := expandedTestNode(200, 10, 100, 10)
+	cold := expandedTestNode(300, 10, 5, 10)
+	stale := expandedTestNode(400, 10, 80, 3) // 访问多，但已经 7 次搜索没碰过
+	root.Children[a], root.Children[b], root.Children[c] = hot, cold, stale
+
+	e := NewEngine()
+	e.mctsGen = 10
+	// 34 个节点，上限 20：剪到 15 个，要收起两个分支
+	kept, pruned := e.retainMCTSTree(root, 20, false)
+	if kept != 14 || pruned != 20 {
+		t.Fatalf("kept %d pruned %d, want 14 20", kept, pruned)
+	}
+	if hot.State != StateExpanded || len(hot.Children) != 10 {
+		t.Fatal("most used branch was pruned")
+	}
+	for name, n := range map[string]*MCTSNode{"cold": cold, "stale": stale} {
+		if n.State != StateUnevaluated || len(n.Children) != 0 || len(n.EdgeVisits) != 0 {
+			t.Fatalf("%s branch not collapsed", name)
+		}
+	}
+	// 收起的节点保留自己的统计，父节点的选择不受影响
+	if stale.Visits != 80 || root.Children[c] != stale {
+		t.Fatal("collapsed node lost its statistics")
+	}
+
+	// 根本身不收起：只剩根的子节点时剪不动
+	flat := expandedTestNode(500, 30, 30, 10)
+	if kept, pruned := e.retainMCTSTree(flat, 20, false); kept != 31 || pruned != 0 {
+		t.Fatalf("flat tree: kept %d pruned %d", kept, pruned)
+	}
+}
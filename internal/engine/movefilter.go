@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"xionghan/internal/xionghan"
+)
+
+// MoveFilter 在合法着法上做启发式裁剪。
+// Apply 返回保留的着法（不得改动传入的切片），被删掉的着法和原因写进 report；
+// report 为 nil 时不记录（搜索热路径）。删光全部着法时由 FilterPipeline 兜底。
+type MoveFilter interface {
+	Name() string
+	Apply(e *Engine, pos *xionghan.Position, moves []xionghan.Move, report *FilterReport) []xionghan.Move
+}
+
+// RemovedMove 一步被过滤掉的着法
+type RemovedMove struct {
+	Move   xionghan.Move
+	Filter string
+	Reason string
+}
+
+// FilterReport 过滤管线的调试记录
+type FilterReport struct {
+	Removed  []RemovedMove
+	Reverted []string // 因删光全部着法而被撤销的过滤器
+}
+
+func (r *FilterReport) remove(filter string, mv xionghan.Move, reason string) {
+	if r == nil {
+		return
+	}
+	r.Removed = append(r.Removed, RemovedMove{Move: mv, Filter: filter, Reason: reason})
+}
+
+// String 每行一步被删掉的着法，便于日志查看
+func (r *FilterReport) String() string {
+	if r == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, rm := range r.Removed {
+		fmt.Fprintf(&sb, "%s %s: %s\n", xionghan.CoordinateNotation{}.FormatMove(nil, rm.Move), rm.Filter, rm.Reason)
+	}
+	for _, name := range r.Reverted {
+		fmt.Fprintf(&sb, "%s: removed every move, reverted\n", name)
+	}
+	return sb.String()
+}
+
+// FilterPipeline 依次执行的一组过滤器
+type FilterPipeline []MoveFilter
+
+// Apply 依次执行每个过滤器，只剩一步时不再过滤。
+// 兜底：某个过滤器删光了全部着法时撤销它这一步，不把“本来有合法步”的局面误判为无招。
+func (fp FilterPipeline) Apply(e *Engine, pos *xionghan.Position, moves []xionghan.Move, report *FilterReport) []xionghan.Move {
+	for _, f := range fp {
+		if len(moves) <= 1 {
+			break
+		}
+		var stage *FilterReport
+		if report != nil {
+			stage = &FilterReport{}
+		}
+		kept := f.Apply(e, pos, moves, stage)
+		if len(kept) == 0 {
+			if report != nil {
+				report.Reverted = append(report.Reverted, f.Name())
+			}
+			continue
+		}
+		if report != nil {
+			report.Removed = append(report.Removed, stage.Removed...)
+		}
+		moves = kept
+	}
+	return moves
+}
+
+// DefaultFilters 搜索主干（根节点、alpha-beta、MCTS 根）使用的完整管线
+func DefaultFilters() FilterPipeline {
+	return FilterPipeline{
+		DefaultHeuristicFilter,
+		LeiLockFilter{},
+		PawnThreatFilter{},
+		BlunderFilter{},
+		VCFFilter{},
+	}
+}
+
+// DefaultQuickFilters VCF 搜索和 MCTS 内部节点使用的轻量管线
+func DefaultQuickFilters() FilterPipeline {
+	return FilterPipeline{
+		DefaultHeuristicFilter,
+		LeiLockFilter{},
+	}
+}
+
+// FilteredMoves 生成合法着法并经过完整过滤管线
+func (e *Engine) FilteredMoves(pos *xionghan.Position) []xionghan.Move {
+	return e.Filters.Apply(e, pos, pos.GenerateLegalMoves(), nil)
+}
+
+// ExplainMoves 与 FilteredMoves 相同，同时返回每一步被删掉的原因，
+// 用来排查 AI 为什么不走某一步
+func (e *Engine) ExplainMoves(pos *xionghan.Position) ([]xionghan.Move, *FilterReport) {
+	report := &FilterReport{}
+	return e.Filters.Apply(e, pos, pos.GenerateLegalMoves(), report), report
+}
+
+// quickMoves 生成合法着法并经过轻量管线
+func (e *Engine) quickMoves(pos *xionghan.Position) []xionghan.Move {
+	return e.QuickFilters.Apply(e, pos, pos.GenerateLegalMoves(), nil)
+}
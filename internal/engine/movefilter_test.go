@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"testing"
+
+	"xionghan/internal/xionghan"
+)
+
+// dropAllFilter 删掉所有着法，用来测试管线兜底
+type dropAllFilter struct{}
+
+func (dropAllFilter) Name() string { return "drop_all" }
+
+func (f dropAllFilter) Apply(_ *Engine, _ *xionghan.Position, moves []xionghan.Move, report *FilterReport) []xionghan.Move {
+	for _, mv := range moves {
+		report.remove(f.Name(), mv, "test")
+	}
+	return nil
+}
+
+func TestFilterPipelineRevertsFilterThatRemovesEverything(t *testing.T) {
+	e := NewEngine()
+	pos := xionghan.NewInitialPosition()
+	legal := pos.GenerateLegalMoves()
+
+	report := &FilterReport{}
+	got := FilterPipeline{dropAllFilter{}}.Apply(e, pos, legal, report)
+	if len(got) != len(legal) {
+		t.Fatalf("moves after revert: got %d want %d", len(got), len(legal))
+	}
+	if len(report.Removed) != 0 {
+		t.Fatalf("reverted filter should not report removals, got %d", len(report.Removed))
+	}
+	if len(report.Reverted) != 1 || report.Reverted[0] != "drop_all" {
+		t.Fatalf("reverted: got %v", report.Reverted)
+	}
+}
+
+func TestExplainMovesAccountsForEveryLegalMove(t *testing.T) {
+	e := NewEngine()
+	pos := xionghan.NewInitialPosition()
+	legal := pos.GenerateLegalMoves()
+
+	kept, report := e.ExplainMoves(pos)
+	if len(kept) == 0 || len(kept) >= len(legal) {
+		t.Fatalf("opening heuristics should remove some moves: kept %d of %d", len(kept), len(legal))
+	}
+	if len(kept)+len(report.Removed) != len(legal) {
+		t.Fatalf("kept %d + removed %d != legal %d", len(kept), len(report.Removed), len(legal))
+	}
+	for _, rm := range report.Removed {
+		if rm.Filter == "" || rm.Reason == "" {
+			t.Fatalf("removed move without filter or reason: %+v", rm)
+		}
+		if containsMove(kept, rm.Move) {
+			t.Fatalf("move both kept and removed: %+v", rm.Move)
+		}
+	}
+
+	// 开局不动王和士
+	for _, mv := range kept {
+		pt := pos.Board.Squares[mv.From].Type()
+		if pt == xionghan.PieceKing || pt == xionghan.PieceAdvisor {
+			t.Fatalf("king or advisor move kept in the opening: %+v", mv)
+		}
+	}
+
+	if plain := e.FilteredMoves(pos); len(plain) != len(kept) {
+		t.Fatalf("FilteredMoves and ExplainMoves disagree: %d vs %d", len(plain), len(kept))
+	}
+}
+
+func TestFiltersCanBeDisabled(t *testing.T) {
+	e := NewEngine()
+	e.Filters = nil
+	pos := xionghan.NewInitialPosition()
+	if got, want := len(e.FilteredMoves(pos)), len(pos.GenerateLegalMoves()); got != want {
+		t.Fatalf("empty pipeline: got %d moves want %d", got, want)
+	}
+}
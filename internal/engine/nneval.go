@@ -800,7 +800,7 @@ func computeResultsBeforeNN(pos *xionghan.Position, stage int, chosenSquare int)
 		myOnlyPass:  false,
 	}
 
-	legalMoves := pos.GenerateLegalMoves(false)
+	legalMoves := pos.GenerateLegalMoves()
 	legalCount := 0
 
 	if stage == 0 {
@@ -832,7 +832,7 @@ func buildPolicyLegalMask(pos *xionghan.Position, stage int, chosenSquare int) (
 	var mask [PolicySize]bool
 	legalCount := 0
 
-	legalMoves := pos.GenerateLegalMoves(false)
+	legalMoves := pos.GenerateLegalMoves()
 	if stage == 0 {
 		var seenFrom [xionghan.NumSquares]bool
 		for _, mv := range legalMoves {
@@ -915,14 +915,12 @@ func postProcessPolicy(raw []float32, legalMask *[PolicySize]bool, legalCount in
 	return out
 }
 
+// mapSquareForNN 黑方走时网络输入上下翻转（即红黑互换）
 func mapSquareForNN(sq int, flipY bool) int {
 	if !flipY {
 		return sq
 	}
-	r := sq / BoardSize
-	c := sq % BoardSize
-	rr := BoardSize - 1 - r
-	return rr*BoardSize + c
+	return xionghan.SymFlipColors.Square(sq)
 }
 
 func unflipPolicyY(raw []float32) []float32 {
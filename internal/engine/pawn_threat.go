@@ -2,13 +2,13 @@ package engine
 
 import "xionghan/internal/xionghan"
 
-// FilterUrgentPawnThreatMoves 在“非被将且非被立即绝杀风险”下，强制优先处理被兵下一手可吃的大子。
+// PawnThreatFilter 在“非被将且非被立即绝杀风险”下，强制优先处理被兵下一手可吃的大子。
 // 目标子仅包含：车、马、炮、檑。
-func (e *Engine) FilterUrgentPawnThreatMoves(pos *xionghan.Position, moves []xionghan.Move) []xionghan.Move {
-	if len(moves) <= 1 {
-		return moves
-	}
+type PawnThreatFilter struct{}
 
+func (PawnThreatFilter) Name() string { return "pawn_threat" }
+
+func (f PawnThreatFilter) Apply(e *Engine, pos *xionghan.Position, moves []xionghan.Move, report *FilterReport) []xionghan.Move {
 	side := pos.SideToMove
 	if side != xionghan.Red && side != xionghan.Black {
 		return moves
@@ -39,32 +39,55 @@ func (e *Engine) FilterUrgentPawnThreatMoves(pos *xionghan.Position, moves []xio
 		}
 		forcedAny = append(forcedAny, mv)
 
-		nextPos, ok := pos.ApplyMove(mv)
-		if !ok {
+		if !pos.MakeMove(mv) {
 			continue
 		}
-		if !nextPos.IsAttackedByPawn(mv.To, opp) {
+		safe := !pos.IsAttackedByPawn(mv.To, opp)
+		pos.UnmakeMove()
+		if safe {
 			forcedSafe = append(forcedSafe, mv)
 		}
 	}
 
-	if len(forcedSafe) > 0 {
-		return forcedSafe
+	kept := forcedSafe
+	if len(kept) == 0 {
+		kept = forcedAny
 	}
-	if len(forcedAny) > 0 {
-		return forcedAny
+	if len(kept) == 0 {
+		return moves
 	}
-	return moves
+	if report != nil {
+		for _, mv := range moves {
+			if containsMove(kept, mv) {
+				continue
+			}
+			if _, ok := threatened[mv.From]; ok {
+				report.remove(f.Name(), mv, "piece is still attacked by a pawn after moving")
+			} else {
+				report.remove(f.Name(), mv, "a major piece is attacked by a pawn and must be saved first")
+			}
+		}
+	}
+	return kept
+}
+
+func containsMove(moves []xionghan.Move, m xionghan.Move) bool {
+	for _, mv := range moves {
+		if mv.From == m.From && mv.To == m.To {
+			return true
+		}
+	}
+	return false
 }
 
 func threatenedSquaresByEnemyPawn(pos *xionghan.Position, side xionghan.Side) map[int]struct{} {
 	opp := oppositeSide(side)
-	oppPos := *pos
+	oppPos := pos.Clone()
 	oppPos.SideToMove = opp
 	oppPos.Hash = 0
 
 	out := make(map[int]struct{}, 4)
-	oppMoves := oppPos.GenerateLegalMoves(false)
+	oppMoves := oppPos.GenerateLegalMoves()
 	for _, mv := range oppMoves {
 		attacker := oppPos.Board.Squares[mv.From]
 		if attacker == 0 || attacker.Type() != xionghan.PiecePawn {
@@ -86,11 +109,11 @@ func canSideCaptureKingNext(pos *xionghan.Position, attacker xionghan.Side) bool
 	if attacker != xionghan.Red && attacker != xionghan.Black {
 		return false
 	}
-	tmp := *pos
+	tmp := pos.Clone()
 	tmp.SideToMove = attacker
 	tmp.Hash = 0
 
-	moves := tmp.GenerateLegalMoves(false)
+	moves := tmp.GenerateLegalMoves()
 	for _, mv := range moves {
 		target := tmp.Board.Squares[mv.To]
 		if target != 0 && target.Type() == xionghan.PieceKing && target.Side() != attacker {
@@ -104,10 +127,10 @@ func (e *Engine) isUnderVCFThreat(pos *xionghan.Position, attacker xionghan.Side
 	if attacker != xionghan.Red && attacker != xionghan.Black {
 		return false
 	}
-	tmp := *pos
+	tmp := pos.Clone()
 	tmp.SideToMove = attacker
 	tmp.Hash = 0
-	return e.VCFSearch(&tmp, vcfDepthFilter).CanWin
+	return e.VCFSearch(tmp, vcfDepthFilter).CanWin
 }
 
 func oppositeSide(side xionghan.Side) xionghan.Side {
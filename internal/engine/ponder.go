@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"xionghan/internal/xionghan"
+)
+
+const (
+	// 后台思考的上限：对手长时间不走（多半是离开了）时自行停止
+	ponderMaxTime = 5 * time.Minute
+	// alpha-beta 后台思考的最大深度，MCTS 后台思考的最多仿真次数（限制树的内存）
+	ponderMaxDepth       = 32
+	ponderMaxSimulations = 200000
+)
+
+// ponderState 后台思考的状态，由 Engine.ponderMu 保护
+type ponderState struct {
+	cancel    context.CancelFunc
+	done      chan struct{}
+	hash      uint64        // 思考的局面（对手要走）
+	predicted xionghan.Move // alpha-beta：预测的对手着法
+	mcts      bool
+}
+
+// StartPonder 在对手思考时后台搜索。pos 是引擎走完、轮到对手的局面。
+// MCTS 搜对手的整棵树；alpha-beta 搜 predicted（通常是主变的第二步）走后的局面，结果留在置换表里。
+// predicted 为空或不合法时 alpha-beta 不思考。已有的后台思考先停下。
+func (e *Engine) StartPonder(pos *xionghan.Position, predicted xionghan.Move, cfg SearchConfig) {
+	e.StopPonder()
+
+	target := pos.Clone()
+	if cfg.UseMCTS && cfg.MCTSSimulations > 0 {
+		cfg.MCTSSimulations = ponderMaxSimulations
+		predicted = xionghan.Move{}
+	} else {
+		if !target.IsLegalMove(predicted) || !target.MakeMove(predicted) {
+			return
+		}
+		// 预测着法走后的局面也计入重复历史
+		counts := make(map[uint64]int, len(cfg.RepetitionCount)+1)
+		for k, v := range cfg.RepetitionCount {
+			counts[k] = v
+		}
+		counts[target.EnsureHash()]++
+		cfg.RepetitionCount = counts
+		cfg.MaxDepth = ponderMaxDepth
+	}
+	cfg.TimeLimit = ponderMaxTime
+	cfg.RemainingTime = 0
+	cfg.Listener = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	st := &ponderState{
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		hash:      pos.EnsureHash(),
+		predicted: predicted,
+		mcts:      cfg.UseMCTS && cfg.MCTSSimulations > 0,
+	}
+	e.ponderMu.Lock()
+	e.ponder = st
+	e.lastPonder = nil
+	e.ponderMu.Unlock()
+	go func() {
+		defer close(st.done)
+		e.search(ctx, target, cfg)
+	}()
+}
+
+// StopPonder 停止后台思考并等它退出；没有在思考时立即返回
+func (e *Engine) StopPonder() {
+	e.ponderMu.Lock()
+	st := e.ponder
+	e.ponder = nil
+	e.ponderMu.Unlock()
+	if st == nil {
+		return
+	}
+	st.cancel()
+	<-st.done
+	e.ponderMu.Lock()
+	e.lastPonder = st
+	e.ponderMu.Unlock()
+}
+
+// PonderMove 对手实际走了 mv：停止后台思考。MCTS 把 mv 对应的子树提升为新的根，
+// 下一次在走后的局面搜索时接着用。返回思考是否用得上（MCTS 找到了子树，或 alpha-beta 猜中了着法）。
+func (e *Engine) PonderMove(mv xionghan.Move) bool {
+	e.StopPonder()
+	e.ponderMu.Lock()
+	st := e.lastPonder
+	e.lastPonder = nil
+	e.ponderMu.Unlock()
+	if st == nil {
+		return false
+	}
+	if !st.mcts {
+		return st.predicted == mv
+	}
+	root := e.mctsRoot
+	if root == nil || root.Hash != st.hash {
+		return false
+	}
+	root.mu.RLock()
+	child := root.Children[mv]
+	root.mu.RUnlock()
+	if child == nil {
+		return false
+	}
+	e.mctsRoot = child
+	return true
+}
+
+// Pondering 是否正在后台思考
+func (e *Engine) Pondering() bool {
+	e.ponderMu.Lock()
+	defer e.ponderMu.Unlock()
+	return e.ponder != nil
+}
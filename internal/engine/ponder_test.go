@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"testing"
+
+	"xionghan/internal/xionghan"
+)
+
+func TestPonderAlphaBeta(t *testing.T) {
+	e := NewEngine()
+	e.Tablebases = testProber(t, "EAvE")
+	start := seePosition(map[int]xionghan.Piece{sq(6, 0): red(xionghan.PieceRook)})
+	pos, _ := start.ApplyMove(firstQuietMove(t, start)) // 引擎走完，轮到黑方
+	replies := pos.GenerateLegalMoves()
+	predicted, other := replies[0], replies[1]
+
+	// 预测着法不合法：不思考
+	e.StartPonder(pos, xionghan.Move{From: 0, To: 1}, SearchConfig{MaxDepth: 2})
+	if e.Pondering() {
+		t.Fatal("pondering on an illegal predicted move")
+	}
+
+	e.StartPonder(pos, predicted, SearchConfig{MaxDepth: 2})
+	if !e.Pondering() {
+		t.Fatal("not pondering")
+	}
+	if !e.PonderMove(predicted) || e.Pondering() {
+		t.Fatal("ponder hit not reported or pondering not stopped")
+	}
+	// 状态只用一次
+	if e.PonderMove(predicted) {
+		t.Fatal("stale ponder state")
+	}
+
+	e.StartPonder(pos, predicted, SearchConfig{MaxDepth: 2})
+	if e.PonderMove(other) {
+		t.Fatal("ponder miss reported as a hit")
+	}
+
+	// 搜索开始前停下后台思考
+	e.StartPonder(pos, predicted, SearchConfig{MaxDepth: 2})
+	e.Search(start, SearchConfig{MaxDepth: 2})
+	if e.Pondering() {
+		t.Fatal("search did not stop pondering")
+	}
+}
+
+func TestPonderMovePromotesMCTSSubtree(t *testing.T) {
+	mv := xionghan.Move{From: 1, To: 2}
+	root := NewMCTSNode(xionghan.Move{}, xionghan.Black, 42, false)
+	child := NewMCTSNode(mv, xionghan.Red, 43, false)
+	root.Children[mv] = child
+
+	e := NewEngine()
+	e.mctsRoot = root
+	e.lastPonder = &ponderState{mcts: true, hash: 42}
+	// 没有对应的子树：根不变
+	if e.PonderMove(xionghan.Move{From: 3, To: 4}) || e.mctsRoot != root {
+		t.Fatal("no child for the played move, root should stay")
+	}
+
+	e.lastPonder = &ponderState{mcts: true, hash: 42}
+	if !e.PonderMove(mv) || e.mctsRoot != child {
+		t.Fatal("subtree of the played move was not promoted")
+	}
+
+	// 树不是在思考的局面上建的：不提升
+	e.mctsRoot = root
+	e.lastPonder = &ponderState{mcts: true, hash: 7}
+	if e.PonderMove(mv) || e.mctsRoot != root {
+		t.Fatal("promoted a subtree from a different position")
+	}
+}
@@ -0,0 +1,155 @@
+package engine
+
+import "xionghan/internal/xionghan"
+
+// 主变最多展开的步数（残局库、MCTS 用；alpha-beta 以搜索深度为限）
+const maxPVLength = 64
+
+// pvWalk 从 pos 先走 first，再反复用 next 取下一步，返回主变和末端局面（pos 不变）。
+// 着法不合法、进入重复禁手、局面出现循环、有一方的王被吃或达到 maxLen 时停止。
+func pvWalk(pos *xionghan.Position, first xionghan.Move, rep *repetitionState, maxLen int,
+	next func(cur *xionghan.Position, r *repetitionState) (xionghan.Move, bool)) ([]xionghan.Move, *xionghan.Position) {
+	cur := pos.Clone()
+	r := rep.clone()
+	seen := map[uint64]bool{cur.EnsureHash(): true}
+	pv := make([]xionghan.Move, 0, 8)
+	mv, ok := first, true
+	for ok && len(pv) < maxLen {
+		if !cur.IsLegalMove(mv) || !cur.MakeMove(mv) {
+			break
+		}
+		h := cur.EnsureHash()
+		if r.enabled && !r.canEnter(h, moveGivesCheck(cur)) {
+			cur.UnmakeMove()
+			break
+		}
+		r.push(h)
+		pv = append(pv, mv)
+		if seen[h] || !cur.KingExists(xionghan.Red) || !cur.KingExists(xionghan.Black) {
+			break
+		}
+		seen[h] = true
+		mv, ok = next(cur, r)
+	}
+	return pv, cur
+}
+
+// principalVariation alpha-beta 的主变：第一步是根的最佳着法，之后沿置换表里的最佳着法走，
+// 最多 maxLen 步。返回主变和末端局面的评估（红方视角），末端评估不出来时用 fallback。
+func (e *Engine) principalVariation(pos *xionghan.Position, first xionghan.Move, rep *repetitionState, maxLen, fallback int) ([]xionghan.Move, int) {
+	pv, leaf := pvWalk(pos, first, rep, maxLen, func(cur *xionghan.Position, r *repetitionState) (xionghan.Move, bool) {
+		entry, ok := e.tt.probe(ttKeyForPosition(cur, r))
+		if !ok || (entry.Move.From == 0 && entry.Move.To == 0) {
+			return xionghan.Move{}, false
+		}
+		return entry.Move, true
+	})
+	return pv, e.leafScore(leaf, fallback)
+}
+
+// tablebasePV 残局库局面的主变：每一步都按库选着
+func (e *Engine) tablebasePV(pos *xionghan.Position, first xionghan.Move, rep *repetitionState) ([]xionghan.Move, int) {
+	pv, leaf := pvWalk(pos, first, rep, maxPVLength, func(cur *xionghan.Position, _ *repetitionState) (xionghan.Move, bool) {
+		mv, _, ok := e.Tablebases.BestMove(cur, cur.GenerateLegalMoves())
+		return mv, ok
+	})
+	fallback := 0
+	if r, ok := e.Tablebases.Probe(pos); ok {
+		fallback = tbScore(r, pos.SideToMove)
+	}
+	return pv, e.leafScore(leaf, fallback)
+}
+
+// vcfPV 连将杀的主变：攻方每步按 VCF 搜索选着，守方选被杀得最慢的应着，直到吃王或杀不下去。
+// depth 为每步 VCF 搜索的深度。返回主变和末端局面的评估（红方视角）。
+func (e *Engine) vcfPV(pos *xionghan.Position, first xionghan.Move, rep *repetitionState, depth, fallback int) ([]xionghan.Move, int) {
+	attacker := pos.SideToMove
+	pv, leaf := pvWalk(pos, first, rep, maxPVLength, func(cur *xionghan.Position, _ *repetitionState) (xionghan.Move, bool) {
+		if cur.SideToMove == attacker {
+			res := e.VCFSearch(cur, depth)
+			return res.Move, res.CanWin
+		}
+		var best xionghan.Move
+		longest := -1
+		for _, mv := range e.quickMoves(cur) {
+			next, ok := cur.ApplyMove(mv)
+			if !ok {
+				continue
+			}
+			res := e.VCFSearch(next, depth)
+			if !res.CanWin {
+				// 这步能逃掉（VCF 搜索的预算内没杀到）：后面不再是强制的
+				return xionghan.Move{}, false
+			}
+			if res.Depth > longest {
+				best, longest = mv, res.Depth
+			}
+		}
+		return best, longest >= 0
+	})
+	return pv, e.leafScore(leaf, fallback)
+}
+
+// leafScore 主变末端局面的评估（红方视角）：王被吃为 ±scoreInf，其次查残局库，再用 NN。
+// NN 不可用、推理失败或搜索已取消时返回 fallback，不标记 NN 故障（搜索已经结束）。
+func (e *Engine) leafScore(pos *xionghan.Position, fallback int) int {
+	if !pos.KingExists(xionghan.Red) {
+		return -scoreInf
+	}
+	if !pos.KingExists(xionghan.Black) {
+		return scoreInf
+	}
+	if r, ok := e.Tablebases.Probe(pos); ok {
+		return tbScore(r, pos.SideToMove)
+	}
+	if e.nn == nil || e.hasNNFailure() || e.cancelled() {
+		return fallback
+	}
+	key := hashPosition(pos)
+	if cached, ok := e.getNNEvalFromCache(key); ok {
+		return cached
+	}
+	res, err := e.nn.Evaluate(pos)
+	if err != nil || res == nil {
+		return fallback
+	}
+	return int((res.LossProb - res.WinProb) * 10000)
+}
+
+// mctsPV MCTS 的主变：从 first 开始每步走访问次数最多的边（已证明必胜时走最快的必胜步），直到没访问过的节点。
+// 调用方持有 root 的读锁。返回主变和末端节点的平均价值（红方视角，-1..1）。
+func mctsPV(root *MCTSNode, first xionghan.Move) ([]xionghan.Move, float64) {
+	node := root.Children[first]
+	if node == nil {
+		return []xionghan.Move{first}, root.UtilityAvg
+	}
+	pv := []xionghan.Move{first}
+	seen := map[*MCTSNode]bool{root: true}
+	for len(pv) < maxPVLength && !seen[node] {
+		seen[node] = true
+		node.mu.RLock()
+		// 已证明必胜的节点沿最快的必胜步走，其余沿访问最多的边
+		bestMove, bestChild, _ := provenChild(node, nil, true)
+		if bestChild == nil {
+			maxVisits := int64(0)
+			for mv, child := range node.Children {
+				if v := node.EdgeVisits[mv]; v > maxVisits && child != nil {
+					maxVisits, bestMove, bestChild = v, mv, child
+				}
+			}
+		}
+		node.mu.RUnlock()
+		if bestChild == nil {
+			break
+		}
+		pv = append(pv, bestMove)
+		node = bestChild
+	}
+	if node == root {
+		return pv, root.UtilityAvg
+	}
+	node.mu.RLock()
+	u := node.UtilityAvg
+	node.mu.RUnlock()
+	return pv, u
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"testing"
+
+	"xionghan/internal/xionghan"
+)
+
+// firstQuietMove 第一个不吃子的合法着法
+func firstQuietMove(t *testing.T, pos *xionghan.Position) xionghan.Move {
+	t.Helper()
+	for _, mv := range pos.GenerateLegalMoves() {
+		if pos.Board.Squares[mv.To] == 0 {
+			return mv
+		}
+	}
+	t.Fatal("no quiet move")
+	return xionghan.Move{}
+}
+
+func TestPrincipalVariationFollowsTT(t *testing.T) {
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(6, 0): red(xionghan.PieceRook),
+	})
+	e := NewEngine()
+	rep := newRepetitionState(SearchConfig{})
+
+	// 根着法之后的两步存进置换表，第三个局面存一步不合法的着法
+	cur := pos.Clone()
+	var line []xionghan.Move
+	for i := 0; i < 3; i++ {
+		mv := firstQuietMove(t, cur)
+		line = append(line, mv)
+		cur.MakeMove(mv)
+		if i < 2 {
+			e.tt.store(ttKeyForPosition(cur, rep), 1, 0, ttExact, firstQuietMove(t, cur))
+		}
+	}
+	e.tt.store(ttKeyForPosition(cur, rep), 1, 0, ttExact, xionghan.Move{From: 0, To: 1})
+
+	pv, score := e.principalVariation(pos, line[0], rep, 10, 123)
+	if len(pv) != 3 || pv[0] != line[0] || pv[1] != line[1] || pv[2] != line[2] {
+		t.Fatalf("pv = %v, want %v", pv, line)
+	}
+	if score != 123 {
+		t.Fatalf("leaf score without NN = %d, want fallback 123", score)
+	}
+	if pv, _ := e.principalVariation(pos, line[0], rep, 2, 0); len(pv) != 2 {
+		t.Fatalf("pv longer than maxLen: %v", pv)
+	}
+}
+
+func TestTablebasePVReachesKingCapture(t *testing.T) {
+	prober := testProber(t, "EAvE")
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(6, 0): red(xionghan.PieceRook),
+	})
+	want, ok := prober.Probe(pos)
+	if !ok {
+		t.Fatal("position not in tablebase")
+	}
+
+	e := NewEngine()
+	e.Tablebases = prober
+	res := e.Search(pos, SearchConfig{MaxDepth: 3})
+	if len(res.PV) != want.Plies || res.PV[0] != res.BestMove {
+		t.Fatalf("pv = %v (len %d), want %d plies starting with %v", res.PV, len(res.PV), want.Plies, res.BestMove)
+	}
+	cur := pos.Clone()
+	for i, mv := range res.PV {
+		if !cur.IsLegalMove(mv) {
+			t.Fatalf("pv[%d] = %v is illegal", i, mv)
+		}
+		cur.MakeMove(mv)
+	}
+	if cur.KingExists(xionghan.Black) || res.PVScore != scoreInf {
+		t.Fatalf("pv should end with the black king captured, pv score %d", res.PVScore)
+	}
+}
+
+func TestMCTSPVFollowsMostVisited(t *testing.T) {
+	a := xionghan.Move{From: 1, To: 2}
+	b := xionghan.Move{From: 3, To: 4}
+	c := xionghan.Move{From: 5, To: 6}
+	root := NewMCTSNode(xionghan.Move{}, xionghan.Red, 1, false)
+	n1 := NewMCTSNode(a, xionghan.Black, 2, false)
+	n2 := NewMCTSNode(b, xionghan.Red, 3, false)
+	n3 := NewMCTSNode(c, xionghan.Black, 4, false)
+	root.Children[a] = n1
+	root.EdgeVisits[a] = 10
+	n1.Children[b], n1.Children[c] = n2, n3
+	n1.EdgeVisits[b], n1.EdgeVisits[c] = 3, 6
+	n3.Children[a] = n1 // 置换形成的环
+	n3.EdgeVisits[a] = 2
+	n1.UtilityAvg = 0.25
+
+	pv, u := mctsPV(root, a)
+	if len(pv) != 3 || pv[0] != a || pv[1] != c || pv[2] != a {
+		t.Fatalf("pv = %v", pv)
+	}
+	if u != 0.25 {
+		t.Fatalf("leaf utility = %v, want 0.25", u)
+	}
+}
+
+func TestVCFShortcutReturnsForcingLine(t *testing.T) {
+	pos, err := xionghan.DecodePosition("i.a.h...h...i/...bcdedcb.../..........a../.....f.....f./..g.g.F.g.g../jF..........j/............./J...........J/..G.G.G.G.G../............./............./...BCDEDCB.../I.A.H...H.A.I w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine()
+	res := e.Search(pos, SearchConfig{MaxDepth: 3})
+	if len(res.PV) < 2 || res.PV[0] != res.BestMove {
+		t.Fatalf("pv = %v, want the forcing line starting with %v", res.PV, res.BestMove)
+	}
+	cur := pos.Clone()
+	for i, mv := range res.PV {
+		if !cur.IsLegalMove(mv) {
+			t.Fatalf("pv[%d] = %v is illegal", i, mv)
+		}
+		cur.MakeMove(mv)
+	}
+	if cur.KingExists(xionghan.Black) || res.PVScore != scoreInf {
+		t.Fatalf("pv should end with the black king captured: %v, pv score %d", res.PV, res.PVScore)
+	}
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"sort"
+
+	"xionghan/internal/xionghan"
+)
+
+const (
+	qsDefaultDepth = 6  // 静态搜索默认最多延伸的层数
+	qsDefaultNodes = 64 // 每个叶子默认的静态搜索节点上限
+	qsCheckPlies   = 1  // 前几层静态搜索除吃子外还搜将军
+)
+
+// qsSettings 一次搜索里静态搜索的设置，由 SearchConfig 得到
+type qsSettings struct {
+	enabled  bool
+	maxDepth int
+	maxNodes int
+}
+
+func newQSSettings(cfg SearchConfig) qsSettings {
+	s := qsSettings{
+		enabled:  cfg.EnableQuiescence,
+		maxDepth: cfg.QuiescenceDepth,
+		maxNodes: cfg.QuiescenceNodes,
+	}
+	if s.maxDepth <= 0 {
+		s.maxDepth = qsDefaultDepth
+	}
+	if s.maxNodes <= 0 {
+		s.maxNodes = qsDefaultNodes
+	}
+	return s
+}
+
+// quiesce alpha-beta 叶子上的静态搜索：继续搜吃子（SEE 不亏的）和将军，直到局面平静。
+// 与 alphaBeta 一样返回红方视角的分数。
+func (e *Engine) quiesce(pos *xionghan.Position, alpha, beta int, rep *repetitionState) int {
+	budget := e.qs.maxNodes
+	if pos.SideToMove == xionghan.Red {
+		return e.qsearch(pos, alpha, beta, 0, rep, &budget)
+	}
+	return -e.qsearch(pos, -beta, -alpha, 0, rep, &budget)
+}
+
+// qsearch 负极大值形式的静态搜索，分数为轮走方视角
+func (e *Engine) qsearch(pos *xionghan.Position, alpha, beta, ply int, rep *repetitionState, budget *int) int {
+	e.qnodes++
+	*budget--
+	if e.aborted() {
+		// NN 故障或搜索被取消：结果作废
+		return 0
+	}
+	side := pos.SideToMove
+	sign := 1
+	if side == xionghan.Black {
+		sign = -1
+	}
+
+	// 胜负分按 ply 递减：越快赢越好、越慢输越好
+	captures := pos.GenerateCaptures()
+	for _, mv := range captures {
+		if target := pos.Board.Squares[mv.To]; target.Type() == xionghan.PieceKing {
+			return scoreInf - ply
+		}
+	}
+	if r, ok := e.Tablebases.Probe(pos); ok {
+		return sign * tbScore(r, side)
+	}
+
+	var moves []xionghan.Move
+	best := -scoreInf
+	if pos.IsInCheck(side) {
+		// 被将军时不能站着不动：搜全部合法的应将着法（不过过滤管线，叶子上太贵）
+		if ply >= e.qs.maxDepth || *budget <= 0 {
+			return sign * e.eval(pos)
+		}
+		moves = pos.GenerateLegalMoves()
+		if len(moves) == 0 {
+			// 无子可动判负
+			return -(scoreInf - ply)
+		}
+		orderMovesByTacticalPriority(pos, moves)
+	} else {
+		standPat := sign * e.eval(pos)
+		if e.aborted() {
+			return 0
+		}
+		if standPat >= beta || ply >= e.qs.maxDepth || *budget <= 0 {
+			return standPat
+		}
+		best = standPat
+		if standPat > alpha {
+			alpha = standPat
+		}
+		moves = qsMoves(pos, captures, ply < qsCheckPlies)
+	}
+
+	searched := false
+	for _, mv := range moves {
+		if !pos.MakeMove(mv) {
+			continue
+		}
+		pushed := false
+		var childHash uint64
+		if rep != nil && rep.enabled {
+			childHash = pos.EnsureHash()
+			if !rep.canEnter(childHash, moveGivesCheck(pos)) {
+				pos.UnmakeMove()
+				continue
+			}
+			rep.push(childHash)
+			pushed = true
+		}
+
+		searched = true
+		score := -e.qsearch(pos, -beta, -alpha, ply+1, rep, budget)
+		pos.UnmakeMove()
+		if pushed {
+			rep.pop(childHash)
+		}
+		if e.aborted() {
+			return 0
+		}
+		if score > best {
+			best = score
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta || *budget <= 0 {
+			break
+		}
+	}
+	if !searched && best == -scoreInf {
+		// 应将着法都因重复禁手走不了
+		return sign * e.eval(pos)
+	}
+	return best
+}
+
+// qsMoves 静态搜索要搜的着法：SEE 不亏的吃子（按 SEE 从大到小），需要时再加上不吃子的将军
+func qsMoves(pos *xionghan.Position, captures []xionghan.Move, withChecks bool) []xionghan.Move {
+	type scored struct {
+		mv  xionghan.Move
+		see int
+	}
+	items := make([]scored, 0, len(captures))
+	for _, mv := range captures {
+		if see := SEE(pos, mv); see >= 0 {
+			items = append(items, scored{mv, see})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].see > items[j].see })
+	moves := make([]xionghan.Move, 0, len(items))
+	for _, it := range items {
+		moves = append(moves, it.mv)
+	}
+	if withChecks {
+		for _, mv := range pos.GenerateChecks() {
+			if pos.Board.Squares[mv.To] == 0 {
+				moves = append(moves, mv)
+			}
+		}
+	}
+	return moves
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"testing"
+
+	"xionghan/internal/xionghan"
+)
+
+func TestQuiescenceTerminalScores(t *testing.T) {
+	e := NewEngine()
+	e.qs = newQSSettings(SearchConfig{EnableQuiescence: true})
+
+	// 红方能直接吃王
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(1, 0): red(xionghan.PieceRook),
+	})
+	if got := e.quiesce(pos, -scoreInf, scoreInf, newRepetitionState(SearchConfig{})); got != scoreInf {
+		t.Fatalf("king capture: got %d, want %d", got, scoreInf)
+	}
+
+	// 红方被将军，两步都走不出车的控制：应将着法全部被吃王
+	pos = seePosition(map[int]xionghan.Piece{
+		sq(11, 0): black(xionghan.PieceRook),
+		sq(10, 0): black(xionghan.PieceRook),
+	})
+	e.qnodes = 0
+	// 应将后下一步被吃王：比当场吃王慢一步
+	if got := e.quiesce(pos, -scoreInf, scoreInf, newRepetitionState(SearchConfig{})); got != -(scoreInf - 1) {
+		t.Fatalf("no escape from check: got %d, want %d", got, -(scoreInf - 1))
+	}
+	if e.hasNNFailure() {
+		t.Fatal("terminal positions should not need the NN")
+	}
+	if e.qnodes < 2 {
+		t.Fatalf("qnodes = %d, want the evasions to be counted", e.qnodes)
+	}
+}
+
+func TestQuiescenceHonoursCancel(t *testing.T) {
+	e := NewEngine()
+	e.qs = newQSSettings(SearchConfig{EnableQuiescence: true})
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(11, 0): black(xionghan.PieceRook),
+		sq(10, 0): black(xionghan.PieceRook),
+	})
+	e.Stop()
+	if got := e.quiesce(pos, -scoreInf, scoreInf, newRepetitionState(SearchConfig{})); got != 0 {
+		t.Fatalf("cancelled quiescence returned %d", got)
+	}
+	if e.qnodes != 1 {
+		t.Fatalf("qnodes = %d, want to stop at the first node", e.qnodes)
+	}
+}
+
+func TestQuiescenceSettingsDefaults(t *testing.T) {
+	s := newQSSettings(SearchConfig{})
+	if s.enabled || s.maxDepth != qsDefaultDepth || s.maxNodes != qsDefaultNodes {
+		t.Fatalf("defaults = %+v", s)
+	}
+	s = newQSSettings(SearchConfig{EnableQuiescence: true, QuiescenceDepth: 3, QuiescenceNodes: 10})
+	if !s.enabled || s.maxDepth != 3 || s.maxNodes != 10 {
+		t.Fatalf("settings = %+v", s)
+	}
+}
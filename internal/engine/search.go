@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"math"
 	"math/rand"
 	"sort"
@@ -28,14 +29,39 @@ func init() {
 // 搜索配置
 type SearchConfig struct {
 	MaxDepth               int            // 最大搜索深度（ply）
-	TimeLimit              time.Duration  // 搜索时间上限（0 表示不限制）
+	TimeLimit              time.Duration  // 搜索时间上限（0 表示不限制）；给了 RemainingTime 时是额外的上限
 	EnableRepetitionFilter bool           // 是否启用重复局面禁手（搜索阶段）
 	RepetitionCount        map[uint64]int // 局面历史计数（包含当前局面）
 	RepetitionBanCount     int            // 达到该次数后禁手（例如 3）
+	Threads                int            // 搜索线程数（Lazy SMP）；0 用默认值，1 为单线程，结果可复现
+
+	// 对局计时：RemainingTime > 0 时由时间管理器分配本步用时（timeman.go），
+	// 局面不稳定时多用、alpha-beta 估计下一层搜不完或 MCTS 最佳着法已不会被超过时提前停
+	RemainingTime time.Duration // 己方剩余时间
+	Increment     time.Duration // 每步加秒
+	MovesToGo     int           // 距下次加时还要走的步数，0 表示剩余时间要用到终局
+
+	// alpha-beta 剪枝与排序启发，各自可以单独关闭（自对弈测 Elo 用）；EnableAllHeuristics 全部打开
+	EnablePVS        bool // 主变搜索：第一个着法全窗口，其余先用零窗口试探
+	EnableAspiration bool // 迭代加深时以上一层分数为中心的窄窗口
+	EnableKillers    bool // 杀手着法
+	EnableHistory    bool // 历史启发
+	EnableLMR        bool // 后期着法减层（按两阶段 NN 策略排序和减层）
+	EnableNullMove   bool // 空着剪枝
+
+	// 静态搜索：叶子节点继续搜吃子和将军，避免水平线效应
+	EnableQuiescence bool // 是否启用静态搜索
+	QuiescenceDepth  int  // 最多延伸的层数（<=0 用默认值）
+	QuiescenceNodes  int  // 每个叶子的节点上限（<=0 用默认值）
 
 	// MCTS 相关的参数
 	UseMCTS         bool // 是否使用 MCTS 搜索
 	MCTSSimulations int  // MCTS 仿真次数（Playouts）
+	MCTSMaxNodes    int  // MCTS 树的节点上限，超过时剪掉不常用的分支（<=0 用默认值）
+
+	// 搜索进度：非 nil 时 alpha-beta 每搜完一层、MCTS 每 ReportInterval 次仿真（<=0 用默认值）调用一次
+	Listener       SearchListener
+	ReportInterval int
 }
 
 // 搜索结果
@@ -45,9 +71,20 @@ type SearchResult struct {
 	WinProb  float32         // 红方胜率
 	Depth    int             // 实际搜索到的深度
 	Nodes    int64           // 节点数
+	QNodes   int64           // 静态搜索节点数（不计入 Nodes）
 	TimeUsed time.Duration   // 花费时间
-	PV       []xionghan.Move // 主变（这里只放了根节点的最佳着法，其它你以后可以扩展）
+	PV       []xionghan.Move // 主变：从根的最佳着法开始的预期着法序列，长度即 len(PV)
+	PVScore  int             // 主变末端局面的评估（红方视角）
 	NNFailed bool            // 搜索期间 NN 推理是否失败
+	Stopped  bool            // 搜索被取消（ctx 或 Stop）：结果是取消前已完成的部分
+
+	// MCTS 树的统计：搜索结束时的节点数、开始时从之前的搜索沿用的节点数、本次剪掉的节点数
+	TreeNodes   int64
+	ReusedNodes int64
+	PrunedNodes int64
+
+	// 已证明的胜负（MCTS-Solver）：N>0 轮到走的一方 N 步（半回合）内吃王获胜，-N 为 N 步内被吃王，0 未证明
+	MateIn int
 }
 
 // 从红方视角的评价：正数红方好，负数黑方好
@@ -75,6 +112,10 @@ func Evaluate(pos *xionghan.Position) int {
 
 // 搜索层调用这个
 func (e *Engine) eval(pos *xionghan.Position) int {
+	if e.cancelled() {
+		// 已取消，结果会被丢弃：不再发起 NN 推理
+		return 0
+	}
 	if e.nn == nil {
 		// Handcrafted eval is disabled; NN is required.
 		e.markNNFailure()
@@ -99,50 +140,41 @@ func (e *Engine) eval(pos *xionghan.Position) int {
 	return 0
 }
 
-// FilterVCFMoves 过滤掉会导致被对方连将绝杀或直接吃王的走法。
-func (e *Engine) FilterVCFMoves(pos *xionghan.Position, moves []xionghan.Move) []xionghan.Move {
-	if pos.TotalPieces() > 43 || len(moves) <= 1 {
-		return moves
-	}
-
-	var safeMoves []xionghan.Move
-	for _, mv := range moves {
-		// 0. 绝杀判定：如果这一步直接吃掉对方的王，那绝对合法且必须走
-		target := pos.Board.Squares[mv.To]
-		if target != 0 && target.Type() == xionghan.PieceKing {
-			safeMoves = append(safeMoves, mv)
-			continue
-		}
-
-		nextPos, ok := pos.ApplyMove(mv)
-		if !ok {
-			continue
-		}
-
-		// 1. 检查对手是否能在下一手直接吃王（预防非将军的杀招）
-		if e.CanCaptureKingNext(nextPos) {
-			continue
-		}
-
-		// 2. 检查对手是否能进入 VCF 连将杀（预防必杀局）
-		vcf := e.VCFSearch(nextPos, vcfDepthFilter)
-		if vcf.CanWin {
-			continue
-		}
-
-		safeMoves = append(safeMoves, mv)
-	}
+// Search 不可取消的搜索，等同于 SearchContext(context.Background(), pos, cfg)
+func (e *Engine) Search(pos *xionghan.Position, cfg SearchConfig) SearchResult {
+	return e.SearchContext(context.Background(), pos, cfg)
+}
 
-	// 如果所有走法都导致输棋，则不进行过滤，维持原样（等死）
-	if len(safeMoves) == 0 {
-		return moves
-	}
-	return safeMoves
+// SearchContext 根节点搜索：带简单迭代加深（根节点内部并行）。
+// ctx 取消或调用 Stop 后尽快返回：alpha-beta 给出上一个搜完的深度的结果，MCTS 给出已做的仿真的结果。
+// 正在后台思考（ponder.go）时先停下它。
+func (e *Engine) SearchContext(ctx context.Context, pos *xionghan.Position, cfg SearchConfig) SearchResult {
+	e.StopPonder()
+	return e.search(ctx, pos, cfg)
 }
 
-// 根节点搜索：带简单迭代加深（根节点内部并行）
-func (e *Engine) Search(pos *xionghan.Position, cfg SearchConfig) SearchResult {
+func (e *Engine) search(ctx context.Context, pos *xionghan.Position, cfg SearchConfig) SearchResult {
 	e.resetNNAbort()
+	e.resetCancel()
+	if ctx.Err() != nil {
+		e.Stop()
+	} else if done := ctx.Done(); done != nil {
+		// 返回前等监视 goroutine 退出，免得它在下一次搜索开始后才置位取消标志
+		finished := make(chan struct{})
+		exited := make(chan struct{})
+		defer func() {
+			close(finished)
+			<-exited
+		}()
+		go func() {
+			defer close(exited)
+			select {
+			case <-done:
+				e.Stop()
+			case <-finished:
+			}
+		}()
+	}
 
 	if cfg.UseMCTS && cfg.MCTSSimulations > 0 {
 		return e.runMCTS(pos, cfg)
@@ -150,9 +182,13 @@ func (e *Engine) Search(pos *xionghan.Position, cfg SearchConfig) SearchResult {
 
 	rep := newRepetitionState(cfg)
 
+	// 0. 残局库：局面在库中时直接按库走
+	if res, ok := e.probeRootTablebase(pos, rep); ok {
+		return res
+	}
+
 	// 1. 绝杀判定：直接吃王
-	moves := pos.GenerateLegalMoves(true)
-	moves = e.FilterLeiLockedMoves(pos, moves)
+	moves := e.quickMoves(pos)
 	for _, mv := range moves {
 		targetPiece := pos.Board.Squares[mv.To]
 		if targetPiece != 0 && targetPiece.Type() == xionghan.PieceKing {
@@ -170,6 +206,7 @@ func (e *Engine) Search(pos *xionghan.Position, cfg SearchConfig) SearchResult {
 				Nodes:    1,
 				TimeUsed: 0,
 				PV:       []xionghan.Move{mv},
+				PVScore:  scoreInf,
 			}
 		}
 	}
@@ -184,6 +221,7 @@ func (e *Engine) Search(pos *xionghan.Position, cfg SearchConfig) SearchResult {
 					goto skipVCFShortcut
 				}
 			}
+			pv, pvScore := e.vcfPV(pos, vcfRes.Move, rep, vcfDepthRoot, 900000)
 			return SearchResult{
 				BestMove: vcfRes.Move,
 				Score:    900000,
@@ -191,7 +229,8 @@ func (e *Engine) Search(pos *xionghan.Position, cfg SearchConfig) SearchResult {
 				Depth:    vcfDepthRoot,
 				Nodes:    100,
 				TimeUsed: 0,
-				PV:       []xionghan.Move{vcfRes.Move},
+				PV:       pv,
+				PVScore:  pvScore,
 			}
 		}
 	}
@@ -207,15 +246,18 @@ skipVCFShortcut:
 	}
 	start := time.Now()
 	atomic.StoreInt64(&e.nodes, 0)
+	atomic.StoreInt64(&e.qnodes, 0)
+	e.qs = newQSSettings(cfg)
+	e.threads = resolveThreads(cfg.Threads)
+	e.heur = newSearchHeuristics(cfg)
+	e.tt.newSearch()
 
 	bestMove := xionghan.Move{}
 	bestScore := 0
 	bestDepth := 0
 
-	deadline := time.Time{}
-	if cfg.TimeLimit > 0 {
-		deadline = start.Add(cfg.TimeLimit)
-	}
+	tm := newTimeManager(cfg, start)
+	deadline := tm.deadline()
 
 	for depth := 1; depth <= cfg.MaxDepth; depth++ {
 		if e.hasNNFailure() {
@@ -223,10 +265,16 @@ skipVCFShortcut:
 			bestDepth = 0
 			break
 		}
-		if !deadline.IsZero() && time.Now().After(deadline) {
+		if e.cancelled() || (!deadline.IsZero() && time.Now().After(deadline)) {
 			break
 		}
-		score, move := e.alphaBetaRoot(pos, depth, -scoreInf, scoreInf, deadline, rep)
+		var score int
+		var move xionghan.Move
+		if e.heur.aspiration && bestDepth > 0 && bestScore > -decisiveScore && bestScore < decisiveScore {
+			score, move = e.aspirationRoot(pos, depth, bestScore, deadline, rep)
+		} else {
+			score, move = e.alphaBetaRoot(pos, depth, -scoreInf, scoreInf, deadline, rep)
+		}
 		if e.hasNNFailure() {
 			bestMove = xionghan.Move{}
 			bestDepth = 0
@@ -239,19 +287,24 @@ skipVCFShortcut:
 		bestMove = move
 		bestScore = score
 		bestDepth = depth
+		if cfg.Listener != nil {
+			e.reportIteration(cfg.Listener, pos, rep, depth, score, move, start)
+		}
+		if tm.iterationDone(move, score) {
+			break
+		}
 	}
 
 	// Default: map search score (red-positive) to [0,1].
-	winProb := (float32(bestScore)/10000.0 + 1.0) / 2.0
-	if winProb < 0 {
-		winProb = 0
-	}
-	if winProb > 1 {
-		winProb = 1
+	winProb := scoreWinProb(bestScore)
+	var pv []xionghan.Move
+	pvScore := bestScore
+	if bestDepth > 0 {
+		pv, pvScore = e.principalVariation(pos, bestMove, rep, bestDepth, bestScore)
 	}
 	// UI label is "Red Win %". Prefer root NN red-win probability (fixed color view)
 	// to avoid shallow minimax max/min amplification that can look overly extreme.
-	if e.UseNN && e.nn != nil && !e.hasNNFailure() {
+	if e.UseNN && e.nn != nil && !e.hasNNFailure() && !e.cancelled() {
 		if root, err := e.nn.Evaluate(pos); err == nil {
 			winProb = root.LossProb // fixed red win prob
 		}
@@ -263,9 +316,12 @@ skipVCFShortcut:
 		WinProb:  winProb,
 		Depth:    bestDepth,
 		Nodes:    atomic.LoadInt64(&e.nodes),
+		QNodes:   atomic.LoadInt64(&e.qnodes),
 		TimeUsed: time.Since(start),
-		PV:       []xionghan.Move{bestMove},
+		PV:       pv,
+		PVScore:  pvScore,
 		NNFailed: e.hasNNFailure(),
+		Stopped:  e.cancelled(),
 	}
 }
 
@@ -275,11 +331,7 @@ func (e *Engine) alphaBetaRoot(pos *xionghan.Position, depth int, alpha, beta in
 		return 0, xionghan.Move{}
 	}
 
-	moves := pos.GenerateLegalMoves(true)
-	moves = e.FilterLeiLockedMoves(pos, moves)
-	moves = e.FilterUrgentPawnThreatMoves(pos, moves)
-	moves = e.FilterBlunderMoves(pos, moves)
-	moves = e.FilterVCFMoves(pos, moves)
+	moves := e.FilteredMoves(pos)
 	if len(moves) == 0 {
 		// 没招就直接返回静态评估
 		return e.eval(pos), xionghan.Move{}
@@ -287,68 +339,11 @@ func (e *Engine) alphaBetaRoot(pos *xionghan.Position, depth int, alpha, beta in
 
 	// 1. 两阶段推理进行排序
 	if e.UseNN && e.nn != nil {
-		// Stage 0: 获取 From 概率
-		res0, err := e.nn.EvaluateWithStage(pos, 0, -1)
-		if err == nil && res0 != nil {
-			// 为了效率，我们将 From 位置相同的招法分组，并对每组调用一次 Stage 1
-			fromGroups := make(map[int][]int) // From -> indices in moves
-			for i, mv := range moves {
-				fromGroups[mv.From] = append(fromGroups[mv.From], i)
-			}
-
-			type moveScore struct {
-				idx  int
-				prob float32
-			}
-			scores := make([]moveScore, len(moves))
-			type stage1Result struct {
-				from int
-				res  *NNResult
-				err  error
-			}
-			stage1Ch := make(chan stage1Result, len(fromGroups))
-			for from := range fromGroups {
-				from := from
-				go func() {
-					res1, err := e.nn.EvaluateWithStage(pos, 1, from)
-					stage1Ch <- stage1Result{from: from, res: res1, err: err}
-				}()
-			}
-			stage1ByFrom := make(map[int]*NNResult, len(fromGroups))
-			for i := 0; i < len(fromGroups); i++ {
-				r := <-stage1Ch
-				if r.err != nil || r.res == nil {
-					e.markNNFailure()
-					return 0, xionghan.Move{}
-				}
-				stage1ByFrom[r.from] = r.res
-			}
-
-			for from, indices := range fromGroups {
-				fromProb := res0.Policy[from]
-				res1 := stage1ByFrom[from]
-				for _, idx := range indices {
-					toProb := res1.Policy[moves[idx].To]
-					scores[idx] = moveScore{idx: idx, prob: fromProb * toProb}
-				}
-			}
-
-			// IMPORTANT:
-			// Do not sort `moves` directly by `scores[i]`, because indices in the
-			// comparator are positions *during sorting*, while `scores` is indexed
-			// by original move order. That would decouple move/prob pairing.
-			sort.SliceStable(scores, func(i, j int) bool {
-				return scores[i].prob > scores[j].prob
-			})
-			sortedMoves := make([]xionghan.Move, len(moves))
-			for i, sc := range scores {
-				sortedMoves[i] = moves[sc.idx]
-			}
-			copy(moves, sortedMoves)
-		} else {
-			e.markNNFailure()
+		priors, ok := e.policyPriors(pos, moves)
+		if !ok {
 			return 0, xionghan.Move{}
 		}
+		sortMovesByPriors(moves, priors)
 	} else {
 		// 没有 NN 时，把吃子招提前一点
 		orderMovesByCaptureFirst(pos, moves)
@@ -357,7 +352,7 @@ func (e *Engine) alphaBetaRoot(pos *xionghan.Position, depth int, alpha, beta in
 	// 根节点用 TT 排序；key 会包含重复状态签名，避免历史路径污染。
 	side := pos.SideToMove
 	key := ttKeyForPosition(pos, rep)
-	if entry, ok := e.tt[key]; ok {
+	if entry, ok := e.tt.probe(key); ok {
 		// 把 entry.Move 提到前面
 		for i := range moves {
 			if moves[i].From == entry.Move.From && moves[i].To == entry.Move.To {
@@ -368,13 +363,8 @@ func (e *Engine) alphaBetaRoot(pos *xionghan.Position, depth int, alpha, beta in
 	}
 	orderMovesByTacticalPriority(pos, moves)
 
-	// 先同步生成所有子局面，避免并发操作 pos
-	type childNode struct {
-		move  xionghan.Move
-		child *xionghan.Position
-		hash  uint64
-	}
-	children := make([]childNode, 0, len(moves))
+	// 先同步生成所有子局面，各搜索线程再各自复制
+	children := make([]rootChild, 0, len(moves))
 	for _, mv := range moves {
 		child, ok := pos.ApplyMove(mv)
 		if !ok {
@@ -384,7 +374,7 @@ func (e *Engine) alphaBetaRoot(pos *xionghan.Position, depth int, alpha, beta in
 		if rep != nil && rep.enabled && !rep.canEnter(childHash, moveGivesCheck(child)) {
 			continue
 		}
-		children = append(children, childNode{
+		children = append(children, rootChild{
 			move:  mv,
 			child: child,
 			hash:  childHash,
@@ -394,118 +384,108 @@ func (e *Engine) alphaBetaRoot(pos *xionghan.Position, depth int, alpha, beta in
 	if len(children) == 0 {
 		return e.eval(pos), xionghan.Move{}
 	}
-	// 只有一个着法时没必要并行，直接用局部 Engine 跑一次
-	if len(children) == 1 {
-		local := &Engine{
-			tt:             make(map[uint64]ttEntry, 1<<14),
-			blunderTT:      make([]uint64, 1<<13),
-			blunderReplyTT: make([]uint64, 1<<13),
-			nn:             e.nn,
-			UseNN:          e.UseNN,
-			nnAbort:        e.nnAbort,
-			nnCache:        e.nnCache,
-		}
-		localRep := rep.clone()
-		localRep.push(children[0].hash)
-		score := local.alphaBeta(children[0].child, depth-1, alpha, beta, deadline, localRep)
-		if local.nodes != 0 {
-			atomic.AddInt64(&e.nodes, local.nodes)
-		}
-		bestMove := children[0].move
-		// 根节点也存一下 TT（全局 tt 仍然单线程访问）
-		e.storeTT(key, depth, score, ttExact, bestMove)
-		return score, bestMove
-	}
-
-	// 有多个着法：并行
-	type rootResult struct {
-		move  xionghan.Move
-		score int
-	}
-
-	results := make(chan rootResult, len(children))
-
-	for _, ch := range children {
-		ch := ch
-		go func() {
-			// 每个 goroutine 用自己的 Engine/TT，避免加锁和 map 竞争
-			local := &Engine{
-				tt:             make(map[uint64]ttEntry, 1<<14),
-				blunderTT:      make([]uint64, 1<<13),
-				blunderReplyTT: make([]uint64, 1<<13),
-				nn:             e.nn,
-				UseNN:          e.UseNN,
-				nnAbort:        e.nnAbort,
-				nnCache:        e.nnCache,
-			}
-			localRep := rep.clone()
-			localRep.push(ch.hash)
-			score := local.alphaBeta(ch.child, depth-1, alpha, beta, deadline, localRep)
-			if local.nodes != 0 {
-				atomic.AddInt64(&e.nodes, local.nodes)
-			}
-			results <- rootResult{
-				move:  ch.move,
-				score: score,
-			}
-		}()
-	}
-
-	bestMove := xionghan.Move{}
-	rootResults := make([]rootResult, 0, len(children))
-	for i := 0; i < len(children); i++ {
-		r := <-results
-		rootResults = append(rootResults, r)
-	}
-
-	if len(rootResults) == 0 {
-		return e.eval(pos), xionghan.Move{}
-	}
 
-	if side == xionghan.Red {
-		sort.SliceStable(rootResults, func(i, j int) bool {
-			return rootResults[i].score > rootResults[j].score
-		})
-	} else {
-		sort.SliceStable(rootResults, func(i, j int) bool {
-			return rootResults[i].score < rootResults[j].score
-		})
+	rootResults := e.searchRootSMP(children, side, depth, alpha, beta, deadline, rep)
+	if e.hasNNFailure() || len(rootResults) == 0 {
+		return 0, xionghan.Move{}
 	}
 
+	// 只有准确分数（落在窗口内）的着法参与排序和随机
 	best := rootResults[0]
-	if len(rootResults) >= 2 {
-		gap := rootResults[0].score - rootResults[1].score
+	exact := make([]rootResult, 0, len(rootResults))
+	for _, r := range rootResults {
+		if betterForSide(side, r.score, best.score) {
+			best = r
+		}
+		if r.exact {
+			exact = append(exact, r)
+		}
+	}
+	if e.threads > 1 && len(exact) >= 2 {
+		if side == xionghan.Red {
+			sort.SliceStable(exact, func(i, j int) bool {
+				return exact[i].score > exact[j].score
+			})
+		} else {
+			sort.SliceStable(exact, func(i, j int) bool {
+				return exact[i].score < exact[j].score
+			})
+		}
+		gap := exact[0].score - exact[1].score
 		if gap < 0 {
 			gap = -gap
 		}
 		if gap <= rootTopTwoRandomGap && rand.Intn(2) == 1 {
-			best = rootResults[1]
+			best = exact[1]
 		}
 	}
-	bestMove = best.move
+	bestMove := best.move
 	bestScore := best.score
-	if e.hasNNFailure() {
-		return 0, xionghan.Move{}
-	}
 
 	if bestMove.From == 0 && bestMove.To == 0 {
 		// 理论上不会走到这里，兜底一下
 		return e.eval(pos), xionghan.Move{}
 	}
 
-	// 根节点存 TT（全局 tt 依然只有主 goroutine 访问）
-	e.storeTT(key, depth, bestScore, ttExact, bestMove)
+	// 根节点存 TT（期望窗口下可能只是界）
+	flag := ttExact
+	if bestScore <= alpha {
+		flag = ttUpperBound
+	} else if bestScore >= beta {
+		flag = ttLowerBound
+	}
+	e.storeTT(key, depth, bestScore, flag, bestMove)
 	return bestScore, bestMove
 }
 
-// 内部递归：标准 alpha-beta（在并行版本里由每个局部 Engine 独享调用）
+// aspirationRoot 以上一层的分数为中心用窄窗口搜根，落在窗口外时放宽重搜；
+// 重搜前已超时则返回空着法，沿用上一层的结果。
+func (e *Engine) aspirationRoot(pos *xionghan.Position, depth, prev int, deadline time.Time, rep *repetitionState) (int, xionghan.Move) {
+	delta := aspirationDelta
+	alpha, beta := prev-delta, prev+delta
+	for {
+		score, move := e.alphaBetaRoot(pos, depth, alpha, beta, deadline, rep)
+		if e.hasNNFailure() || (move.From == 0 && move.To == 0) {
+			return score, move
+		}
+		failLow := score <= alpha && alpha > -scoreInf
+		failHigh := score >= beta && beta < scoreInf
+		if !failLow && !failHigh {
+			return score, move
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return score, xionghan.Move{}
+		}
+		delta *= 4
+		if delta > aspirationMaxDelta {
+			alpha, beta = -scoreInf, scoreInf
+			continue
+		}
+		if failLow {
+			alpha = prev - delta
+		} else {
+			beta = prev + delta
+		}
+	}
+}
+
+// 内部递归：标准 alpha-beta（由每个搜索线程的局部 Engine 调用，置换表共享）
+// pos 在搜索过程中被就地 MakeMove/UnmakeMove，返回时已还原。
 func (e *Engine) alphaBeta(pos *xionghan.Position, depth int, alpha, beta int, deadline time.Time, rep *repetitionState) int {
 	e.nodes++
-	if e.hasNNFailure() {
+	if e.aborted() {
 		return 0
 	}
 
+	// 残局库命中：精确胜负，不再往下搜
+	if r, ok := e.Tablebases.Probe(pos); ok {
+		return tbScore(r, pos.SideToMove)
+	}
+
 	if depth <= 0 {
+		if e.qs.enabled {
+			return e.quiesce(pos, alpha, beta, rep)
+		}
 		return e.eval(pos)
 	}
 	if !deadline.IsZero() && time.Now().After(deadline) {
@@ -516,7 +496,7 @@ func (e *Engine) alphaBeta(pos *xionghan.Position, depth int, alpha, beta int, d
 	key := ttKeyForPosition(pos, rep)
 	origAlpha, origBeta := alpha, beta
 	ttMove := xionghan.Move{}
-	if entry, ok := e.tt[key]; ok {
+	if entry, ok := e.tt.probe(key); ok {
 		ttMove = entry.Move
 		if entry.Depth >= depth {
 			switch entry.Flag {
@@ -543,109 +523,155 @@ func (e *Engine) alphaBeta(pos *xionghan.Position, depth int, alpha, beta int, d
 		}
 	}
 
-	moves := pos.GenerateLegalMoves(true)
-	moves = e.FilterLeiLockedMoves(pos, moves)
-	moves = e.FilterUrgentPawnThreatMoves(pos, moves)
-	moves = e.FilterBlunderMoves(pos, moves)
-	moves = e.FilterVCFMoves(pos, moves)
+	side := pos.SideToMove
+	// 以下用轮走方视角的窗口 (lo, hi) 和分数，存 TT 时再换回红方视角
+	sign := 1
+	lo, hi := alpha, beta
+	if side == xionghan.Black {
+		sign = -1
+		lo, hi = -beta, -alpha
+	}
+	search := func(d, lo, hi int) int {
+		if side == xionghan.Red {
+			return e.alphaBeta(pos, d, lo, hi, deadline, rep)
+		}
+		return -e.alphaBeta(pos, d, -hi, -lo, deadline, rep)
+	}
+	inCheck := (e.heur.nullMove || e.heur.lmr) && pos.IsInCheck(side)
+
+	// 空着剪枝：让对方连走一步仍然 >= hi，说明这里多半会截断。
+	// 被将军、已分胜负、只剩王士相兵（容易“谁走谁亏”）时不做，空着之下也不再做空着。
+	if e.heur.nullMove && !e.inNull && !inCheck && depth >= nullMoveMinDepth &&
+		hi < decisiveScore && lo > -decisiveScore && hasNullMoveMaterial(pos, side) {
+		r := nullMoveReduction
+		if depth >= 6 {
+			r++
+		}
+		e.inNull = true
+		pos.MakeNullMove()
+		score := search(depth-1-r, hi-1, hi)
+		pos.UnmakeMove()
+		e.inNull = false
+		if e.aborted() {
+			return 0
+		}
+		if score >= hi {
+			return sign * hi
+		}
+	}
+
+	moves := e.FilteredMoves(pos)
 	if len(moves) == 0 {
 		// 没招，简单直接评估（以后可以做将死检测）
 		return e.eval(pos)
 	}
 
-	side := pos.SideToMove
+	ply := pos.Ply()
 	orderMovesByCaptureFirst(pos, moves)
+	// NN 先验：LMR 按它排序和决定减层（只在剩余层数够时算，推理开销大）
+	var priors []float32
+	if e.heur.lmr && depth >= lmrMinDepth && e.UseNN && e.nn != nil {
+		var ok bool
+		if priors, ok = e.policyPriors(pos, moves); !ok {
+			return 0
+		}
+	}
+	if priors != nil || e.heur.killers || e.heur.history {
+		e.orderQuietMoves(pos, moves, priors, ply)
+	}
 	if ttMove.From != 0 || ttMove.To != 0 {
 		for i := range moves {
 			if moves[i].From == ttMove.From && moves[i].To == ttMove.To {
 				moves[0], moves[i] = moves[i], moves[0]
+				if priors != nil {
+					priors[0], priors[i] = priors[i], priors[0]
+				}
 				break
 			}
 		}
 	}
-	orderMovesByTacticalPriority(pos, moves)
+	if priors == nil {
+		orderMovesByTacticalPriority(pos, moves)
+	}
 
-	var bestScore int
+	bestScore := math.MinInt
 	bestMove := xionghan.Move{}
-	if side == xionghan.Red {
-		bestScore = math.MinInt
-		for i := range moves {
-			child, ok := pos.ApplyMove(moves[i])
-			if !ok {
-				continue
-			}
-
-			pushed := false
-			var childHash uint64
-			if rep != nil && rep.enabled {
-				childHash = child.EnsureHash()
-				if !rep.canEnter(childHash, moveGivesCheck(child)) {
-					continue
-				}
-				rep.push(childHash)
-				pushed = true
-			}
-
-			score := e.alphaBeta(child, depth-1, alpha, beta, deadline, rep)
-			if pushed {
-				rep.pop(childHash)
-			}
-			if e.hasNNFailure() {
-				return 0
-			}
-			if score > bestScore {
-				bestScore = score
-				bestMove = moves[i]
-			}
-			if score > alpha {
-				alpha = score
-			}
-			if alpha >= beta {
-				break
-			}
+	searched := 0
+	for i, mv := range moves {
+		capture := pos.Board.Squares[mv.To] != 0
+		if !pos.MakeMove(mv) {
+			continue
 		}
-	} else {
-		bestScore = math.MaxInt
-		for i := range moves {
-			child, ok := pos.ApplyMove(moves[i])
-			if !ok {
+
+		givesCheck := ((rep != nil && rep.enabled) || e.heur.lmr) && moveGivesCheck(pos)
+		pushed := false
+		var childHash uint64
+		if rep != nil && rep.enabled {
+			childHash = pos.EnsureHash()
+			if !rep.canEnter(childHash, givesCheck) {
+				pos.UnmakeMove()
 				continue
 			}
+			rep.push(childHash)
+			pushed = true
+		}
 
-			pushed := false
-			var childHash uint64
-			if rep != nil && rep.enabled {
-				childHash = child.EnsureHash()
-				if !rep.canEnter(childHash, moveGivesCheck(child)) {
-					continue
-				}
-				rep.push(childHash)
-				pushed = true
+		// 后期着法减层：不吃子、不将军、不在被将军中、不是杀手着法
+		r := 0
+		if e.heur.lmr && !capture && !givesCheck && !inCheck && e.isKiller(mv, ply) == 0 {
+			prior := float32(-1)
+			if priors != nil {
+				prior = priors[i]
 			}
+			r = lmrReduction(depth, searched, prior)
+		}
 
-			score := e.alphaBeta(child, depth-1, alpha, beta, deadline, rep)
-			if pushed {
-				rep.pop(childHash)
-			}
-			if e.hasNNFailure() {
-				return 0
+		var score int
+		switch {
+		case searched == 0:
+			score = search(depth-1, lo, hi)
+		case e.heur.pvs:
+			// 主变搜索：先用零窗口试探，能改进 lo 再用全窗口重搜
+			score = search(depth-1-r, lo, lo+1)
+			if r > 0 && score > lo {
+				score = search(depth-1, lo, lo+1)
 			}
-			if score < bestScore {
-				bestScore = score
-				bestMove = moves[i]
+			if score > lo && score < hi {
+				score = search(depth-1, lo, hi)
 			}
-			if score < beta {
-				beta = score
+		default:
+			score = search(depth-1-r, lo, hi)
+			if r > 0 && score > lo {
+				score = search(depth-1, lo, hi)
 			}
-			if alpha >= beta {
-				break
+		}
+		pos.UnmakeMove()
+		if pushed {
+			rep.pop(childHash)
+		}
+		if e.aborted() {
+			return 0
+		}
+		searched++
+		if score > bestScore {
+			bestScore = score
+			bestMove = mv
+		}
+		if score > lo {
+			lo = score
+		}
+		if lo >= hi {
+			if !capture {
+				e.recordCutoff(side, mv, ply, depth)
 			}
+			break
 		}
 	}
 
 	if bestMove.From == 0 && bestMove.To == 0 {
 		return e.eval(pos)
 	}
+	bestScore *= sign
 
 	flag := ttExact
 	if bestScore <= origAlpha {
@@ -654,7 +680,7 @@ func (e *Engine) alphaBeta(pos *xionghan.Position, depth int, alpha, beta int, d
 		flag = ttLowerBound
 	}
 
-	// 存入 TT（这里写的是局部 Engine 的 tt，不会有并发冲突）
+	// 存入 TT（共享置换表，无锁）
 	e.storeTT(key, depth, bestScore, flag, bestMove)
 	return bestScore
 }
@@ -674,7 +700,7 @@ func newRepetitionState(cfg SearchConfig) *repetitionState {
 	}
 	banCount := cfg.RepetitionBanCount
 	if banCount <= 1 {
-		banCount = 3
+		banCount = xionghan.DefaultRepetitionBanCount
 	}
 	capLimit := banCount - 1
 	if capLimit < 1 {
@@ -821,9 +847,8 @@ func orderMovesByCaptureFirst(pos *xionghan.Position, moves []xionghan.Move) {
 }
 
 // 战术优先排序：
-// 1) 优先搜索“移走被对方兵盯住的己方车/檑/炮/马”。
-// 2) 其次优先搜索“吃到对方车/檑/炮/马”。
-// 同类内部顺序：车 > 檑 > 炮 > 马。
+// 1) 优先搜索“移走被对方兵盯住的己方车/檑/炮/马”，顺序：车 > 檑 > 炮 > 马。
+// 2) 其次是交换不亏的吃子（SEE >= 0），净得越多越靠前；亏子的吃子不提前。
 func orderMovesByTacticalPriority(pos *xionghan.Position, moves []xionghan.Move) {
 	if len(moves) <= 1 {
 		return
@@ -834,8 +859,8 @@ func orderMovesByTacticalPriority(pos *xionghan.Position, moves []xionghan.Move)
 	type movePriority struct {
 		evacuate     bool
 		evacuateRank int
-		captureCore  bool
-		captureRank  int
+		captureGood  bool
+		captureSEE   int
 	}
 	type moveOrderItem struct {
 		mv xionghan.Move
@@ -858,13 +883,13 @@ func orderMovesByTacticalPriority(pos *xionghan.Position, moves []xionghan.Move)
 
 		target := pos.Board.Squares[mv.To]
 		if target != 0 && target.Side() == opp {
-			if pieceRank := tacticalPriorityRank(target.Type()); pieceRank > 0 {
-				items[i].pr.captureCore = true
-				items[i].pr.captureRank = pieceRank
+			if see := SEE(pos, mv); see >= 0 {
+				items[i].pr.captureGood = true
+				items[i].pr.captureSEE = see
 			}
 		}
 
-		if items[i].pr.evacuate || items[i].pr.captureCore {
+		if items[i].pr.evacuate || items[i].pr.captureGood {
 			hasPriority = true
 		}
 	}
@@ -882,11 +907,11 @@ func orderMovesByTacticalPriority(pos *xionghan.Position, moves []xionghan.Move)
 		if pi.evacuate && pi.evacuateRank != pj.evacuateRank {
 			return pi.evacuateRank > pj.evacuateRank
 		}
-		if pi.captureCore != pj.captureCore {
-			return pi.captureCore
+		if pi.captureGood != pj.captureGood {
+			return pi.captureGood
 		}
-		if pi.captureCore && pi.captureRank != pj.captureRank {
-			return pi.captureRank > pj.captureRank
+		if pi.captureGood && pi.captureSEE != pj.captureSEE {
+			return pi.captureSEE > pj.captureSEE
 		}
 		return false
 	})
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"xionghan/internal/xionghan"
+)
+
+func TestSearchContextCancelled(t *testing.T) {
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(6, 0):  red(xionghan.PieceRook),
+		sq(9, 3):  red(xionghan.PieceKnight),
+		sq(3, 10): black(xionghan.PieceCannon),
+	})
+	e := NewEngine()
+
+	// ctx 已取消：不发起 NN 推理，立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := e.SearchContext(ctx, pos, SearchConfig{MaxDepth: 4})
+	if !res.Stopped || res.NNFailed || res.Depth != 0 {
+		t.Fatalf("cancelled search: %+v", res)
+	}
+
+	// 搜索途中叫停：alpha-beta 直接返回，不写置换表
+	e.Stop()
+	rep := newRepetitionState(SearchConfig{})
+	if got := e.alphaBeta(pos, 3, -scoreInf, scoreInf, time.Time{}, rep); got != 0 || e.hasNNFailure() {
+		t.Fatalf("alphaBeta after Stop = %d, nn failure %v", got, e.hasNNFailure())
+	}
+	if _, ok := e.tt.probe(ttKeyForPosition(pos, rep)); ok {
+		t.Fatal("aborted search stored a TT entry")
+	}
+
+	// 下一次搜索清掉取消标志
+	e.Tablebases = testProber(t, "EAvE")
+	tbPos := seePosition(map[int]xionghan.Piece{sq(6, 0): red(xionghan.PieceRook)})
+	if res := e.Search(tbPos, SearchConfig{MaxDepth: 2}); res.Stopped || e.cancelled() || res.BestMove == (xionghan.Move{}) {
+		t.Fatalf("search after a cancelled one: %+v", res)
+	}
+}
@@ -0,0 +1,71 @@
+package engine
+
+import "xionghan/internal/xionghan"
+
+// seeValues 静态交换评估用的子力价值（下标为 PieceType）；王取极大值，吃王即结束交换
+var seeValues = [...]int{
+	xionghan.PieceNone:     0,
+	xionghan.PieceRook:     900,
+	xionghan.PieceKnight:   400,
+	xionghan.PieceCannon:   450,
+	xionghan.PieceElephant: 200,
+	xionghan.PieceAdvisor:  200,
+	xionghan.PieceKing:     20000,
+	xionghan.PiecePawn:     100,
+	xionghan.PieceLei:      600,
+	xionghan.PieceFeng:     300,
+	xionghan.PieceWei:      250,
+}
+
+func seeValue(pc xionghan.Piece) int {
+	return seeValues[pc.Type()]
+}
+
+// SEE 静态交换评估：走 mv 之后，双方轮流用最便宜的子在 mv.To 上吃来吃去，
+// 任何一方都可以停手；返回走子方净得的子力。
+// 不吃子的走法结果为 0（落点安全）或负数（送子）。
+// 吃子规则按 AttackersTo：炮要炮架、檑只吃落单子、锋只从锋站吃、尉隔一格吃、兵前吃和过长城后横吃。
+// 在副本上计算，不改动 pos。
+func SEE(pos *xionghan.Position, mv xionghan.Move) int {
+	target := pos.Board.Squares[mv.To]
+	gain := seeValue(target)
+	if target.Type() == xionghan.PieceKing {
+		return gain
+	}
+	np := pos.Clone()
+	if !np.MakeMove(mv) {
+		return 0
+	}
+	return gain - seeExchange(np, mv.To)
+}
+
+// seeExchange 轮走方在 sq 上用最便宜的子吃、并一直交换下去的最好结果；不吃为 0。
+// 在 pos 上就地走子，返回前还原。
+func seeExchange(pos *xionghan.Position, sq int) int {
+	from := leastValuableAttacker(pos, sq, pos.SideToMove)
+	if from < 0 {
+		return 0
+	}
+	victim := pos.Board.Squares[sq]
+	gain := seeValue(victim)
+	if victim.Type() != xionghan.PieceKing && pos.MakeMove(xionghan.Move{From: from, To: sq}) {
+		gain -= seeExchange(pos, sq)
+		pos.UnmakeMove()
+	}
+	if gain < 0 {
+		return 0
+	}
+	return gain
+}
+
+// leastValuableAttacker side 一方能吃到 sq 的最便宜的子，没有返回 -1
+func leastValuableAttacker(pos *xionghan.Position, sq int, side xionghan.Side) int {
+	best, bestVal := -1, 0
+	for att := pos.AttackersTo(sq, side); !att.IsZero(); {
+		from := att.PopLSB()
+		if v := seeValue(pos.Board.Squares[from]); best < 0 || v < bestVal {
+			best, bestVal = from, v
+		}
+	}
+	return best
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"testing"
+
+	"xionghan/internal/xionghan"
+)
+
+func sq(row, col int) int { return row*xionghan.Cols + col }
+
+func red(pt xionghan.PieceType) xionghan.Piece   { return xionghan.Piece(pt) }
+func black(pt xionghan.PieceType) xionghan.Piece { return -xionghan.Piece(pt) }
+
+// seePosition 红方走；两王放在不同列，避免王对脸
+func seePosition(pieces map[int]xionghan.Piece) *xionghan.Position {
+	var b xionghan.Board
+	b.Squares[sq(11, 5)] = red(xionghan.PieceKing)
+	b.Squares[sq(1, 7)] = black(xionghan.PieceKing)
+	for s, pc := range pieces {
+		b.Squares[s] = pc
+	}
+	return xionghan.NewPosition(b, xionghan.Red, nil)
+}
+
+func TestSEE(t *testing.T) {
+	cases := []struct {
+		name   string
+		pieces map[int]xionghan.Piece
+		move   xionghan.Move
+		want   int
+	}{
+		{
+			name: "rook takes a pawn defended by a pawn",
+			pieces: map[int]xionghan.Piece{
+				sq(10, 6): red(xionghan.PieceRook),
+				sq(6, 6):  black(xionghan.PiecePawn),
+				sq(5, 6):  black(xionghan.PiecePawn),
+			},
+			move: xionghan.Move{From: sq(10, 6), To: sq(6, 6)},
+			want: 100 - 900,
+		},
+		{
+			name: "cannon over a screen takes an undefended knight",
+			pieces: map[int]xionghan.Piece{
+				sq(10, 6): red(xionghan.PieceCannon),
+				sq(8, 6):  red(xionghan.PiecePawn),
+				sq(6, 6):  black(xionghan.PieceKnight),
+			},
+			move: xionghan.Move{From: sq(10, 6), To: sq(6, 6)},
+			want: 400,
+		},
+		{
+			name: "wei recaptures two files away",
+			pieces: map[int]xionghan.Piece{
+				sq(6, 2): red(xionghan.PieceRook),
+				sq(6, 6): black(xionghan.PieceKnight),
+				sq(6, 8): black(xionghan.PieceWei),
+			},
+			move: xionghan.Move{From: sq(6, 2), To: sq(6, 6)},
+			want: 400 - 900,
+		},
+		{
+			name: "wei cannot recapture over a piece",
+			pieces: map[int]xionghan.Piece{
+				sq(6, 2): red(xionghan.PieceRook),
+				sq(6, 6): black(xionghan.PieceKnight),
+				sq(6, 7): red(xionghan.PiecePawn),
+				sq(6, 8): black(xionghan.PieceWei),
+			},
+			move: xionghan.Move{From: sq(6, 2), To: sq(6, 6)},
+			want: 400,
+		},
+		{
+			name: "lei takes a lone knight and is recaptured by a rook",
+			pieces: map[int]xionghan.Piece{
+				sq(5, 5): red(xionghan.PieceLei),
+				sq(6, 6): black(xionghan.PieceKnight),
+				sq(6, 9): black(xionghan.PieceRook),
+			},
+			move: xionghan.Move{From: sq(5, 5), To: sq(6, 6)},
+			want: 400 - 600,
+		},
+		{
+			// 黑车吃回檑会被红炮再吃掉车，所以黑方停手
+			name: "defender stands pat when the recapture loses",
+			pieces: map[int]xionghan.Piece{
+				sq(5, 5): red(xionghan.PieceLei),
+				sq(6, 6): black(xionghan.PieceKnight),
+				sq(6, 9): black(xionghan.PieceRook),
+				sq(6, 3): red(xionghan.PieceCannon),
+				sq(6, 4): red(xionghan.PiecePawn),
+			},
+			move: xionghan.Move{From: sq(5, 5), To: sq(6, 6)},
+			want: 400,
+		},
+		{
+			name: "quiet move to a safe square",
+			pieces: map[int]xionghan.Piece{
+				sq(10, 0): red(xionghan.PieceRook),
+			},
+			move: xionghan.Move{From: sq(10, 0), To: sq(7, 0)},
+			want: 0,
+		},
+		{
+			name: "quiet move hangs the rook to a pawn",
+			pieces: map[int]xionghan.Piece{
+				sq(10, 0): red(xionghan.PieceRook),
+				sq(6, 0):  black(xionghan.PiecePawn),
+			},
+			move: xionghan.Move{From: sq(10, 0), To: sq(7, 0)},
+			want: -900,
+		},
+	}
+	for _, c := range cases {
+		pos := seePosition(c.pieces)
+		before := pos.Board
+		if got := SEE(pos, c.move); got != c.want {
+			t.Errorf("%s: SEE = %d, want %d", c.name, got, c.want)
+		}
+		if pos.Board != before {
+			t.Fatalf("%s: SEE modified the position", c.name)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"xionghan/internal/xionghan"
+)
+
+// 默认搜索线程数的上限：线程多了只会让 NN 推理排队
+const maxDefaultThreads = 4
+
+// resolveThreads SearchConfig.Threads 为 0 时取 CPU 数（不超过 maxDefaultThreads）
+func resolveThreads(n int) int {
+	if n > 0 {
+		return n
+	}
+	n = runtime.NumCPU()
+	if n > maxDefaultThreads {
+		n = maxDefaultThreads
+	}
+	return n
+}
+
+// rootChild 根着法及走后的局面
+type rootChild struct {
+	move  xionghan.Move
+	child *xionghan.Position
+	hash  uint64
+}
+
+// rootResult 根着法的分数；exact 为 false 时分数只是上界或下界
+type rootResult struct {
+	move  xionghan.Move
+	score int
+	exact bool
+}
+
+// newWorker 搜索线程用的局部 Engine：节点计数和 blunder 缓存各自独立，置换表、NN、残局库共享。
+// stop 非 nil 时，置位后该线程尽快返回（结果作废）。
+func (e *Engine) newWorker(stop *uint32) *Engine {
+	return &Engine{
+		tt:             e.tt,
+		blunderTT:      make([]uint64, 1<<13),
+		blunderReplyTT: make([]uint64, 1<<13),
+		nn:             e.nn,
+		UseNN:          e.UseNN,
+		nnAbort:        e.nnAbort,
+		nnCache:        e.nnCache,
+		Tablebases:     e.Tablebases,
+		qs:             e.qs,
+		threads:        e.threads,
+		heur:           e.heur,
+		stop:           stop,
+		cancel:         e.cancel,
+	}
+}
+
+// mergeCounts 把线程的节点数加回 e
+func (e *Engine) mergeCounts(w *Engine) {
+	if w.nodes != 0 {
+		atomic.AddInt64(&e.nodes, w.nodes)
+	}
+	if w.qnodes != 0 {
+		atomic.AddInt64(&e.qnodes, w.qnodes)
+	}
+}
+
+// stopped 辅助线程是否已被叫停
+func (e *Engine) stopped() bool {
+	return e.stop != nil && atomic.LoadUint32(e.stop) != 0
+}
+
+// aborted NN 故障、被叫停或搜索被取消：搜索结果作废，不能写入置换表
+func (e *Engine) aborted() bool {
+	return e.hasNNFailure() || e.stopped() || e.cancelled()
+}
+
+// searchRootSMP Lazy SMP：主线程按顺序搜根着法，给出结果；
+// 其余线程从不同的着法开始、一半多搜一层，只为填充共享置换表，主线程搜完即叫停。
+// 单线程时只有主线程，结果可复现。
+func (e *Engine) searchRootSMP(children []rootChild, side xionghan.Side, depth, alpha, beta int, deadline time.Time, rep *repetitionState) []rootResult {
+	var stop uint32
+	var wg sync.WaitGroup
+	helpers := e.threads - 1
+	if len(children) < 2 {
+		helpers = 0
+	}
+	for id := 1; id <= helpers; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			w := e.newWorker(&stop)
+			order := make([]rootChild, 0, len(children))
+			k := id % len(children)
+			order = append(order, children[k:]...)
+			order = append(order, children[:k]...)
+			w.searchRootMoves(order, side, depth+id%2, alpha, beta, deadline, rep, 0)
+			e.mergeCounts(w)
+		}(id)
+	}
+
+	gap := 0
+	if e.threads > 1 {
+		gap = rootTopTwoRandomGap
+	}
+	w := e.newWorker(nil)
+	results := w.searchRootMoves(children, side, depth, alpha, beta, deadline, rep, gap)
+	e.mergeCounts(w)
+	atomic.StoreUint32(&stop, 1)
+	wg.Wait()
+	return results
+}
+
+// searchRootMoves 顺序搜根着法，alpha/beta 随已搜着法收紧。
+// randomGap > 0 时窗口多放宽 randomGap，使和最佳分相差不超过它的着法也得到准确分数。
+// 被叫停或 NN 故障时返回 nil。
+func (e *Engine) searchRootMoves(children []rootChild, side xionghan.Side, depth, alpha, beta int, deadline time.Time, rep *repetitionState, randomGap int) []rootResult {
+	// 换成轮走方视角的窗口 (lo, hi)
+	sign := 1
+	lo, hi := alpha, beta
+	if side == xionghan.Black {
+		sign = -1
+		lo, hi = -beta, -alpha
+	}
+	best := math.MinInt
+	localRep := rep.clone()
+	results := make([]rootResult, 0, len(children))
+	for _, ch := range children {
+		a := lo
+		if best != math.MinInt && best-randomGap > a {
+			a = best - randomGap
+		}
+		child := ch.child.Clone()
+		localRep.push(ch.hash)
+		search := func(lo, hi int) int {
+			if side == xionghan.Red {
+				return sign * e.alphaBeta(child, depth-1, lo, hi, deadline, localRep)
+			}
+			return sign * e.alphaBeta(child, depth-1, -hi, -lo, deadline, localRep)
+		}
+		var rel int
+		if e.heur.pvs && len(results) > 0 {
+			// 主变搜索：先用零窗口试探能否超过 a
+			rel = search(a, a+1)
+			if rel > a && rel < hi && !e.aborted() {
+				rel = search(a, hi)
+			}
+		} else {
+			rel = search(a, hi)
+		}
+		localRep.pop(ch.hash)
+		if e.aborted() {
+			return nil
+		}
+		results = append(results, rootResult{move: ch.move, score: sign * rel, exact: rel > a && rel < hi})
+		if rel > best {
+			best = rel
+		}
+		if best >= hi {
+			break
+		}
+	}
+	return results
+}
+
+// betterForSide 对 side 来说分数 a 是否比 b 好（分数为红方视角）
+func betterForSide(side xionghan.Side, a, b int) bool {
+	if side == xionghan.Red {
+		return a > b
+	}
+	return a < b
+}
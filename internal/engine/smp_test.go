@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"xionghan/internal/tablebase"
+	"xionghan/internal/xionghan"
+)
+
+// 根着法的子局面都在残局库里，搜索不需要 NN，分数是精确值
+func TestLazySMPMatchesSingleThread(t *testing.T) {
+	prober := testProber(t, "EAvE")
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(6, 0): red(xionghan.PieceRook),
+	})
+	_, want, ok := prober.BestMove(pos, pos.GenerateLegalMoves())
+	if !ok {
+		t.Fatal("position not in tablebase")
+	}
+
+	// 子局面的查表分数不含根节点这一步
+	wantScore := tbScore(tablebase.Result{WDL: want.WDL, Plies: want.Plies - 1}, pos.SideToMove)
+
+	search := func(threads int) (int, xionghan.Move, int64) {
+		e := NewEngine()
+		e.Tablebases = prober
+		e.threads = resolveThreads(threads)
+		score, mv := e.alphaBetaRoot(pos.Clone(), 3, -scoreInf, scoreInf, time.Time{}, newRepetitionState(SearchConfig{}))
+		if e.hasNNFailure() {
+			t.Fatal("search needed the NN")
+		}
+		return score, mv, e.nodes
+	}
+
+	score1, mv1, nodes1 := search(1)
+	if score1 != wantScore {
+		t.Fatalf("single-threaded score = %d, want %d", score1, wantScore)
+	}
+	score2, mv2, nodes2 := search(1)
+	if score2 != score1 || mv2 != mv1 || nodes2 != nodes1 {
+		t.Fatalf("single-threaded search is not deterministic: %d %v %d vs %d %v %d",
+			score1, mv1, nodes1, score2, mv2, nodes2)
+	}
+	if score4, _, _ := search(4); score4 != score1 {
+		t.Fatalf("4 threads score = %d, want %d", score4, score1)
+	}
+}
+
+func TestResolveThreads(t *testing.T) {
+	if got := resolveThreads(3); got != 3 {
+		t.Fatalf("resolveThreads(3) = %d", got)
+	}
+	if got := resolveThreads(0); got < 1 || got > maxDefaultThreads {
+		t.Fatalf("resolveThreads(0) = %d", got)
+	}
+}
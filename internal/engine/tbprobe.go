@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"xionghan/internal/tablebase"
+	"xionghan/internal/xionghan"
+)
+
+// 残局库确定胜负时的分数（红方为正）：低于吃王和 VCF，减去步数使更快的胜利分更高
+const tbWinScore = 800000
+
+// tbScore 把轮走方视角的查表结果换成红方视角的分数
+func tbScore(r tablebase.Result, stm xionghan.Side) int {
+	s := 0
+	switch r.WDL {
+	case tablebase.Win:
+		s = tbWinScore - r.Plies
+	case tablebase.Loss:
+		s = -(tbWinScore - r.Plies)
+	}
+	if stm == xionghan.Black {
+		s = -s
+	}
+	return s
+}
+
+// tbUtility 查表结果对应的 MCTS 价值（红方视角，+1 红胜）
+func tbUtility(r tablebase.Result, stm xionghan.Side) float64 {
+	u := float64(r.WDL)
+	if stm == xionghan.Black {
+		u = -u
+	}
+	return u
+}
+
+// probeRootTablebase 根节点查残局库：局面在库中时按库选着，不再搜索
+func (e *Engine) probeRootTablebase(pos *xionghan.Position, rep *repetitionState) (SearchResult, bool) {
+	if _, ok := e.Tablebases.Probe(pos); !ok {
+		return SearchResult{}, false
+	}
+	moves := pos.GenerateLegalMoves()
+	if rep.enabled {
+		kept := moves[:0]
+		for _, mv := range moves {
+			nextPos, ok := pos.ApplyMove(mv)
+			if ok && rep.canEnter(nextPos.EnsureHash(), moveGivesCheck(nextPos)) {
+				kept = append(kept, mv)
+			}
+		}
+		moves = kept
+	}
+	mv, r, ok := e.Tablebases.BestMove(pos, moves)
+	if !ok {
+		return SearchResult{}, false
+	}
+	pv, pvScore := e.tablebasePV(pos, mv, rep)
+	winProb := float32(0.5)
+	if r.WDL != tablebase.Draw {
+		winProb = float32(tbUtility(r, pos.SideToMove)+1) / 2
+	}
+	return SearchResult{
+		BestMove: mv,
+		Score:    tbScore(r, pos.SideToMove),
+		WinProb:  winProb,
+		Depth:    r.Plies,
+		Nodes:    int64(len(moves)),
+		PV:       pv,
+		PVScore:  pvScore,
+	}, true
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"xionghan/internal/tablebase"
+	"xionghan/internal/xionghan"
+)
+
+// testProber 生成 sig 的残局库（含子表）
+func testProber(t *testing.T, sig string) *tablebase.Prober {
+	t.Helper()
+	parsed, err := tablebase.ParseSignature(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := tablebase.NewGenerator(nil)
+	gen.Workers = 1
+	if _, err := gen.Generate(parsed); err != nil {
+		t.Fatal(err)
+	}
+	prober := tablebase.NewProber()
+	for _, tb := range gen.Tables() {
+		prober.Add(tb)
+	}
+	return prober
+}
+
+func TestSearchProbesTablebase(t *testing.T) {
+	prober := testProber(t, "EDvE")
+
+	e := NewEngine()
+	e.Tablebases = prober
+	pos := seePosition(map[int]xionghan.Piece{
+		sq(10, 6): red(xionghan.PieceAdvisor),
+	})
+	want, ok := prober.Probe(pos)
+	if !ok {
+		t.Fatal("position not in tablebase")
+	}
+
+	res := e.Search(pos, SearchConfig{MaxDepth: 3})
+	if got := res.Score; got != tbScore(want, pos.SideToMove) {
+		t.Fatalf("score = %d, want %d (%v in %d)", got, tbScore(want, pos.SideToMove), want.WDL, want.Plies)
+	}
+	mv, _, _ := prober.BestMove(pos, pos.GenerateLegalMoves())
+	if res.BestMove != mv {
+		t.Fatalf("best move = %v, want %v", res.BestMove, mv)
+	}
+
+	// 搜索树内部同样直接返回查表结果
+	child, _ := pos.ApplyMove(mv)
+	cr, _ := prober.Probe(child)
+	if got := e.alphaBeta(child, 2, -scoreInf, scoreInf, time.Time{}, newRepetitionState(SearchConfig{})); got != tbScore(cr, child.SideToMove) {
+		t.Fatalf("alphaBeta = %d, want %d", got, tbScore(cr, child.SideToMove))
+	}
+}
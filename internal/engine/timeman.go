@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"time"
+
+	"xionghan/internal/xionghan"
+)
+
+const (
+	tmDefaultMovesToGo = 30                    // 不知道离下次加时还有几步时按这么多步分配
+	tmMoveOverhead     = 50 * time.Millisecond // 每步留给通信、走子的余量
+	tmMinTime          = 10 * time.Millisecond // 每步至少给这么多时间
+	tmHardRatio        = 5                     // 硬上限最多是软上限的几倍
+	tmMaxUsage         = 0.75                  // 一步最多用掉剩余时间的比例
+	tmIterationStop    = 0.5                   // 已用时间超过软上限的这个比例就不再开始新一层（下一层通常比之前各层加起来还久）
+	tmUnstableScore    = 250                   // 相邻两层分数变化超过它视为不稳定
+	tmCheckInterval    = 10 * time.Millisecond // MCTS 检查用时的间隔
+
+	// 软上限的伸缩：最佳着法变了或分数不稳定时放大，稳定时缩小
+	tmMinScale        = 0.5
+	tmMaxScale        = 2.5
+	tmMoveChangeScale = 1.4
+	tmUnstableScale   = 1.2
+	tmStableScale     = 0.9
+	tmMCTSChangeScale = 1.1
+)
+
+// timeManager 一步的用时：软上限按局面是否稳定伸缩，硬上限（搜索的 deadline）绝不超过。
+// 只给 TimeLimit 时是固定用时，软硬上限相同、不做伸缩和提前停止。
+type timeManager struct {
+	start    time.Time
+	soft     time.Duration // 0 表示不限时
+	hard     time.Duration
+	adaptive bool
+	scale    float64
+
+	iterations int
+	lastBest   xionghan.Move
+	lastScore  int
+}
+
+// newTimeManager 按 SearchConfig 分配本步用时。RemainingTime > 0 时按对局计时分配，TimeLimit 再作为额外的上限。
+func newTimeManager(cfg SearchConfig, start time.Time) *timeManager {
+	tm := &timeManager{start: start, scale: 1}
+	if cfg.RemainingTime <= 0 {
+		tm.soft, tm.hard = cfg.TimeLimit, cfg.TimeLimit
+		return tm
+	}
+	tm.adaptive = true
+	mtg := cfg.MovesToGo
+	if mtg <= 0 || mtg > tmDefaultMovesToGo {
+		mtg = tmDefaultMovesToGo
+	}
+	avail := cfg.RemainingTime - tmMoveOverhead
+	if avail < tmMinTime {
+		avail = tmMinTime
+	}
+	soft := avail/time.Duration(mtg) + cfg.Increment*3/4
+	hard := soft * tmHardRatio
+	if limit := time.Duration(float64(avail) * tmMaxUsage); hard > limit {
+		hard = limit
+	}
+	if cfg.TimeLimit > 0 && hard > cfg.TimeLimit {
+		hard = cfg.TimeLimit
+	}
+	if hard < tmMinTime {
+		hard = tmMinTime
+	}
+	if soft > hard {
+		soft = hard
+	}
+	tm.soft, tm.hard = soft, hard
+	return tm
+}
+
+// deadline 硬上限对应的时刻，不限时为零值
+func (tm *timeManager) deadline() time.Time {
+	if tm.hard <= 0 {
+		return time.Time{}
+	}
+	return tm.start.Add(tm.hard)
+}
+
+// hardExceeded 是否已超过硬上限
+func (tm *timeManager) hardExceeded() bool {
+	return tm.hard > 0 && time.Since(tm.start) > tm.hard
+}
+
+// softLimit 伸缩后的软上限（不超过硬上限）
+func (tm *timeManager) softLimit() time.Duration {
+	s := time.Duration(float64(tm.soft) * tm.scale)
+	if s > tm.hard {
+		s = tm.hard
+	}
+	return s
+}
+
+func (tm *timeManager) rescale(f float64) {
+	tm.scale *= f
+	if tm.scale < tmMinScale {
+		tm.scale = tmMinScale
+	}
+	if tm.scale > tmMaxScale {
+		tm.scale = tmMaxScale
+	}
+}
+
+// iterationDone alpha-beta 搜完一层后调用，返回是否不再开始下一层
+func (tm *timeManager) iterationDone(move xionghan.Move, score int) bool {
+	if !tm.adaptive {
+		return false
+	}
+	if tm.iterations > 0 {
+		if move != tm.lastBest {
+			tm.rescale(tmMoveChangeScale)
+		} else {
+			tm.rescale(tmStableScale)
+		}
+		if d := score - tm.lastScore; d > tmUnstableScore || d < -tmUnstableScore {
+			tm.rescale(tmUnstableScale)
+		}
+	}
+	tm.iterations++
+	tm.lastBest, tm.lastScore = move, score
+	if score >= decisiveScore || score <= -decisiveScore {
+		// 已分胜负，加深也改变不了
+		return true
+	}
+	return time.Since(tm.start) >= time.Duration(float64(tm.softLimit())*tmIterationStop)
+}
+
+// mctsShouldStop MCTS 是否该停止：超过软上限，或按目前的速度到软上限前访问次数第二多的根着法也追不上第一。
+// playouts 为本次搜索已做的仿真次数。
+func (tm *timeManager) mctsShouldStop(root *MCTSNode, playouts int64) bool {
+	if !tm.adaptive {
+		return false
+	}
+	root.mu.RLock()
+	var bestMove xionghan.Move
+	var best, second int64
+	for mv := range root.Children {
+		v := root.EdgeVisits[mv]
+		if v > best {
+			best, second, bestMove = v, best, mv
+		} else if v > second {
+			second = v
+		}
+	}
+	root.mu.RUnlock()
+
+	if tm.iterations > 0 && bestMove != tm.lastBest {
+		tm.rescale(tmMCTSChangeScale)
+	}
+	tm.iterations++
+	tm.lastBest = bestMove
+
+	elapsed := time.Since(tm.start)
+	limit := tm.softLimit()
+	if elapsed >= limit {
+		return true
+	}
+	if playouts <= 0 || elapsed <= 0 {
+		return false
+	}
+	remaining := float64(playouts) / elapsed.Seconds() * (limit - elapsed).Seconds()
+	return float64(best-second) > remaining
+}
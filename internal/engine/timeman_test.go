@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"xionghan/internal/xionghan"
+)
+
+func TestTimeManagerAllocation(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name       string
+		cfg        SearchConfig
+		soft, hard time.Duration
+	}{
+		{"fixed", SearchConfig{TimeLimit: 3 * time.Second}, 3 * time.Second, 3 * time.Second},
+		{"unlimited", SearchConfig{}, 0, 0},
+		{
+			"sudden death",
+			SearchConfig{RemainingTime: 30*time.Second + tmMoveOverhead},
+			time.Second, 5 * time.Second,
+		},
+		{
+			"increment",
+			SearchConfig{RemainingTime: 30*time.Second + tmMoveOverhead, Increment: 2 * time.Second},
+			2500 * time.Millisecond, 12500 * time.Millisecond,
+		},
+		{
+			"last move before time control",
+			SearchConfig{RemainingTime: 4*time.Second + tmMoveOverhead, MovesToGo: 1},
+			3 * time.Second, 3 * time.Second,
+		},
+		{
+			"time limit caps the clock",
+			SearchConfig{RemainingTime: 30*time.Second + tmMoveOverhead, TimeLimit: 2 * time.Second},
+			time.Second, 2 * time.Second,
+		},
+	}
+	for _, c := range cases {
+		tm := newTimeManager(c.cfg, now)
+		if tm.soft != c.soft || tm.hard != c.hard {
+			t.Errorf("%s: soft %v hard %v, want %v %v", c.name, tm.soft, tm.hard, c.soft, c.hard)
+		}
+		if c.hard > 0 && !tm.deadline().Equal(now.Add(c.hard)) {
+			t.Errorf("%s: deadline %v", c.name, tm.deadline())
+		}
+	}
+}
+
+func TestTimeManagerIterations(t *testing.T) {
+	a := xionghan.Move{From: 1, To: 2}
+	b := xionghan.Move{From: 3, To: 4}
+
+	// 固定用时：从不提前停
+	fixed := newTimeManager(SearchConfig{TimeLimit: time.Millisecond}, time.Now().Add(-time.Second))
+	if fixed.iterationDone(a, 0) {
+		t.Fatal("fixed time limit should not stop between iterations")
+	}
+
+	cfg := SearchConfig{RemainingTime: 30*time.Second + tmMoveOverhead} // 软上限 1s
+	tm := newTimeManager(cfg, time.Now())
+	if tm.iterationDone(a, 0) {
+		t.Fatal("stopped right after the first iteration")
+	}
+	// 最佳着法变了、分数跳动：软上限放大
+	tm.iterationDone(b, 1000)
+	if want := tmMoveChangeScale * tmUnstableScale; tm.scale != want {
+		t.Fatalf("scale = %v, want %v", tm.scale, want)
+	}
+	// 稳定：缩小，但不低于下限
+	for i := 0; i < 20; i++ {
+		tm.iterationDone(b, 1000)
+	}
+	if tm.scale != tmMinScale {
+		t.Fatalf("scale = %v, want %v", tm.scale, tmMinScale)
+	}
+
+	// 已用时间超过软上限的 tmIterationStop 就不再开始新一层
+	late := newTimeManager(cfg, time.Now().Add(-600*time.Millisecond))
+	if !late.iterationDone(a, 0) {
+		t.Fatal("should stop after using more than half of the soft limit")
+	}
+	// 已分胜负时立即停止
+	if !newTimeManager(cfg, time.Now()).iterationDone(a, tbWinScore-5) {
+		t.Fatal("should stop once the result is decided")
+	}
+}
+
+func TestTimeManagerMCTSDominance(t *testing.T) {
+	a := xionghan.Move{From: 1, To: 2}
+	b := xionghan.Move{From: 3, To: 4}
+	root := NewMCTSNode(xionghan.Move{}, xionghan.Red, 1, false)
+	root.Children[a] = NewMCTSNode(a, xionghan.Black, 2, false)
+	root.Children[b] = NewMCTSNode(b, xionghan.Black, 3, false)
+	root.EdgeVisits[a], root.EdgeVisits[b] = 300, 100
+
+	cfg := SearchConfig{RemainingTime: 30*time.Second + tmMoveOverhead} // 软上限 1s
+	// 0.5s 做了 400 次仿真，剩下 0.5s 大约还能做 400 次：b 还追得上
+	tm := newTimeManager(cfg, time.Now().Add(-500*time.Millisecond))
+	if tm.mctsShouldStop(root, 400) {
+		t.Fatal("second move can still catch up")
+	}
+	// 0.9s 做了 400 次，剩下约 44 次：差距 200 追不上
+	tm = newTimeManager(cfg, time.Now().Add(-900*time.Millisecond))
+	if !tm.mctsShouldStop(root, 400) {
+		t.Fatal("best move dominates, should stop")
+	}
+	// 超过软上限
+	tm = newTimeManager(cfg, time.Now().Add(-1100*time.Millisecond))
+	root.EdgeVisits[b] = 300
+	if !tm.mctsShouldStop(root, 600) {
+		t.Fatal("past the soft limit, should stop")
+	}
+	// 固定用时不提前停
+	if newTimeManager(SearchConfig{TimeLimit: time.Second}, time.Now().Add(-900*time.Millisecond)).mctsShouldStop(root, 400) {
+		t.Fatal("fixed time limit should not stop early")
+	}
+}
@@ -1,6 +1,11 @@
 package engine
 
-import "xionghan/internal/xionghan"
+import (
+	"math"
+	"sync/atomic"
+
+	"xionghan/internal/xionghan"
+)
 
 const (
 	ttExact int8 = iota
@@ -8,35 +13,152 @@ const (
 	ttLowerBound
 )
 
-// 简单 TT 条目
+// DefaultHashMB 置换表默认大小（MB）
+const DefaultHashMB = 32
+
+// TT 条目（解包后的形式）
 type ttEntry struct {
-	Key   uint64
 	Depth int
 	Score int
 	Flag  int8
 	Move  xionghan.Move
 }
 
-// 存入 TT（你之前的简单策略）
-func (e *Engine) storeTT(key uint64, depth int, score int, flag int8, mv xionghan.Move) {
-	// 不加锁 ———— *你自己要求的*
-	if len(e.tt) > 1_000_000 {
-		e.tt = make(map[uint64]ttEntry, 1<<18)
+const (
+	ttBucketSlots = 4
+	ttAgeBits     = 6
+	ttAgeMask     = 1<<ttAgeBits - 1
+	ttMaxDepth    = 254
+)
+
+// 一个槽两个字：data 是打包的条目，check = key ^ data。
+// 读写都不加锁：并发写撕裂的槽校验不过，当作未命中。
+type ttSlot struct {
+	check uint64
+	data  uint64
+}
+
+type ttBucket [ttBucketSlots]ttSlot
+
+// TransTable 固定大小、按桶组织的置换表，所有搜索 goroutine 共享，无锁访问。
+// 同一对局的多次搜索之间保留；每次搜索开始时年龄加一，旧搜索的条目优先被替换。
+type TransTable struct {
+	buckets []ttBucket
+	mask    uint64
+	age     uint32
+	sizeMB  int
+}
+
+// NewTransTable 按大小（MB）分配置换表，桶数取不超过该大小的 2 的幂
+func NewTransTable(mb int) *TransTable {
+	if mb <= 0 {
+		mb = DefaultHashMB
 	}
-	old, ok := e.tt[key]
-	replace := !ok || depth > old.Depth
-	if ok && depth == old.Depth && flag == ttExact && old.Flag != ttExact {
-		replace = true
+	const bucketBytes = ttBucketSlots * 16
+	n := 1
+	for n*2*bucketBytes <= mb<<20 {
+		n *= 2
 	}
-	if replace {
-		e.tt[key] = ttEntry{
-			Key:   key,
-			Depth: depth,
-			Score: score,
-			Flag:  flag,
-			Move:  mv,
+	return &TransTable{buckets: make([]ttBucket, n), mask: uint64(n - 1), sizeMB: mb}
+}
+
+// SizeMB 分配时指定的大小
+func (t *TransTable) SizeMB() int { return t.sizeMB }
+
+// Clear 清空全部条目（不能和搜索同时调用）
+func (t *TransTable) Clear() {
+	for i := range t.buckets {
+		t.buckets[i] = ttBucket{}
+	}
+	atomic.StoreUint32(&t.age, 0)
+}
+
+// newSearch 新的一次搜索：年龄加一
+func (t *TransTable) newSearch() {
+	atomic.AddUint32(&t.age, 1)
+}
+
+// data 的布局：score 32 位 | depth+1 8 位 | flag 2 位 | age 6 位 | from 8 位 | to 8 位。
+// depth 存成 depth+1，保证用过的槽 data 不为 0。
+func ttPack(depth, score int, flag int8, age uint32, mv xionghan.Move) uint64 {
+	if depth < 0 {
+		depth = 0
+	}
+	if depth > ttMaxDepth {
+		depth = ttMaxDepth
+	}
+	return uint64(uint32(int32(score))) |
+		uint64(depth+1)<<32 |
+		uint64(flag&3)<<40 |
+		uint64(age&ttAgeMask)<<42 |
+		uint64(uint8(mv.From))<<48 |
+		uint64(uint8(mv.To))<<56
+}
+
+func ttUnpack(data uint64) (ttEntry, uint32) {
+	return ttEntry{
+		Score: int(int32(uint32(data))),
+		Depth: int(uint8(data>>32)) - 1,
+		Flag:  int8(data>>40) & 3,
+		Move:  xionghan.Move{From: int(uint8(data >> 48)), To: int(uint8(data >> 56))},
+	}, uint32(data>>42) & ttAgeMask
+}
+
+func (t *TransTable) probe(key uint64) (ttEntry, bool) {
+	b := &t.buckets[key&t.mask]
+	for i := range b {
+		data := atomic.LoadUint64(&b[i].data)
+		if data != 0 && atomic.LoadUint64(&b[i].check)^data == key {
+			e, _ := ttUnpack(data)
+			return e, true
 		}
 	}
+	return ttEntry{}, false
+}
+
+// store 同一局面：更深的、同深度的精确值、或旧搜索留下的才替换；
+// 否则替换桶里最不值钱的槽（空槽优先，其次按深度减去年龄差）。
+func (t *TransTable) store(key uint64, depth, score int, flag int8, mv xionghan.Move) {
+	b := &t.buckets[key&t.mask]
+	age := atomic.LoadUint32(&t.age) & ttAgeMask
+	victim := 0
+	victimWorth := math.MaxInt
+	for i := range b {
+		data := atomic.LoadUint64(&b[i].data)
+		if data == 0 {
+			if victimWorth > math.MinInt {
+				victim, victimWorth = i, math.MinInt
+			}
+			continue
+		}
+		old, oldAge := ttUnpack(data)
+		if atomic.LoadUint64(&b[i].check)^data == key {
+			replace := oldAge != age || depth > old.Depth
+			if depth == old.Depth && flag == ttExact && old.Flag != ttExact {
+				replace = true
+			}
+			if !replace {
+				return
+			}
+			if mv.From == 0 && mv.To == 0 {
+				mv = old.Move
+			}
+			victim = i
+			break
+		}
+		worth := old.Depth - 8*int((age-oldAge)&ttAgeMask)
+		if worth < victimWorth {
+			victim, victimWorth = i, worth
+		}
+	}
+	data := ttPack(depth, score, flag, age, mv)
+	atomic.StoreUint64(&b[victim].data, data)
+	atomic.StoreUint64(&b[victim].check, key^data)
+}
+
+// 存入 TT
+func (e *Engine) storeTT(key uint64, depth int, score int, flag int8, mv xionghan.Move) {
+	e.tt.store(key, depth, score, flag, mv)
 }
 
 // Position 哈希键：优先使用增量维护的 Zobrist。
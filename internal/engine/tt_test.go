@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+
+	"xionghan/internal/xionghan"
+)
+
+func TestTransTableStoreProbe(t *testing.T) {
+	tt := NewTransTable(1)
+	mv := xionghan.Move{From: 168, To: 3}
+	tt.store(12345, 7, -scoreInf, ttLowerBound, mv)
+	got, ok := tt.probe(12345)
+	if !ok {
+		t.Fatal("stored entry not found")
+	}
+	want := ttEntry{Depth: 7, Score: -scoreInf, Flag: ttLowerBound, Move: mv}
+	if got != want {
+		t.Fatalf("probe = %+v, want %+v", got, want)
+	}
+	if _, ok := tt.probe(54321); ok {
+		t.Fatal("unexpected hit")
+	}
+
+	// 同一搜索里较浅的结果不覆盖较深的；同深度的精确值可以覆盖
+	tt.store(12345, 3, 10, ttExact, xionghan.Move{})
+	if got, _ := tt.probe(12345); got.Depth != 7 {
+		t.Fatalf("shallower entry replaced a deeper one: %+v", got)
+	}
+	tt.store(12345, 7, 10, ttExact, xionghan.Move{})
+	got, _ = tt.probe(12345)
+	if got.Flag != ttExact || got.Score != 10 || got.Move != mv {
+		t.Fatalf("exact entry should replace the bound and keep the move: %+v", got)
+	}
+
+	// 新的一次搜索：旧条目可以被浅的覆盖
+	tt.newSearch()
+	tt.store(12345, 1, 20, ttUpperBound, xionghan.Move{From: 1, To: 2})
+	if got, _ := tt.probe(12345); got.Depth != 1 || got.Score != 20 {
+		t.Fatalf("entry from an older search was not replaced: %+v", got)
+	}
+
+	tt.Clear()
+	if _, ok := tt.probe(12345); ok {
+		t.Fatal("entry survived Clear")
+	}
+}
+
+func TestTransTableBucketReplacement(t *testing.T) {
+	tt := NewTransTable(1)
+	stride := tt.mask + 1
+	// 同一个桶里放满，再放一个：最浅的被替换
+	for i := 0; i < ttBucketSlots; i++ {
+		tt.store(uint64(i+1)*stride, 10+i, i, ttExact, xionghan.Move{})
+	}
+	tt.store(uint64(ttBucketSlots+1)*stride, 5, 99, ttExact, xionghan.Move{})
+	if _, ok := tt.probe(stride); ok {
+		t.Fatal("the shallowest entry should have been replaced")
+	}
+	for i := 1; i <= ttBucketSlots; i++ {
+		if _, ok := tt.probe(uint64(i+1) * stride); !ok {
+			t.Fatalf("entry %d missing", i+1)
+		}
+	}
+}
+
+func TestTransTableConcurrentAccess(t *testing.T) {
+	tt := NewTransTable(1)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20000; i++ {
+				key := uint64(i%512)*0x9E3779B97F4A7C15 + 1
+				// 分数、深度都由 key 决定：读到的条目必须与 key 对得上
+				tt.store(key, int(key%50), int(key%100000), ttExact, xionghan.Move{})
+				if e, ok := tt.probe(key); ok {
+					if e.Score != int(key%100000) || e.Depth != int(key%50) {
+						t.Errorf("corrupted entry for key %x: %+v", key, e)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -28,16 +28,70 @@ type vcfContext struct {
 	inPath     map[uint64]bool
 	nodes      int
 	nodeBudget int
+	exact      bool // 防守方考虑全部合法着法（不过启发式过滤），结果可以当作证明
 }
 
 // VCFResult 连将搜索结果
 type VCFResult struct {
 	CanWin bool
 	Move   xionghan.Move
+	Depth  int // CanWin 时最多再走几步（半回合）吃王或对方无着可走
+}
+
+// VCFFilter 过滤掉会导致被对方连将绝杀或直接吃王的走法（子力 <= 43 时启用）
+type VCFFilter struct{}
+
+func (VCFFilter) Name() string { return "vcf" }
+
+func (f VCFFilter) Apply(e *Engine, pos *xionghan.Position, moves []xionghan.Move, report *FilterReport) []xionghan.Move {
+	if pos.TotalPieces() > 43 {
+		return moves
+	}
+
+	safeMoves := make([]xionghan.Move, 0, len(moves))
+	for _, mv := range moves {
+		// 0. 绝杀判定：如果这一步直接吃掉对方的王，那绝对合法且必须走
+		target := pos.Board.Squares[mv.To]
+		if target != 0 && target.Type() == xionghan.PieceKing {
+			safeMoves = append(safeMoves, mv)
+			continue
+		}
+
+		if !pos.MakeMove(mv) {
+			continue
+		}
+
+		// 1. 检查对手是否能在下一手直接吃王（预防非将军的杀招）
+		// 2. 检查对手是否能进入 VCF 连将杀（预防必杀局）
+		reason := ""
+		if e.CanCaptureKingNext(pos) {
+			reason = "opponent can capture the king next move"
+		} else if e.VCFSearch(pos, vcfDepthFilter).CanWin {
+			reason = "opponent has a forced mate by continuous checks"
+		}
+		pos.UnmakeMove()
+		if reason != "" {
+			report.remove(f.Name(), mv, reason)
+			continue
+		}
+
+		safeMoves = append(safeMoves, mv)
+	}
+	return safeMoves
 }
 
 // VCFSearch 寻找连将胜
+// 搜索时在 pos 上就地走子，返回前还原。
 func (e *Engine) VCFSearch(pos *xionghan.Position, maxDepth int) VCFResult {
+	return e.vcfSearch(pos, maxDepth, false)
+}
+
+// vcfProve 与 VCFSearch 相同，但防守方的每一步合法应着都要试过，CanWin 是真正的必胜证明
+func (e *Engine) vcfProve(pos *xionghan.Position, maxDepth int) VCFResult {
+	return e.vcfSearch(pos, maxDepth, true)
+}
+
+func (e *Engine) vcfSearch(pos *xionghan.Position, maxDepth int, exact bool) VCFResult {
 	if maxDepth <= 0 {
 		maxDepth = vcfDefaultDepth
 	}
@@ -53,6 +107,7 @@ func (e *Engine) VCFSearch(pos *xionghan.Position, maxDepth int) VCFResult {
 		tt:         make(map[uint64]vcfTTEntry, 1<<16), // 加大置换表
 		inPath:     make(map[uint64]bool, 1<<10),
 		nodeBudget: vcfNodeBudgetBase + maxDepth*vcfNodeBudgetPerPly,
+		exact:      exact,
 	}
 
 	var bestMove xionghan.Move
@@ -60,7 +115,7 @@ func (e *Engine) VCFSearch(pos *xionghan.Position, maxDepth int) VCFResult {
 	for d := 2; d <= maxDepth; d += 2 {
 		found, move := e.vcfRootSearch(pos, d, ctx)
 		if found {
-			return VCFResult{CanWin: true, Move: move}
+			return VCFResult{CanWin: true, Move: move, Depth: d - 1}
 		}
 		bestMove = move
 		if ctx.reachNodeBudget() {
@@ -72,8 +127,7 @@ func (e *Engine) VCFSearch(pos *xionghan.Position, maxDepth int) VCFResult {
 }
 
 func (e *Engine) vcfRootSearch(pos *xionghan.Position, depth int, ctx *vcfContext) (bool, xionghan.Move) {
-	moves := pos.GenerateLegalMoves(true)
-	moves = e.FilterLeiLockedMoves(pos, moves)
+	moves := e.vcfCheckMoves(pos)
 	e.scoreVCFMoves(pos, moves, ctx)
 	
 	// 排序：权重越高越优先尝试
@@ -82,28 +136,28 @@ func (e *Engine) vcfRootSearch(pos *xionghan.Position, depth int, ctx *vcfContex
 	})
 
 	for _, mv := range moves {
-		nextPos, ok := pos.ApplyMove(mv)
-		if !ok {
-			continue
-		}
-
 		target := pos.Board.Squares[mv.To]
 		if target != 0 && target.Type() == xionghan.PieceKing {
 			return true, mv
 		}
 
-		// 攻击方必须将军
-		if !nextPos.IsInCheck(nextPos.SideToMove) {
+		if !pos.MakeMove(mv) {
 			continue
 		}
-
-		if !e.vcfDefenderCanEscape(nextPos, depth-1, ctx) {
+		forced := !e.vcfDefenderCanEscape(pos, depth-1, ctx)
+		pos.UnmakeMove()
+		if forced {
 			return true, mv
 		}
 	}
 	return false, xionghan.Move{}
 }
 
+// vcfCheckMoves 攻击方必须将军：只生成将军（含吃王）的走法，再过快速过滤
+func (e *Engine) vcfCheckMoves(pos *xionghan.Position) []xionghan.Move {
+	return e.QuickFilters.Apply(e, pos, pos.GenerateChecks(), nil)
+}
+
 // scoreVCFMoves 启发式评分：车 > 檑 = 炮 > 马
 func (e *Engine) scoreVCFMoves(pos *xionghan.Position, moves []xionghan.Move, ctx *vcfContext) {
 	key := hashPosition(pos) ^ vcfModeAttack
@@ -149,7 +203,7 @@ func (e *Engine) vcfAttackerCanForce(pos *xionghan.Position, depth int, ctx *vcf
 	if depth <= 0 {
 		return false
 	}
-	if ctx.reachNodeBudget() {
+	if ctx.reachNodeBudget() || e.cancelled() {
 		return false
 	}
 	key := hashPosition(pos) ^ vcfModeAttack
@@ -162,8 +216,7 @@ func (e *Engine) vcfAttackerCanForce(pos *xionghan.Position, depth int, ctx *vcf
 	ctx.inPath[key] = true
 	defer delete(ctx.inPath, key)
 
-	moves := pos.GenerateLegalMoves(true)
-	moves = e.FilterLeiLockedMoves(pos, moves)
+	moves := e.vcfCheckMoves(pos)
 	e.scoreVCFMoves(pos, moves, ctx)
 	sort.Slice(moves, func(i, j int) bool {
 		return moves[i].Score > moves[j].Score
@@ -172,11 +225,6 @@ func (e *Engine) vcfAttackerCanForce(pos *xionghan.Position, depth int, ctx *vcf
 	result := false
 	var bestMove xionghan.Move
 	for _, mv := range moves {
-		nextPos, ok := pos.ApplyMove(mv)
-		if !ok {
-			continue
-		}
-
 		target := pos.Board.Squares[mv.To]
 		if target != 0 && target.Type() == xionghan.PieceKing {
 			result = true
@@ -184,11 +232,12 @@ func (e *Engine) vcfAttackerCanForce(pos *xionghan.Position, depth int, ctx *vcf
 			break
 		}
 
-		if !nextPos.IsInCheck(nextPos.SideToMove) {
+		if !pos.MakeMove(mv) {
 			continue
 		}
-
-		if !e.vcfDefenderCanEscape(nextPos, depth-1, ctx) {
+		forced := !e.vcfDefenderCanEscape(pos, depth-1, ctx)
+		pos.UnmakeMove()
+		if forced {
 			result = true
 			bestMove = mv
 			break
@@ -206,7 +255,7 @@ func (e *Engine) vcfDefenderCanEscape(pos *xionghan.Position, depth int, ctx *vc
 	if depth <= 0 {
 		return true
 	}
-	if ctx.reachNodeBudget() {
+	if ctx.reachNodeBudget() || e.cancelled() {
 		return true
 	}
 	key := hashPosition(pos) ^ vcfModeDefend
@@ -219,8 +268,12 @@ func (e *Engine) vcfDefenderCanEscape(pos *xionghan.Position, depth int, ctx *vc
 	ctx.inPath[key] = true
 	defer delete(ctx.inPath, key)
 
-	moves := pos.GenerateLegalMoves(true)
-	moves = e.FilterLeiLockedMoves(pos, moves)
+	var moves []xionghan.Move
+	if ctx.exact {
+		moves = pos.GenerateLegalMoves()
+	} else {
+		moves = e.quickMoves(pos)
+	}
 	if len(moves) == 0 {
 		ctx.tt[key] = vcfTTEntry{
 			Depth:  depth,
@@ -232,11 +285,12 @@ func (e *Engine) vcfDefenderCanEscape(pos *xionghan.Position, depth int, ctx *vc
 	result := false
 	var bestMove xionghan.Move
 	for _, mv := range moves {
-		nextPos, ok := pos.ApplyMove(mv)
-		if !ok {
+		if !pos.MakeMove(mv) {
 			continue
 		}
-		if !e.vcfAttackerCanForce(nextPos, depth-1, ctx) {
+		escaped := !e.vcfAttackerCanForce(pos, depth-1, ctx)
+		pos.UnmakeMove()
+		if escaped {
 			result = true // 防守方只要找到一个不被 VCF 的走法就算逃脱
 			bestMove = mv
 			break
@@ -256,9 +310,7 @@ func (ctx *vcfContext) reachNodeBudget() bool {
 }
 
 func (e *Engine) CanCaptureKingNext(pos *xionghan.Position) bool {
-	moves := pos.GenerateLegalMoves(true)
-	moves = e.FilterLeiLockedMoves(pos, moves)
-	for _, mv := range moves {
+	for _, mv := range pos.GenerateCaptures() {
 		target := pos.Board.Squares[mv.To]
 		if target != 0 && target.Type() == xionghan.PieceKing {
 			return true
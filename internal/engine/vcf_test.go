@@ -36,8 +36,8 @@ func TestVCF_RedWin_BlackDefense(t *testing.T) {
 		pos, _ := xionghan.DecodePosition(fenBlackToMove)
 
 		// 获取黑方所有合法走法
-		moves := pos.GenerateLegalMoves(true)
-		safeMoves := engine.FilterVCFMoves(pos, moves)
+		moves := pos.GenerateLegalMoves()
+		safeMoves := VCFFilter{}.Apply(engine, pos, moves, nil)
 
 		// 重点断言：黑方必须能识别“红方下一步存在 VCF 绝杀风险”。
 		// 不要求黑方必死，只要能识别到危险分支即可。
@@ -54,9 +54,9 @@ func TestVCF_RedWin_BlackDefense(t *testing.T) {
 			t.Fatalf("Black should detect at least one move that allows Red to VCF win next.")
 		}
 
-		// 如果存在安全步，FilterVCFMoves 应该能过滤掉部分危险步。
+		// 如果存在安全步，VCFFilter 应该能过滤掉部分危险步。
 		if threatCount < len(moves) && len(safeMoves) == len(moves) {
-			t.Fatalf("Threat exists but FilterVCFMoves did not filter any risky move.")
+			t.Fatalf("Threat exists but VCFFilter did not filter any risky move.")
 		}
 
 		t.Logf("Detected threat lines: %d/%d, safe moves: %d", threatCount, len(moves), len(safeMoves))
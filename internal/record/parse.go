@@ -0,0 +1,275 @@
+package record
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var evalRe = regexp.MustCompile(`\[%eval\s+(-?\d+)\]`)
+
+// Parse 读取一个或多个对局记录
+func Parse(r io.Reader) ([]*Game, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: string(data), line: 1}
+	return p.parseAll()
+}
+
+// ParseString 解析单局记录；输入中没有对局时报错
+func ParseString(s string) (*Game, error) {
+	games, err := Parse(strings.NewReader(s))
+	if err != nil {
+		return nil, err
+	}
+	if len(games) == 0 {
+		return nil, fmt.Errorf("record: no game found")
+	}
+	return games[0], nil
+}
+
+type parser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("record: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\n':
+			p.line++
+		case ' ', '\t', '\r':
+		default:
+			return
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) parseAll() ([]*Game, error) {
+	var games []*Game
+	var g *Game
+	var moveText []string // 当前局尚未解析的着法文字，与 g.Moves 一一对应
+	inMoves := false      // 已进入着法区；此后再遇到标签视为下一局
+
+	finish := func() error {
+		if g != nil {
+			if g.Result == "" {
+				g.Result = g.Tag("Result")
+				if g.Result == "" {
+					g.Result = ResultUnknown
+				}
+			}
+			if err := resolveMoves(g, moveText); err != nil {
+				return err
+			}
+			games = append(games, g)
+		}
+		g = nil
+		moveText = nil
+		inMoves = false
+		return nil
+	}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			break
+		}
+		if g == nil {
+			g = &Game{}
+		}
+		switch c := p.src[p.pos]; {
+		case c == '[':
+			if inMoves {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+				g = &Game{}
+			}
+			tag, err := p.parseTag()
+			if err != nil {
+				return nil, err
+			}
+			g.Tags = append(g.Tags, tag)
+		case c == '{':
+			text, err := p.parseComment()
+			if err != nil {
+				return nil, err
+			}
+			inMoves = true
+			p.attachComment(g, text)
+		default:
+			tok := p.nextToken()
+			inMoves = true
+			if n := moveNumberPrefix(tok); n > 0 && n < len(tok) {
+				tok = tok[n:] // "1.f4-f7" 这种写法
+			}
+			switch {
+			case tok == ResultRedWin || tok == ResultBlackWin || tok == ResultDraw || tok == ResultUnknown:
+				g.Result = tok
+				if err := finish(); err != nil {
+					return nil, err
+				}
+			case isMoveNumber(tok):
+			default:
+				g.Moves = append(g.Moves, MoveEntry{})
+				moveText = append(moveText, tok)
+			}
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+// resolveMoves 按 Notation 标签从起始局面逐步解析着法文字
+func resolveMoves(g *Game, moveText []string) error {
+	notation, err := g.Notation()
+	if err != nil {
+		return err
+	}
+	pos, err := g.StartPosition()
+	if err != nil {
+		return err
+	}
+	for i, text := range moveText {
+		m, err := notation.ParseMove(pos, text)
+		if err != nil {
+			return fmt.Errorf("record: move %d: %w", i+1, err)
+		}
+		g.Moves[i].Move = m
+		pos, _ = pos.ApplyMove(m)
+	}
+	return nil
+}
+
+func (p *parser) parseTag() (Tag, error) {
+	p.pos++ // '['
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if name == "" {
+		return Tag{}, p.errorf("missing tag name")
+	}
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '"' {
+		return Tag{}, p.errorf("tag %s: missing value", name)
+	}
+	start = p.pos
+	p.pos++
+	for p.pos < len(p.src) && p.src[p.pos] != '"' {
+		if p.src[p.pos] == '\\' {
+			p.pos++
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == '\n' {
+			return Tag{}, p.errorf("tag %s: unterminated value", name)
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return Tag{}, p.errorf("tag %s: unterminated value", name)
+	}
+	p.pos++
+	value, err := strconv.Unquote(p.src[start:p.pos])
+	if err != nil {
+		return Tag{}, p.errorf("tag %s: %v", name, err)
+	}
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+		return Tag{}, p.errorf("tag %s: missing ']'", name)
+	}
+	p.pos++
+	return Tag{Name: name, Value: value}, nil
+}
+
+func (p *parser) parseComment() (string, error) {
+	end := strings.IndexByte(p.src[p.pos:], '}')
+	if end < 0 {
+		return "", p.errorf("unterminated comment")
+	}
+	text := p.src[p.pos+1 : p.pos+end]
+	p.line += strings.Count(text, "\n")
+	p.pos += end + 1
+	return text, nil
+}
+
+// attachComment 注释归属于前一步着法；第一步之前的注释归属整局
+func (p *parser) attachComment(g *Game, text string) {
+	var e *MoveEntry
+	if len(g.Moves) > 0 {
+		e = &g.Moves[len(g.Moves)-1]
+	}
+	if e != nil {
+		if m := evalRe.FindStringSubmatch(text); m != nil {
+			e.Eval, _ = strconv.Atoi(m[1])
+			e.HasEval = true
+			text = evalRe.ReplaceAllString(text, "")
+		}
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if e == nil {
+		g.Comment = joinComment(g.Comment, text)
+	} else {
+		e.Comment = joinComment(e.Comment, text)
+	}
+}
+
+func joinComment(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + " " + b
+}
+
+func (p *parser) nextToken() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\r', '\n', '{', '[':
+			return p.src[start:p.pos]
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// moveNumberPrefix 返回开头 "12." / "12..." 的长度，没有时返回 0
+func moveNumberPrefix(tok string) int {
+	i := 0
+	for i < len(tok) && tok[i] >= '0' && tok[i] <= '9' {
+		i++
+	}
+	if i == 0 || i == len(tok) || tok[i] != '.' {
+		return 0
+	}
+	for i < len(tok) && tok[i] == '.' {
+		i++
+	}
+	return i
+}
+
+// isMoveNumber 匹配 "12." 或 "12..."
+func isMoveNumber(tok string) bool {
+	return moveNumberPrefix(tok) == len(tok)
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,307 @@
+// Package record 实现匈汉象棋的对局记录格式（类似 PGN）。
+//
+// 一局记录由标签区和着法区组成：
+//
+//	[Event "selfplay"]
+//	[Date "2026.10.16"]
+//	[Red "alpha-beta d2"]
+//	[Black "mcts 2000"]
+//	[Result "1-0"]
+//
+//	1. f4-f7 {[%eval 35] 炮打中兵} f10-f7 2. ... 1-0
+//
+// 着法默认用坐标记法（xionghan.CoordinateNotation）：列 a-m，行号 = 13 - row（红方底线为 1）；
+// 标签 [Notation "chinese"] 表示着法用中文记法（xionghan.ChineseNotation）。
+// 花括号内是注释，其中 [%eval N] 记录引擎评估（红方视角分数）。
+// 起始局面不是初始局面时写 FEN 标签；Rules 标签记录所用的内置规则（online / training）。
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"xionghan/internal/xionghan"
+)
+
+// 对局结果标记
+const (
+	ResultRedWin   = "1-0"
+	ResultBlackWin = "0-1"
+	ResultDraw     = "1/2-1/2"
+	ResultUnknown  = "*"
+)
+
+// 记法标签的取值
+const (
+	NotationCoordinate = "coord"
+	NotationChinese    = "chinese"
+)
+
+// Tag 标签区的一项，保持写入顺序
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// MoveEntry 着法区的一步
+type MoveEntry struct {
+	Move    xionghan.Move
+	Comment string // 注释（不含 eval 标注）
+	HasEval bool
+	Eval    int // 引擎评估，红方视角
+}
+
+// Game 一局完整记录
+type Game struct {
+	Tags    []Tag
+	Comment string // 第一步之前的注释
+	Moves   []MoveEntry
+	Result  string // ResultRedWin / ResultBlackWin / ResultDraw / ResultUnknown
+}
+
+// NewGame 创建带基本标签的空记录；startFEN 为空表示初始局面
+func NewGame(red, black, date, startFEN string) *Game {
+	g := &Game{Result: ResultUnknown}
+	g.SetTag("Event", "?")
+	g.SetTag("Date", date)
+	g.SetTag("Red", red)
+	g.SetTag("Black", black)
+	g.SetTag("Result", ResultUnknown)
+	if startFEN != "" {
+		g.SetTag("FEN", startFEN)
+	}
+	return g
+}
+
+// Tag 返回标签值，不存在时返回空串
+func (g *Game) Tag(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// SetTag 设置标签，已存在则覆盖原值
+func (g *Game) SetTag(name, value string) {
+	for i := range g.Tags {
+		if g.Tags[i].Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, Tag{Name: name, Value: value})
+}
+
+// AddMove 追加一步着法
+func (g *Game) AddMove(m xionghan.Move, comment string) {
+	g.Moves = append(g.Moves, MoveEntry{Move: m, Comment: comment})
+}
+
+// AddMoveWithEval 追加一步带引擎评估的着法
+func (g *Game) AddMoveWithEval(m xionghan.Move, eval int, comment string) {
+	g.Moves = append(g.Moves, MoveEntry{Move: m, Comment: comment, HasEval: true, Eval: eval})
+}
+
+// SetResult 同时设置着法区末尾的结果和 Result 标签；
+// 终局原因写入 Termination 标签。
+func (g *Game) SetResult(res xionghan.GameResult) {
+	g.Result = ResultString(res)
+	g.SetTag("Result", g.Result)
+	if res.Reason != xionghan.ReasonNone {
+		g.SetTag("Termination", res.Reason.String())
+	}
+}
+
+// ResultString 把规则包的终局结果转换为结果标记
+func ResultString(res xionghan.GameResult) string {
+	switch res.Status {
+	case xionghan.StatusRedWin:
+		return ResultRedWin
+	case xionghan.StatusBlackWin:
+		return ResultBlackWin
+	case xionghan.StatusDraw:
+		return ResultDraw
+	default:
+		return ResultUnknown
+	}
+}
+
+// Rules 返回 Rules 标签对应的内置规则，缺省为 nil（即 OnlineRules）
+func (g *Game) Rules() (*xionghan.RuleSet, error) {
+	name := g.Tag("Rules")
+	if name == "" {
+		return nil, nil
+	}
+	rules, ok := xionghan.RulesByName(name)
+	if !ok {
+		return nil, fmt.Errorf("record: unknown rules %q", name)
+	}
+	return rules, nil
+}
+
+// StartPosition 返回起始局面：有 FEN 标签则解析，否则为初始局面；规则取自 Rules 标签
+func (g *Game) StartPosition() (*xionghan.Position, error) {
+	rules, err := g.Rules()
+	if err != nil {
+		return nil, err
+	}
+	fen := g.Tag("FEN")
+	if fen == "" {
+		pos := xionghan.NewInitialPosition()
+		pos.Rules = rules
+		return pos, nil
+	}
+	pos, err := xionghan.DecodePositionWithRules(fen, rules)
+	if err != nil {
+		return nil, fmt.Errorf("record: bad FEN tag: %w", err)
+	}
+	return pos, nil
+}
+
+// Notation 返回 Notation 标签对应的记法，缺省为坐标记法
+func (g *Game) Notation() (xionghan.Notation, error) {
+	switch name := g.Tag("Notation"); name {
+	case "", NotationCoordinate:
+		return xionghan.CoordinateNotation{}, nil
+	case NotationChinese:
+		return xionghan.ChineseNotation{}, nil
+	default:
+		return nil, fmt.Errorf("record: unknown notation %q", name)
+	}
+}
+
+// Replay 从起始局面逐步走完所有着法并校验合法性，返回终局局面
+func (g *Game) Replay() (*xionghan.Position, error) {
+	pos, err := g.StartPosition()
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range g.Moves {
+		if !pos.IsLegalMove(e.Move) {
+			return nil, fmt.Errorf("record: move %d: %w: %s", i+1, xionghan.ErrIllegalMove,
+				xionghan.CoordinateNotation{}.FormatMove(pos, e.Move))
+		}
+		pos, _ = pos.ApplyMove(e.Move)
+	}
+	return pos, nil
+}
+
+// String 返回记录的文本形式
+func (g *Game) String() string {
+	var sb strings.Builder
+	_ = Write(&sb, g)
+	return sb.String()
+}
+
+const maxLineWidth = 80
+
+func onBoard(sq int) bool { return sq >= 0 && sq < xionghan.NumSquares }
+
+// Write 把一局记录写成文本，末尾带一个空行，便于多局连续写入同一文件
+func Write(w io.Writer, g *Game) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range g.Tags {
+		fmt.Fprintf(bw, "[%s %s]\n", t.Name, strconv.Quote(t.Value))
+	}
+	bw.WriteString("\n")
+
+	notation, err := g.Notation()
+	if err != nil {
+		return err
+	}
+	// 中文记法依赖局面，需要边写边走；坐标记法在起始局面无法解析时也能写出
+	pos, err := g.StartPosition()
+	if err != nil {
+		if _, ok := notation.(xionghan.CoordinateNotation); !ok {
+			return err
+		}
+		pos = nil
+	}
+	blackFirst := pos != nil && pos.SideToMove == xionghan.Black
+
+	var tokens []string
+	if c := formatComment(MoveEntry{Comment: g.Comment}); c != "" {
+		tokens = append(tokens, c)
+	}
+	for i, e := range g.Moves {
+		ply := i
+		if blackFirst {
+			ply++
+		}
+		num := ply/2 + 1
+		if ply%2 == 0 {
+			tokens = append(tokens, strconv.Itoa(num)+".")
+		} else if i == 0 {
+			tokens = append(tokens, strconv.Itoa(num)+"...")
+		}
+		if !onBoard(e.Move.From) || !onBoard(e.Move.To) {
+			return fmt.Errorf("record: move %d: square out of range (%d-%d)", i+1, e.Move.From, e.Move.To)
+		}
+		if pos != nil {
+			pc := pos.Board.Squares[e.Move.From]
+			if pc == 0 {
+				return fmt.Errorf("record: move %d: no piece on %s", i+1, xionghan.SquareName(e.Move.From))
+			}
+			if pc.Side() != pos.SideToMove {
+				return fmt.Errorf("record: move %d: piece on %s does not belong to the side to move",
+					i+1, xionghan.SquareName(e.Move.From))
+			}
+		}
+		tokens = append(tokens, notation.FormatMove(pos, e.Move))
+		if pos != nil {
+			next, ok := pos.ApplyMove(e.Move)
+			if !ok {
+				return fmt.Errorf("record: move %d: %w: %s", i+1, xionghan.ErrIllegalMove,
+					xionghan.CoordinateNotation{}.FormatMove(pos, e.Move))
+			}
+			pos = next
+		}
+		if c := formatComment(e); c != "" {
+			tokens = append(tokens, c)
+		}
+	}
+	result := g.Result
+	if result == "" {
+		result = ResultUnknown
+	}
+	tokens = append(tokens, result)
+
+	width := 0
+	for _, tok := range tokens {
+		n := utf8.RuneCountInString(tok)
+		if width > 0 && width+1+n > maxLineWidth {
+			bw.WriteString("\n")
+			width = 0
+		}
+		if width > 0 {
+			bw.WriteString(" ")
+			width++
+		}
+		bw.WriteString(tok)
+		width += n
+	}
+	bw.WriteString("\n\n")
+	return bw.Flush()
+}
+
+func formatComment(e MoveEntry) string {
+	text := strings.NewReplacer("{", "(", "}", ")").Replace(strings.TrimSpace(e.Comment))
+	if e.HasEval {
+		ev := fmt.Sprintf("[%%eval %d]", e.Eval)
+		if text == "" {
+			text = ev
+		} else {
+			text = ev + " " + text
+		}
+	}
+	if text == "" {
+		return ""
+	}
+	return "{" + text + "}"
+}
@@ -0,0 +1,157 @@
+package record
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"xionghan/internal/xionghan"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, notation := range []string{"", NotationChinese} {
+		testRoundTrip(t, notation)
+	}
+}
+
+func testRoundTrip(t *testing.T, notation string) {
+	rng := rand.New(rand.NewSource(3))
+	g := NewGame("alpha-beta d2", `mcts "2000"`, "2026.10.16", "")
+	g.SetTag("Engine", "depth=2 sims=2000")
+	if notation != "" {
+		g.SetTag("Notation", notation)
+	}
+	g.Comment = "随机对局"
+
+	pos := xionghan.NewInitialPosition()
+	for ply := 0; ply < 60; ply++ {
+		moves := pos.GenerateLegalMoves()
+		if len(moves) == 0 {
+			break
+		}
+		mv := moves[rng.Intn(len(moves))]
+		switch ply % 3 {
+		case 0:
+			g.AddMove(mv, "")
+		case 1:
+			g.AddMoveWithEval(mv, rng.Intn(2000)-1000, "")
+		default:
+			g.AddMoveWithEval(mv, -7, "depth 2")
+		}
+		pos, _ = pos.ApplyMove(mv)
+		if pos.Outcome(nil).IsOver() {
+			break
+		}
+	}
+	g.SetResult(pos.Outcome(nil))
+
+	text := g.String()
+	got, err := ParseString(text)
+	if err != nil {
+		t.Fatalf("%s: parse: %v\n%s", notation, err, text)
+	}
+	if !reflect.DeepEqual(got, g) {
+		t.Fatalf("%s: round trip mismatch\nwant %+v\ngot  %+v\n%s", notation, g, got, text)
+	}
+	if got.String() != text {
+		t.Fatalf("rewrite differs:\n%s\n---\n%s", text, got.String())
+	}
+	if _, err := got.Replay(); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if n := utf8.RuneCountInString(line); n > maxLineWidth && !strings.HasPrefix(line, "[") {
+			t.Fatalf("line too long (%d): %q", n, line)
+		}
+	}
+}
+
+const blackFirstFEN = "i.a.h...h.a.i/...bcdedcb.../............./.f.........f./..g.g.g.g.g../j...........j/............./J...........J/..G.G.G.G.G../.F.........F./............./...BCDEDCB.../I.A.H...H.A.I b"
+
+func TestParseMultipleGames(t *testing.T) {
+	text := `[Event "a"]
+[FEN "` + blackFirstFEN + `"]
+
+{开局} 1... c9-c8 {[%eval -12] 挺卒} 2.c5-c6 {x} 0-1
+
+[Event "b"]
+[Result "1/2-1/2"]
+
+1. c5-c6 c9-c8
+`
+	games, err := Parse(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("games: got %d want 2", len(games))
+	}
+
+	a := games[0]
+	if a.Comment != "开局" || a.Result != ResultBlackWin || len(a.Moves) != 2 {
+		t.Fatalf("game a: %+v", a)
+	}
+	first := a.Moves[0]
+	if !first.HasEval || first.Eval != -12 || first.Comment != "挺卒" {
+		t.Fatalf("first move annotations: %+v", first)
+	}
+	if first.Move != (xionghan.Move{From: 4*13 + 2, To: 5*13 + 2}) {
+		t.Fatalf("first move: %+v", first.Move)
+	}
+	if _, err := a.Replay(); err != nil {
+		t.Fatalf("replay a: %v", err)
+	}
+	if !strings.Contains(a.String(), "1... c9-c8") {
+		t.Fatalf("black-first numbering lost:\n%s", a.String())
+	}
+
+	b := games[1]
+	if b.Tag("Event") != "b" || b.Result != ResultDraw || len(b.Moves) != 2 {
+		t.Fatalf("game b: %+v", b)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		"1. z1-a2 *",
+		"1. a1a2 *",
+		"[Event \"x\"\n1. c5-c6 *",
+		"[Event \"x]\n",
+		"1. c5-c6 {unterminated",
+	} {
+		if _, err := ParseString(text); err == nil {
+			t.Errorf("expected error for %q", text)
+		}
+	}
+}
+
+func TestWriteRejectsBadMoves(t *testing.T) {
+	pos := xionghan.NewInitialPosition()
+	moves := pos.GenerateLegalMoves()
+	first := moves[0]
+	var otherRed xionghan.Move
+	for _, mv := range moves {
+		if mv.From != first.From {
+			otherRed = mv
+			break
+		}
+	}
+
+	for name, bad := range map[string]xionghan.Move{
+		"off the board": {From: xionghan.NumSquares, To: 0},
+		"wrong side":    otherRed, // 第二步轮到黑方，却走红子
+	} {
+		for _, notation := range []string{NotationCoordinate, NotationChinese} {
+			g := NewGame("a", "b", "2026.10.16", "")
+			g.SetTag("Notation", notation)
+			g.AddMove(first, "")
+			g.AddMove(bad, "")
+			err := Write(&strings.Builder{}, g)
+			if err == nil || !strings.HasPrefix(err.Error(), "record: move 2:") {
+				t.Errorf("%s, %s: err = %v", name, notation, err)
+			}
+		}
+	}
+}
@@ -12,15 +12,18 @@ import (
 	"xionghan/internal/xionghan"
 )
 
+// trainingRules KataGomo 训练用的规则；步数上限由 C++ 侧的 maxMovesPerGame 控制
+var trainingRules = &xionghan.TrainingRules
+
 //export IsLegal
 func IsLegal(boardPtr *C.int8_t, xSize, ySize C.int, pla C.int8_t, loc C.short, stage C.int, midLoc0 C.short) C.bool {
 	if loc <= 1 { return C.bool(false) }
 	b := cToGoBoard(boardPtr, xSize, ySize)
 	side := cToGoSide(pla)
-	pos := &xionghan.Position{Board: b, SideToMove: side}
+	pos := xionghan.NewPosition(b, side, trainingRules)
 	gSq := cToGoSq(loc, xSize)
 	if gSq < 0 || gSq >= 169 { return C.bool(false) }
-	legalMoves := pos.GenerateLegalMoves(false)
+	legalMoves := pos.GenerateLegalMoves()
 	if stage == 0 {
 		for _, lm := range legalMoves {
 			if lm.From == gSq { return C.bool(true) }
@@ -39,10 +42,10 @@ func GetLegalBitmask(boardPtr *C.int8_t, xSize, ySize C.int, pla C.int8_t, stage
 	start := time.Now()
 	b := cToGoBoard(boardPtr, xSize, ySize)
 	side := cToGoSide(pla)
-	pos := &xionghan.Position{Board: b, SideToMove: side}
+	pos := xionghan.NewPosition(b, side, trainingRules)
 	
 	// 生成合法走法
-	legalMoves := pos.GenerateLegalMoves(false)
+	legalMoves := pos.GenerateLegalMoves()
 
 	mask := (*[211]int8)(unsafe.Pointer(maskOut))
 	for i := 0; i < 211; i++ { mask[i] = 0 }
@@ -83,26 +86,19 @@ func GetLegalBitmask(boardPtr *C.int8_t, xSize, ySize C.int, pla C.int8_t, stage
 //export CheckWinner
 func CheckWinner(boardPtr *C.int8_t, xSize, ySize C.int, pla C.int8_t) C.int8_t {
 	b := cToGoBoard(boardPtr, xSize, ySize)
-	// pla 是刚走完的人（当前局面的胜者候选）。
+	// pla 是刚走完的人，轮到对方走；终局判定统一交给规则包。
+	// C 侧只传棋盘，没有局面历史和步数计数，这里只判吃王和无着可走；步数上限由 maxMovesPerGame 控制。
 	side := cToGoSide(pla)
-	oppSide := xionghan.Side(1 - int(side))
-
-	// 1. 检查自己的王是否还在（防止自杀或异常）
-	posSelf := &xionghan.Position{Board: b, SideToMove: side}
-	if !posSelf.KingExists(side) {
-		return C.int8_t(3 - int(pla)) // 自己王没了，对方赢
-	}
-
-	// 2. 检查对手的王是否还在
-	posOpp := &xionghan.Position{Board: b, SideToMove: oppSide}
-	if !posOpp.KingExists(oppSide) {
-		return pla // 对手王被吃了，pla 赢
+	pos := xionghan.NewPosition(b, xionghan.Side(1-int(side)), trainingRules)
+	res := pos.Outcome(nil)
+	switch res.Status {
+	case xionghan.StatusRedWin:
+		return 1
+	case xionghan.StatusBlackWin:
+		return 2
+	case xionghan.StatusDraw:
+		return 0 // C_EMPTY (和棋)
 	}
-
-	// 3. 检查对手是否被将死或困毙（无合法招法）
-			if len(posOpp.GenerateLegalMoves(false)) == 0 {		return pla // 对手没棋走了，pla 赢
-	}
-
 	return 3 // C_WALL (游戏继续)
 }
 
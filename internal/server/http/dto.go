@@ -1,6 +1,9 @@
 package httpserver
 
-import "xionghan/internal/xionghan"
+import (
+	"xionghan/internal/engine"
+	"xionghan/internal/xionghan"
+)
 
 // AiMoveRequest 请求让 AI 为当前局面走一步
 type AiMoveRequest struct {
@@ -10,9 +13,20 @@ type AiMoveRequest struct {
 	MaxDepth int    `json:"max_depth"`
 	TimeMs   int64  `json:"time_ms"`
 
+	// 对局计时（可选）：给了 remaining_ms 时由引擎的时间管理器分配本步用时，time_ms 只作为上限
+	RemainingMs int64 `json:"remaining_ms"`
+	IncrementMs int64 `json:"increment_ms"`
+	MovesToGo   int   `json:"moves_to_go"`
+
 	// MCTS 相关的参数
 	UseMCTS         bool `json:"use_mcts"`
 	MCTSSimulations int  `json:"mcts_simulations"`
+
+	// 为 true 时以 SSE（text/event-stream）返回：搜索中发 info 事件（SearchInfoDTO），最后发 result 事件（AiMoveResponse）
+	Stream bool `json:"stream"`
+
+	// 为 true 时这步棋在 /api/play 走出后，引擎在对手思考时后台搜索（ponder）
+	Ponder bool `json:"ponder"`
 }
 
 // 前端用的招法结构
@@ -36,6 +50,33 @@ type AiMoveResponse struct {
 	LegalMoves []MoveDTO `json:"legal_moves"` // 下一手所有可走棋
 	Status     string    `json:"status"`      // "ongoing" / "no_moves" / 以后再扩展赢家
 	TimeMs     int64     `json:"time_ms"`
+	PV         []MoveDTO `json:"pv"`        // 主变（AI 预期的着法序列，第一步即 best_move）
+	PVScore    int       `json:"pv_score"`  // 主变末端局面的评估（红方视角）
+	PVLength   int       `json:"pv_length"` // 主变步数
+
+	TreeNodes   int64 `json:"tree_nodes"`   // MCTS 树的节点数
+	ReusedNodes int64 `json:"reused_nodes"` // MCTS 从之前的搜索沿用的节点数
+	MateIn      int   `json:"mate_in"`      // 已证明的胜负：N>0 AI N 步内吃王，-N 为 N 步内被吃王，0 未证明
+}
+
+// SearchInfoDTO 搜索进度（stream 模式的 info 事件）
+type SearchInfoDTO struct {
+	MCTS     bool          `json:"mcts"`
+	Depth    int           `json:"depth"`
+	Score    int           `json:"score"`
+	WinProb  float32       `json:"win_prob"`
+	Nodes    int64         `json:"nodes"` // alpha-beta 节点数；MCTS 为根节点访问次数
+	NPS      int64         `json:"nps"`
+	TimeMs   int64         `json:"time_ms"`
+	PV       []MoveDTO     `json:"pv"`
+	PVScore  int           `json:"pv_score"`
+	TopMoves []RootMoveDTO `json:"top_moves,omitempty"` // MCTS：访问最多的几个根着法
+}
+
+type RootMoveDTO struct {
+	Move    MoveDTO `json:"move"`
+	Visits  int64   `json:"visits"`
+	WinProb float32 `json:"win_prob"`
 }
 
 // NewGame 返回
@@ -57,7 +98,9 @@ type PlayResponse struct {
 	Position   string    `json:"position"`
 	ToMove     int       `json:"to_move"`
 	LegalMoves []MoveDTO `json:"legal_moves"`
-	Status     string    `json:"status"` // "ongoing" / "checkmate" / "draw" 先留着
+	Status     string    `json:"status"`           // "ongoing" / "red_win" / "black_win" / "draw"
+	Winner     int       `json:"winner"`           // 0=红, 1=黑, -1=未分胜负
+	Reason     string    `json:"reason,omitempty"` // 终局原因，如 "king_captured"
 }
 
 func sideToInt(s xionghan.Side) int {
@@ -90,6 +133,11 @@ func movesToDTO(ms []xionghan.Move) []MoveDTO {
 	return out
 }
 
+// Record 请求：导出对局记录
+type RecordRequest struct {
+	GameID string `json:"game_id"`
+}
+
 // State 请求：前端刷新时用 game_id 来要当前盘面
 type StateRequest struct {
 	GameID string `json:"game_id"`
@@ -100,5 +148,49 @@ type StateResponse struct {
 	Position   string    `json:"position"`
 	ToMove     int       `json:"to_move"`
 	LegalMoves []MoveDTO `json:"legal_moves"`
-	Status     string    `json:"status"` // 先统一用 "ongoing"
+	Status     string    `json:"status"`           // 同 PlayResponse
+	Winner     int       `json:"winner"`           // 0=红, 1=黑, -1=未分胜负
+	Reason     string    `json:"reason,omitempty"` // 终局原因
+}
+
+// ExplainMovesRequest 调试：查看 AI 过滤管线删掉了哪些着法
+type ExplainMovesRequest struct {
+	GameID   string `json:"game_id"`
+	Position string `json:"position"`
+	ToMove   int    `json:"to_move"`
+}
+
+// RemovedMoveDTO 一步被过滤掉的着法
+type RemovedMoveDTO struct {
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Move   string `json:"move"`   // 坐标记法，如 "a12-a9"
+	Filter string `json:"filter"` // 过滤器名，如 "blunder"
+	Reason string `json:"reason"`
+}
+
+// ExplainMovesResponse 过滤前后的着法和每一步被删掉的原因
+type ExplainMovesResponse struct {
+	LegalMoves []MoveDTO        `json:"legal_moves"`
+	KeptMoves  []MoveDTO        `json:"kept_moves"`
+	Removed    []RemovedMoveDTO `json:"removed"`
+	Reverted   []string         `json:"reverted,omitempty"` // 删光全部着法而被撤销的过滤器
+}
+
+func searchInfoToDTO(info engine.SearchInfo) SearchInfoDTO {
+	dto := SearchInfoDTO{
+		MCTS:    info.MCTS,
+		Depth:   info.Depth,
+		Score:   info.Score,
+		WinProb: info.WinProb,
+		Nodes:   info.Nodes,
+		NPS:     info.NPS,
+		TimeMs:  info.TimeUsed.Milliseconds(),
+		PV:      movesToDTO(info.PV),
+		PVScore: info.PVScore,
+	}
+	for _, m := range info.TopMoves {
+		dto.TopMoves = append(dto.TopMoves, RootMoveDTO{Move: moveToDTO(m.Move), Visits: m.Visits, WinProb: m.WinProb})
+	}
+	return dto
 }
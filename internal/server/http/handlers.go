@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"xionghan/internal/engine"
+	"xionghan/internal/record"
 	"xionghan/internal/xionghan"
 )
 
@@ -21,6 +22,17 @@ type Game struct {
 	HashCount map[uint64]int
 	Engine    *engine.Engine
 	LastMove  time.Time
+	Record    *record.Game // 对局记录，/api/record 导出
+	aiHint    *aiHint      // 最近一次 AI 建议，玩家照走时把评估写进记录
+}
+
+// aiHint 记录 /api/ai_move 给出的着法和评估
+type aiHint struct {
+	Move   xionghan.Move
+	Score  int
+	Depth  int
+	PV     []xionghan.Move
+	Ponder *engine.SearchConfig // 非 nil 时这步走出后用它后台思考
 }
 
 var (
@@ -29,6 +41,9 @@ var (
 
 	gameSeq  uint64
 	aiEngine = engine.NewEngine()
+
+	// gameRules 网页对局使用的规则
+	gameRules = &xionghan.OnlineRules
 )
 
 const (
@@ -87,6 +102,20 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		h.handleAiMove(w, r)
 
+	case "/api/record":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.handleRecord(w, r)
+
+	case "/api/explain_moves":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.handleExplainMoves(w, r)
+
 	default:
 		http.NotFound(w, r)
 	}
@@ -94,7 +123,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleNewGame(w http.ResponseWriter, r *http.Request) {
 	pos := xionghan.NewInitialPosition()
-	legal := pos.GenerateLegalMoves(false)
+	pos.Rules = gameRules
+	legal := pos.GenerateLegalMoves()
 	now := time.Now()
 
 	game := &Game{
@@ -102,7 +132,10 @@ func (h *Handler) handleNewGame(w http.ResponseWriter, r *http.Request) {
 		HashCount: map[uint64]int{pos.EnsureHash(): 1},
 		Engine:    aiEngine.CloneForGame(),
 		LastMove:  now,
+		Record:    record.NewGame("?", "?", now.Format("2006.01.02"), ""),
 	}
+	game.Record.SetTag("Event", "xionghan web")
+	game.Record.SetTag("Rules", gameRules.Name)
 	id := newGameID()
 
 	gamesMu.Lock()
@@ -140,7 +173,12 @@ func (h *Handler) handlePlay(w http.ResponseWriter, r *http.Request) {
 
 	pos := game.Pos
 	ensureGameHashCount(game)
-	legal := pos.GenerateLegalMoves(false)
+	if gameOutcomeLocked(game).IsOver() {
+		gamesMu.Unlock()
+		http.Error(w, "game_over", http.StatusBadRequest)
+		return
+	}
+	legal := pos.GenerateLegalMoves()
 
 	// 确认这步是不是合法招之一
 	var found *xionghan.Move
@@ -172,22 +210,49 @@ func (h *Handler) handlePlay(w http.ResponseWriter, r *http.Request) {
 	game.Pos = newPos
 	game.HashCount[newPos.EnsureHash()]++
 	touchGameLocked(game)
+	result := gameOutcomeLocked(game)
+	hint := game.aiHint
+	recordMoveLocked(game, *found, result)
+	gameEngine := game.Engine
+	history := copyHashCountLocked(game)
 	gamesMu.Unlock()
 
-	legal2 := newPos.GenerateLegalMoves(false)
+	// 后台思考：AI 的着法走出后开始想对手的应着；对手走子时停下，并提升对应的子树
+	if gameEngine != nil {
+		if hint != nil && hint.Move == *found && hint.Ponder != nil {
+			if !result.IsOver() {
+				startPonder(gameEngine, newPos, hint, history)
+			}
+		} else {
+			gameEngine.PonderMove(*found)
+		}
+	}
 
-	status := "ongoing"
-	// TODO: 以后加上将死 / 和棋判断
+	legal2 := newPos.GenerateLegalMoves()
 
 	resp := PlayResponse{
 		Position:   newPos.Encode(),
 		ToMove:     sideToInt(newPos.SideToMove),
 		LegalMoves: movesToDTO(legal2),
-		Status:     status,
+		Status:     result.Status.String(),
+		Winner:     sideToInt(result.Winner),
+		Reason:     result.Reason.String(),
 	}
 	writeJSON(w, resp)
 }
 
+// startPonder 在 pos（轮到对手）上后台思考；alpha-beta 预测对手走 AI 主变的第二步
+func startPonder(eng *engine.Engine, pos *xionghan.Position, hint *aiHint, history map[uint64]int) {
+	cfg := *hint.Ponder
+	cfg.EnableRepetitionFilter = shouldEnableRepetitionRule(pos)
+	cfg.RepetitionCount = history
+	var predicted xionghan.Move
+	if len(hint.PV) > 1 {
+		predicted = hint.PV[1]
+	}
+	eng.StartPonder(pos, predicted, cfg)
+}
+
 func writeJSON(w http.ResponseWriter, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	if err := json.NewEncoder(w).Encode(v); err != nil {
@@ -195,6 +260,17 @@ func writeJSON(w http.ResponseWriter, v any) {
 	}
 }
 
+// writeSSE 写一个 Server-Sent Events 事件，data 为 JSON
+func writeSSE(w http.ResponseWriter, f http.Flusher, event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Println("writeSSE error:", err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	f.Flush()
+}
+
 func (h *Handler) handleState(w http.ResponseWriter, r *http.Request) {
 	var req StateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -202,28 +278,97 @@ func (h *Handler) handleState(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gamesMu.RLock()
+	gamesMu.Lock()
 	game, ok := games[req.GameID]
-	gamesMu.RUnlock()
-	if !ok {
-		http.Error(w, "game not found", http.StatusNotFound)
-		return
-	}
-	if game == nil || game.Pos == nil {
+	if !ok || game == nil || game.Pos == nil {
+		gamesMu.Unlock()
 		http.Error(w, "game not found", http.StatusNotFound)
 		return
 	}
-
+	ensureGameHashCount(game)
 	pos := game.Pos
-	legal := pos.GenerateLegalMoves(false)
+	result := gameOutcomeLocked(game)
+	gamesMu.Unlock()
 
-	status := "ongoing" // 以后你可以在 Game 里存状态，这里直接返回
+	legal := pos.GenerateLegalMoves()
 
 	resp := StateResponse{
 		Position:   pos.Encode(),
 		ToMove:     sideToInt(pos.SideToMove),
 		LegalMoves: movesToDTO(legal),
-		Status:     status,
+		Status:     result.Status.String(),
+		Winner:     sideToInt(result.Winner),
+		Reason:     result.Reason.String(),
+	}
+	writeJSON(w, resp)
+}
+
+// handleRecord 以文本对局记录的形式导出整盘棋
+func (h *Handler) handleRecord(w http.ResponseWriter, r *http.Request) {
+	var req RecordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+
+	gamesMu.Lock()
+	game, ok := games[req.GameID]
+	if !ok || game == nil || game.Pos == nil {
+		gamesMu.Unlock()
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	if game.Record == nil {
+		gamesMu.Unlock()
+		http.Error(w, "game has no record", http.StatusNotFound)
+		return
+	}
+	text := game.Record.String()
+	gamesMu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="xionghan.txt"`)
+	_, _ = w.Write([]byte(text))
+}
+
+func (h *Handler) handleExplainMoves(w http.ResponseWriter, r *http.Request) {
+	var req ExplainMovesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	pos, err := xionghan.DecodePositionWithRules(req.Position, gameRules)
+	if err != nil {
+		http.Error(w, "invalid position: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if reqSide := intToSide(req.ToMove); pos.SideToMove != reqSide {
+		pos.SideToMove = reqSide
+		pos.Hash = pos.CalculateHash()
+	}
+
+	eng := aiEngine
+	if req.GameID != "" {
+		if gameEngine, _, err := snapshotGameAIContext(req.GameID); err == nil {
+			eng = gameEngine
+		}
+	}
+	kept, report := eng.ExplainMoves(pos)
+
+	resp := ExplainMovesResponse{
+		LegalMoves: movesToDTO(pos.GenerateLegalMoves()),
+		KeptMoves:  movesToDTO(kept),
+		Removed:    make([]RemovedMoveDTO, 0, len(report.Removed)),
+		Reverted:   report.Reverted,
+	}
+	for _, rm := range report.Removed {
+		resp.Removed = append(resp.Removed, RemovedMoveDTO{
+			From:   rm.Move.From,
+			To:     rm.Move.To,
+			Move:   xionghan.CoordinateNotation{}.FormatMove(nil, rm.Move),
+			Filter: rm.Filter,
+			Reason: rm.Reason,
+		})
 	}
 	writeJSON(w, resp)
 }
@@ -248,9 +393,9 @@ func (h *Handler) handleAiMove(w http.ResponseWriter, r *http.Request) {
 	// 这里假设你有类似这样的函数：
 	//   func DecodePosition(enc string) (*Position, error)
 	// 如果你实际名字不同，在这里改一下即可。
-	pos, err := xionghan.DecodePosition(req.Position)
+	pos, err := xionghan.DecodePositionWithRules(req.Position, gameRules)
 	if err != nil {
-		http.Error(w, "invalid position", http.StatusBadRequest)
+		http.Error(w, "invalid position: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -260,7 +405,7 @@ func (h *Handler) handleAiMove(w http.ResponseWriter, r *http.Request) {
 		pos.SideToMove = reqSide
 		pos.Hash = pos.CalculateHash()
 	}
-	legalNow := movesToDTO(pos.GenerateLegalMoves(false))
+	legalNow := movesToDTO(pos.GenerateLegalMoves())
 	gameEngine, historyCount, err := snapshotGameAIContext(req.GameID)
 	if err != nil {
 		http.Error(w, "game not found", http.StatusNotFound)
@@ -271,6 +416,10 @@ func (h *Handler) handleAiMove(w http.ResponseWriter, r *http.Request) {
 	depth := req.MaxDepth
 	if depth <= 0 {
 		depth = 3
+		if req.RemainingMs > 0 {
+			// 对局计时：深度不设限，何时停由时间管理器决定
+			depth = 64
+		}
 	}
 	var limit time.Duration
 	if req.TimeMs > 0 {
@@ -280,23 +429,43 @@ func (h *Handler) handleAiMove(w http.ResponseWriter, r *http.Request) {
 	cfg := engine.SearchConfig{
 		MaxDepth:               depth,
 		TimeLimit:              limit,
+		RemainingTime:          time.Duration(req.RemainingMs) * time.Millisecond,
+		Increment:              time.Duration(req.IncrementMs) * time.Millisecond,
+		MovesToGo:              req.MovesToGo,
 		EnableRepetitionFilter: shouldEnableRepetitionRule(pos),
 		RepetitionCount:        historyCount,
-		RepetitionBanCount:     3,
+		RepetitionBanCount:     gameRules.RepetitionBanCount,
+		EnableQuiescence:       true,
 		UseMCTS:                req.UseMCTS,
 		MCTSSimulations:        req.MCTSSimulations,
 	}
+	cfg.EnableAllHeuristics()
+
+	// stream 模式以 SSE 返回：搜索进度发 info 事件，最后的结果发 result 事件
+	reply := func(v any) { writeJSON(w, v) }
+	if req.Stream {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		cfg.Listener = func(info engine.SearchInfo) {
+			writeSSE(w, flusher, "info", searchInfoToDTO(info))
+		}
+		reply = func(v any) { writeSSE(w, flusher, "result", v) }
+	}
 
-	// ===== 3. 调用搜索，只思考不落子 =====
-	res := gameEngine.Search(pos, cfg)
+	// ===== 3. 调用搜索，只思考不落子；客户端断开时搜索随请求的 ctx 取消 =====
+	res := gameEngine.SearchContext(r.Context(), pos, cfg)
+	if r.Context().Err() != nil {
+		return
+	}
 
 	// NN 推理失败：本次请求直接失败，不落子不换边。
 	if res.NNFailed {
-		moves := pos.GenerateLegalMoves(true)
-		moves = gameEngine.FilterLeiLockedMoves(pos, moves)
-		moves = gameEngine.FilterUrgentPawnThreatMoves(pos, moves)
-		moves = gameEngine.FilterBlunderMoves(pos, moves)
-		moves = gameEngine.FilterVCFMoves(pos, moves)
+		moves := gameEngine.FilteredMoves(pos)
 		if shouldEnableRepetitionRule(pos) {
 			filtered := make([]xionghan.Move, 0, len(moves))
 			for _, mv := range moves {
@@ -328,7 +497,7 @@ func (h *Handler) handleAiMove(w http.ResponseWriter, r *http.Request) {
 				LegalMoves: legalNow,
 				Status:     "ok",
 			}
-			writeJSON(w, resp)
+			reply(resp)
 			return
 		}
 		resp := AiMoveResponse{
@@ -342,7 +511,7 @@ func (h *Handler) handleAiMove(w http.ResponseWriter, r *http.Request) {
 			LegalMoves: legalNow,
 			Status:     "no_moves",
 		}
-		writeJSON(w, resp)
+		reply(resp)
 		return
 	}
 
@@ -359,9 +528,17 @@ func (h *Handler) handleAiMove(w http.ResponseWriter, r *http.Request) {
 			LegalMoves: legalNow,
 			Status:     "no_moves",
 		}
-		writeJSON(w, resp)
+		reply(resp)
 		return
 	}
+	hint := &aiHint{Move: res.BestMove, Score: res.Score, Depth: res.Depth, PV: res.PV}
+	if req.Ponder {
+		ponderCfg := cfg
+		ponderCfg.Listener = nil
+		hint.Ponder = &ponderCfg
+	}
+	rememberAIHint(req.GameID, pos, hint)
+
 	// 正常返回
 	resp := AiMoveResponse{
 		BestMove: MoveDTO{
@@ -377,12 +554,17 @@ func (h *Handler) handleAiMove(w http.ResponseWriter, r *http.Request) {
 		ToMove:     sideToInt(pos.SideToMove), // 当前轮到谁
 		LegalMoves: legalNow,
 		Status:     "ok",
+		PV:         movesToDTO(res.PV),
+		PVScore:    res.PVScore,
+		PVLength:   len(res.PV),
+
+		TreeNodes:   res.TreeNodes,
+		ReusedNodes: res.ReusedNodes,
+		MateIn:      res.MateIn,
 	}
-	writeJSON(w, resp)
+	reply(resp)
 }
 
-const repetitionRulePieceThreshold = 40
-
 func ensureGameHashCount(game *Game) {
 	if game == nil || game.Pos == nil {
 		return
@@ -395,6 +577,38 @@ func ensureGameHashCount(game *Game) {
 	}
 }
 
+// recordMoveLocked 把刚走的一步写入对局记录；若与 AI 建议一致则附上评估。
+// 记录只在 handleNewGame 里创建（起始局面在那里才知道），没有记录的对局不记
+func recordMoveLocked(game *Game, mv xionghan.Move, result xionghan.GameResult) {
+	if game.Record == nil {
+		game.aiHint = nil
+		return
+	}
+	if hint := game.aiHint; hint != nil && hint.Move == mv {
+		game.Record.AddMoveWithEval(mv, hint.Score, fmt.Sprintf("depth %d", hint.Depth))
+	} else {
+		game.Record.AddMove(mv, "")
+	}
+	game.aiHint = nil
+	if result.IsOver() {
+		game.Record.SetResult(result)
+	}
+}
+
+// rememberAIHint 保存 AI 的建议；局面已经变了（请求过期）则丢弃
+func rememberAIHint(gameID string, pos *xionghan.Position, hint *aiHint) {
+	gamesMu.Lock()
+	defer gamesMu.Unlock()
+	game, ok := games[gameID]
+	if !ok || game == nil || game.Pos == nil {
+		return
+	}
+	if game.Pos.EnsureHash() != pos.EnsureHash() {
+		return
+	}
+	game.aiHint = hint
+}
+
 func touchGameLocked(game *Game) {
 	if game == nil {
 		return
@@ -406,7 +620,7 @@ func shouldEnableRepetitionRule(pos *xionghan.Position) bool {
 	if pos == nil {
 		return false
 	}
-	return pos.TotalPieces() < repetitionRulePieceThreshold
+	return pos.ActiveRules().RepetitionActive(pos)
 }
 
 func isRepetitionForbidden(hashCount map[uint64]int, nextPos *xionghan.Position) bool {
@@ -419,7 +633,16 @@ func isRepetitionForbidden(hashCount map[uint64]int, nextPos *xionghan.Position)
 		return false
 	}
 	nextHash := nextPos.EnsureHash()
-	return hashCount[nextHash]+1 >= 3
+	return hashCount[nextHash]+1 >= nextPos.ActiveRules().RepetitionBanCount
+}
+
+// gameOutcomeLocked 用规则包判定当前对局是否结束；调用方需持有 gamesMu。
+// 长将判负与走子时的禁手一样由对局规则决定是否生效。
+func gameOutcomeLocked(game *Game) xionghan.GameResult {
+	return game.Pos.Outcome(&xionghan.GameHistory{
+		HashCount:      game.HashCount,
+		NoCaptureLimit: xionghan.DefaultNoCaptureLimit,
+	})
 }
 
 func copyHashCountLocked(game *Game) map[uint64]int {
@@ -475,9 +698,8 @@ func startIdleGameJanitor() {
 
 func cleanupIdleGames(now time.Time) int {
 	gamesMu.Lock()
-	defer gamesMu.Unlock()
-
 	removed := 0
+	var evicted []*engine.Engine
 	for id, game := range games {
 		if game == nil {
 			delete(games, id)
@@ -491,7 +713,16 @@ func cleanupIdleGames(now time.Time) int {
 		if now.Sub(last) >= gameIdleTTL {
 			delete(games, id)
 			removed++
+			if game.Engine != nil {
+				evicted = append(evicted, game.Engine)
+			}
 		}
 	}
+	gamesMu.Unlock()
+
+	// 被清掉的对局可能还在后台思考，锁外停下（要等搜索线程退出）
+	for _, eng := range evicted {
+		eng.StopPonder()
+	}
 	return removed
 }
@@ -0,0 +1,140 @@
+package tablebase
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"xionghan/internal/xionghan"
+)
+
+// 文件格式（小端）：
+//
+//	"XHTB" | 版本 1 字节 | 规则名长度 1 字节 + 规则名 | 子力组合长度 1 字节 + 子力组合 | 局面数 uint32 | 每局面 1 字节
+//
+// 局面按 layout 编号顺序排列，可以直接按编号查。
+const (
+	fileMagic   = "XHTB"
+	fileVersion = 1
+
+	// FileExt 残局库文件扩展名，文件名为 <规则名>-<子力组合>.xtb
+	FileExt = ".xtb"
+)
+
+// FileName 规则和子力组合对应的文件名；不同规则的同一子力组合可以放在同一目录
+func FileName(rules string, sig Signature) string { return rules + "-" + sig.String() + FileExt }
+
+// WriteTo 把残局库写到 w
+func (t *Table) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(fileMagic)
+	bw.WriteByte(fileVersion)
+	for _, s := range []string{t.Rules, t.Sig.String()} {
+		bw.WriteByte(byte(len(s)))
+		bw.WriteString(s)
+	}
+	binary.Write(bw, binary.LittleEndian, uint32(len(t.data)))
+	bw.Write(t.data)
+	n := int64(len(fileMagic) + 1 + 2 + len(t.Rules) + len(t.Sig.String()) + 4 + len(t.data))
+	return n, bw.Flush()
+}
+
+// Save 写到 dir/<规则名>-<子力组合>.xtb
+func (t *Table) Save(dir string) error {
+	f, err := os.Create(filepath.Join(dir, FileName(t.Rules, t.Sig)))
+	if err != nil {
+		return err
+	}
+	if _, err := t.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadTable 读取残局库；规则名必须是已知的预设（RuleSet.Name），以便还原局面编号
+func ReadTable(r io.Reader) (*Table, error) {
+	br := bufio.NewReader(r)
+	head := make([]byte, len(fileMagic)+1)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return nil, err
+	}
+	if string(head[:len(fileMagic)]) != fileMagic {
+		return nil, errors.New("tablebase: not a tablebase file")
+	}
+	if head[len(fileMagic)] != fileVersion {
+		return nil, fmt.Errorf("tablebase: unsupported version %d", head[len(fileMagic)])
+	}
+	var strs [2]string
+	for i := range strs {
+		n, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		strs[i] = string(buf)
+	}
+	rules, ok := xionghan.RulesByName(strs[0])
+	if !ok {
+		return nil, fmt.Errorf("tablebase: unknown rules %q", strs[0])
+	}
+	sig, err := ParseSignature(strs[1])
+	if err != nil {
+		return nil, err
+	}
+	var size uint32
+	if err := binary.Read(br, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+	t := &Table{Sig: sig, Rules: rules.Name, lay: newLayout(sig, rules)}
+	if int(size) != t.lay.size {
+		return nil, fmt.Errorf("tablebase %s: %d positions, layout has %d", sig, size, t.lay.size)
+	}
+	t.data = make([]byte, size)
+	if _, err := io.ReadFull(br, t.data); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LoadTable 从文件读取残局库
+func LoadTable(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := ReadTable(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// LoadDir 读取目录下全部 .xtb 文件
+func LoadDir(dir string) (*Prober, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	p := NewProber()
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), FileExt) {
+			continue
+		}
+		t, err := LoadTable(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		p.Add(t)
+	}
+	return p, nil
+}
@@ -0,0 +1,234 @@
+package tablebase
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"xionghan/internal/xionghan"
+)
+
+// Generator 逆推生成残局库。吃子后的子力组合会先递归生成，结果缓存在 Generator 里。
+//
+// 胜负只按吃王（和轮走方无合法走法判负）计算，不考虑长将、步数上限等与历史有关的规则；
+// 双方都无法取胜的局面（循环）记为和棋。
+type Generator struct {
+	Rules   *xionghan.RuleSet
+	Workers int                              // 并行线程数，<=0 时用 CPU 数
+	Logf    func(format string, args ...any) // 进度输出，可以为 nil
+
+	tables map[string]*Table
+	order  []*Table
+}
+
+func NewGenerator(rules *xionghan.RuleSet) *Generator {
+	if rules == nil {
+		rules = &xionghan.OnlineRules
+	}
+	return &Generator{Rules: rules, tables: make(map[string]*Table)}
+}
+
+// Tables 已生成的全部残局库，子表在前
+func (g *Generator) Tables() []*Table { return g.order }
+
+// Generate 生成 sig 的残局库（以及吃子后会用到的全部子表）
+func (g *Generator) Generate(sig Signature) (*Table, error) {
+	if t, ok := g.tables[sig.String()]; ok {
+		return t, nil
+	}
+
+	// 吃掉第 i 个棋子后进入的子表；王被吃直接终局，不需要子表
+	lay := newLayout(sig, g.Rules)
+	subs := make([]*Table, len(lay.slots))
+	for i, s := range lay.slots {
+		if s.pt == xionghan.PieceKing {
+			continue
+		}
+		own := i
+		if s.side == xionghan.Black {
+			own -= len(sig[xionghan.Red])
+		}
+		sub, err := g.Generate(sig.without(s.side, own))
+		if err != nil {
+			return nil, err
+		}
+		subs[i] = sub
+	}
+
+	t := &Table{Sig: sig, Rules: g.Rules.Name, data: make([]byte, lay.size), lay: lay}
+	if err := g.solve(t, subs); err != nil {
+		return nil, err
+	}
+	g.tables[sig.String()] = t
+	g.order = append(g.order, t)
+	return t, nil
+}
+
+// 一步走法的去向：同表的另一个编号，或子表里的局面
+type child struct {
+	table *Table
+	idx   int
+}
+
+// solve 分层逆推：第 k 轮只确定恰好 k 步的局面。
+// 胜：有一个子局面是对方负，取最短；负：所有子局面都是对方胜，取最长。
+// 子局面的值都来自之前的轮次（或已完成的子表），因此每轮的结果与遍历顺序无关，可以并行。
+func (g *Generator) solve(t *Table, subs []*Table) error {
+	lay := t.lay
+	workers := g.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// 子表里最长的步数：超过它之后，一轮没有新结果就可以停止
+	subMax := 0
+	for _, sub := range subs {
+		if sub == nil {
+			continue
+		}
+		for _, b := range sub.data {
+			if p := decode(b).Plies; p > subMax {
+				subMax = p
+			}
+		}
+	}
+
+	valid := make([]bool, lay.size)
+	resolved := make([]bool, lay.size)
+	type update struct {
+		idx int
+		val byte
+	}
+
+	for k := 0; ; k++ {
+		chunk := (lay.size + workers - 1) / workers
+		results := make([][]update, workers)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			lo, hi := w*chunk, (w+1)*chunk
+			if hi > lay.size {
+				hi = lay.size
+			}
+			wg.Add(1)
+			go func(w, lo, hi int) {
+				defer wg.Done()
+				sqs := make([]int, len(lay.slots))
+				var kids []child
+				for idx := lo; idx < hi; idx++ {
+					if resolved[idx] || (k > 0 && !valid[idx]) {
+						continue
+					}
+					side, ok := lay.decode(idx, sqs)
+					if !ok {
+						continue
+					}
+					if k == 0 {
+						valid[idx] = true
+					}
+					var r Result
+					kids, r, ok = g.children(t, subs, sqs, side, kids[:0])
+					if !ok {
+						r, ok = evalChildren(t, kids, k)
+					}
+					if ok && r.Plies <= k {
+						results[w] = append(results[w], update{idx, encode(r)})
+					}
+				}
+			}(w, lo, hi)
+		}
+		wg.Wait()
+
+		n := 0
+		for _, us := range results {
+			for _, u := range us {
+				t.data[u.idx] = u.val
+				resolved[u.idx] = true
+				n++
+			}
+		}
+		if g.Logf != nil && n > 0 {
+			g.Logf("%s: %d positions at %d plies", t.Sig, n, k)
+		}
+		if n == 0 && k > subMax+1 {
+			return nil
+		}
+		if k >= maxPlies {
+			return fmt.Errorf("%s: distance exceeds %d plies", t.Sig, maxPlies)
+		}
+	}
+}
+
+// children 生成局面的全部子局面。能直接吃王、或没有合法走法时直接给出结果（done 为 true）
+func (g *Generator) children(t *Table, subs []*Table, sqs []int, side xionghan.Side, kids []child) ([]child, Result, bool) {
+	lay := t.lay
+	pos := xionghan.NewPosition(lay.board(sqs), side, g.Rules)
+	moves := pos.GenerateLegalMoves()
+	if len(moves) == 0 {
+		return kids, Result{WDL: Loss, Plies: 0}, true
+	}
+	next := make([]int, len(sqs))
+	for _, mv := range moves {
+		mover, victim := -1, -1
+		for i, sq := range sqs {
+			switch sq {
+			case mv.From:
+				mover = i
+			case mv.To:
+				victim = i
+			}
+		}
+		if victim >= 0 && lay.slots[victim].pt == xionghan.PieceKing {
+			return kids, Result{WDL: Win, Plies: 1}, true
+		}
+		copy(next, sqs)
+		next[mover] = mv.To
+		target := t
+		if victim >= 0 {
+			target = subs[victim]
+			next = append(next[:victim], next[victim+1:]...)
+		}
+		idx, ok := target.lay.index(next, 1-side)
+		if !ok {
+			// 走到了枚举范围外的格子：不该发生（CanStand 覆盖了全部可达格子）
+			panic(fmt.Sprintf("tablebase %s: move %s-%s leaves the indexed squares",
+				t.Sig, xionghan.SquareName(mv.From), xionghan.SquareName(mv.To)))
+		}
+		kids = append(kids, child{target, idx})
+		next = next[:len(sqs)]
+	}
+	return kids, Result{}, false
+}
+
+// evalChildren 按第 k 轮之前已知的子局面求值；还不能确定时 ok 为 false
+func evalChildren(t *Table, kids []child, k int) (Result, bool) {
+	bestWin := -1
+	worstLoss := -1
+	allWins := true
+	for _, c := range kids {
+		if c.table == t && t.data[c.idx] == 0 {
+			// 同表里还没确定（或是和棋）
+			allWins = false
+			continue
+		}
+		r := decode(c.table.data[c.idx])
+		switch r.WDL {
+		case Loss:
+			if bestWin < 0 || r.Plies+1 < bestWin {
+				bestWin = r.Plies + 1
+			}
+		case Win:
+			if r.Plies+1 > worstLoss {
+				worstLoss = r.Plies + 1
+			}
+		default:
+			allWins = false
+		}
+	}
+	if bestWin >= 0 && bestWin <= k {
+		return Result{WDL: Win, Plies: bestWin}, true
+	}
+	if allWins && worstLoss <= k {
+		return Result{WDL: Loss, Plies: worstLoss}, true
+	}
+	return Result{}, false
+}
@@ -0,0 +1,128 @@
+package tablebase
+
+import (
+	"sort"
+
+	"xionghan/internal/xionghan"
+)
+
+// slot 子力组合里的一个棋子，以及它能出现的格子
+type slot struct {
+	side  xionghan.Side
+	pt    xionghan.PieceType
+	sqs   []int                      // 能出现的格子，升序
+	rank  [xionghan.NumSquares]int32 // 格子 -> 在 sqs 中的序号，不能出现为 -1
+	first int                        // 同一方同兵种中第一个 slot 的下标；同兵种的格子按升序排列
+}
+
+// layout 局面编号：各棋子格子序号的混合进制数 *2 + 轮走方
+type layout struct {
+	slots  []slot
+	stride []int
+	size   int
+}
+
+func newLayout(sig Signature, rules *xionghan.RuleSet) *layout {
+	l := &layout{}
+	for _, side := range []xionghan.Side{xionghan.Red, xionghan.Black} {
+		for _, pt := range sig[side] {
+			s := slot{side: side, pt: pt, first: len(l.slots)}
+			if n := len(l.slots); n > 0 && l.slots[n-1].side == side && l.slots[n-1].pt == pt {
+				s.first = l.slots[n-1].first
+			}
+			for sq := 0; sq < xionghan.NumSquares; sq++ {
+				s.rank[sq] = -1
+				if rules.CanStand(side, pt, sq) {
+					s.rank[sq] = int32(len(s.sqs))
+					s.sqs = append(s.sqs, sq)
+				}
+			}
+			l.slots = append(l.slots, s)
+		}
+	}
+	l.stride = make([]int, len(l.slots))
+	n := 2
+	for i := len(l.slots) - 1; i >= 0; i-- {
+		l.stride[i] = n
+		n *= len(l.slots[i].sqs)
+	}
+	l.size = n
+	return l
+}
+
+// decode 把编号还原成各棋子的格子；两子同格或同兵种未按升序时 ok 为 false（无效编号）
+func (l *layout) decode(idx int, sqs []int) (xionghan.Side, bool) {
+	side := xionghan.Side(idx & 1)
+	var used xionghan.Bitboard
+	for i := range l.slots {
+		s := &l.slots[i]
+		sq := s.sqs[idx/l.stride[i]%len(s.sqs)]
+		if used.Has(sq) {
+			return side, false
+		}
+		if s.first != i && sq < sqs[i-1] {
+			return side, false
+		}
+		used.Set(sq)
+		sqs[i] = sq
+	}
+	return side, true
+}
+
+// index 各棋子的格子 -> 编号；会把同兵种的格子就地排成升序。有棋子落在不能出现的格子时 ok 为 false
+func (l *layout) index(sqs []int, side xionghan.Side) (int, bool) {
+	for i := 0; i < len(l.slots); {
+		j := i + 1
+		for j < len(l.slots) && l.slots[j].first == i {
+			j++
+		}
+		if j-i > 1 {
+			sort.Ints(sqs[i:j])
+		}
+		i = j
+	}
+	idx := int(side)
+	for i := range l.slots {
+		r := l.slots[i].rank[sqs[i]]
+		if r < 0 {
+			return 0, false
+		}
+		idx += int(r) * l.stride[i]
+	}
+	return idx, true
+}
+
+// squaresOf 按 slot 顺序取出局面中各棋子的格子；子力组合不符时 ok 为 false
+func (l *layout) squaresOf(pos *xionghan.Position, sqs []int) bool {
+	for i := 0; i < len(l.slots); {
+		s := &l.slots[i]
+		bb := pos.PieceBitboard(s.side, s.pt)
+		for ; i < len(l.slots) && l.slots[i].side == s.side && l.slots[i].pt == s.pt; i++ {
+			sq := bb.PopLSB()
+			if sq < 0 {
+				return false
+			}
+			sqs[i] = sq
+		}
+		if !bb.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// board 按各棋子的格子摆出棋盘
+func (l *layout) board(sqs []int) xionghan.Board {
+	var b xionghan.Board
+	for i := range l.slots {
+		b.Squares[sqs[i]] = piece(l.slots[i].side, l.slots[i].pt)
+	}
+	return b
+}
+
+func piece(side xionghan.Side, pt xionghan.PieceType) xionghan.Piece {
+	if side == xionghan.Red {
+		return xionghan.Piece(pt)
+	}
+	return -xionghan.Piece(pt)
+}
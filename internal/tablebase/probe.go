@@ -0,0 +1,98 @@
+package tablebase
+
+import "xionghan/internal/xionghan"
+
+// Prober 按子力组合查多个残局库，规则名要与局面的规则一致
+type Prober struct {
+	tables    map[string]*Table // key: 规则名 + "/" + 子力组合
+	maxPieces int
+}
+
+func NewProber() *Prober {
+	return &Prober{tables: make(map[string]*Table)}
+}
+
+// Add 加入一个残局库（同规则同子力组合的会被替换）
+func (p *Prober) Add(t *Table) {
+	p.tables[t.Rules+"/"+t.Sig.String()] = t
+	if n := t.Sig.Pieces(); n > p.maxPieces {
+		p.maxPieces = n
+	}
+}
+
+// Len 残局库个数
+func (p *Prober) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.tables)
+}
+
+// MaxPieces 已加载残局库中最多的棋子数，子数更多的局面不必查
+func (p *Prober) MaxPieces() int {
+	if p == nil {
+		return 0
+	}
+	return p.maxPieces
+}
+
+// Probe 查局面（轮走方视角）；没有对应的残局库时 ok 为 false。p 为 nil 时总是 false。
+func (p *Prober) Probe(pos *xionghan.Position) (Result, bool) {
+	if p == nil || pos.TotalPieces() > p.maxPieces {
+		return Result{}, false
+	}
+	sig, ok := SignatureOf(pos)
+	if !ok {
+		return Result{}, false
+	}
+	t, ok := p.tables[pos.ActiveRules().Name+"/"+sig.String()]
+	if !ok {
+		return Result{}, false
+	}
+	return t.Probe(pos)
+}
+
+// BestMove 按残局库选着：能赢的走最快吃王的，和棋保持和棋，必输的尽量拖延。
+// 返回着法和走之前局面的结果；某个子局面查不到时跳过该着法。
+func (p *Prober) BestMove(pos *xionghan.Position, moves []xionghan.Move) (xionghan.Move, Result, bool) {
+	var best xionghan.Move
+	var bestRes Result
+	found := false
+	for _, mv := range moves {
+		var r Result
+		if target := pos.Board.Squares[mv.To]; target != 0 && target.Type() == xionghan.PieceKing {
+			r = Result{WDL: Win, Plies: 1}
+		} else {
+			child, ok := pos.ApplyMove(mv)
+			if !ok {
+				continue
+			}
+			cr, ok := p.Probe(child)
+			if !ok {
+				continue
+			}
+			r = Result{WDL: -cr.WDL}
+			if cr.WDL != Draw {
+				r.Plies = cr.Plies + 1
+			}
+		}
+		if !found || better(r, bestRes) {
+			best, bestRes, found = mv, r, true
+		}
+	}
+	return best, bestRes, found
+}
+
+// better a 是否比 b 好（轮走方视角）
+func better(a, b Result) bool {
+	if a.WDL != b.WDL {
+		return a.WDL > b.WDL
+	}
+	switch a.WDL {
+	case Win:
+		return a.Plies < b.Plies
+	case Loss:
+		return a.Plies > b.Plies
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+// Package tablebase 少子残局库：按子力组合枚举全部局面，逆推出吃王胜负和步数，
+// 写成按局面编号索引的文件，供引擎在搜索中直接查表。
+package tablebase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"xionghan/internal/xionghan"
+)
+
+// Signature 子力组合，按 Side 索引；每方第一个是王，其余按兵种编号排列
+type Signature [2][]xionghan.PieceType
+
+// ParseSignature 解析子力组合，写法是两方的 FEN 字母（不分大小写）用 "v" 隔开，
+// 红方在前、各含一个王（E）。例如 "EAvED" 车对士，"EFGvE" 炮兵对单王。
+func ParseSignature(s string) (Signature, error) {
+	var sig Signature
+	parts := strings.Split(strings.ToUpper(s), "V")
+	if len(parts) != 2 {
+		return sig, fmt.Errorf("signature %q: want <red>v<black>, e.g. EAvED", s)
+	}
+	for side, part := range parts {
+		kings := 0
+		var rest []xionghan.PieceType
+		for _, ch := range part {
+			pt, ok := xionghan.PieceTypeFromLetter(ch)
+			if !ok {
+				return sig, fmt.Errorf("signature %q: unknown piece %q", s, ch)
+			}
+			if pt == xionghan.PieceKing {
+				kings++
+				continue
+			}
+			rest = append(rest, pt)
+		}
+		if kings != 1 {
+			return sig, fmt.Errorf("signature %q: each side needs exactly one king (E)", s)
+		}
+		sort.Slice(rest, func(i, j int) bool { return rest[i] < rest[j] })
+		sig[side] = append([]xionghan.PieceType{xionghan.PieceKing}, rest...)
+	}
+	return sig, nil
+}
+
+// SignatureOf 局面的子力组合；某方没有王或不止一个王时 ok 为 false
+func SignatureOf(pos *xionghan.Position) (Signature, bool) {
+	var sig Signature
+	for _, side := range []xionghan.Side{xionghan.Red, xionghan.Black} {
+		if pos.PieceBitboard(side, xionghan.PieceKing).Count() != 1 {
+			return sig, false
+		}
+		sig[side] = []xionghan.PieceType{xionghan.PieceKing}
+		for pt := xionghan.PieceRook; pt <= xionghan.PieceWei; pt++ {
+			if pt == xionghan.PieceKing {
+				continue
+			}
+			for n := pos.PieceBitboard(side, pt).Count(); n > 0; n-- {
+				sig[side] = append(sig[side], pt)
+			}
+		}
+	}
+	return sig, true
+}
+
+func (s Signature) String() string {
+	var sb strings.Builder
+	for side, pts := range s {
+		if side > 0 {
+			sb.WriteByte('v')
+		}
+		for _, pt := range pts {
+			sb.WriteRune(pt.Letter())
+		}
+	}
+	return sb.String()
+}
+
+// Pieces 双方棋子总数
+func (s Signature) Pieces() int {
+	return len(s[xionghan.Red]) + len(s[xionghan.Black])
+}
+
+// without 去掉 side 一方第 i 个棋子（被吃）后的子力组合
+func (s Signature) without(side xionghan.Side, i int) Signature {
+	var out Signature
+	for sd := range s {
+		out[sd] = append([]xionghan.PieceType(nil), s[sd]...)
+	}
+	out[side] = append(out[side][:i], out[side][i+1:]...)
+	return out
+}
@@ -0,0 +1,97 @@
+package tablebase
+
+import "xionghan/internal/xionghan"
+
+// WDL 轮走方视角的胜负
+type WDL int8
+
+const (
+	Loss WDL = -1
+	Draw WDL = 0
+	Win  WDL = 1
+)
+
+func (w WDL) String() string {
+	switch w {
+	case Win:
+		return "win"
+	case Loss:
+		return "loss"
+	default:
+		return "draw"
+	}
+}
+
+// Result 查表结果（轮走方视角）。Plies 是双方最优应对下到终局的半回合数：
+// 胜方尽快吃王，负方尽量拖延；0 表示轮走方已无合法走法。和棋时为 0。
+type Result struct {
+	WDL   WDL
+	Plies int
+}
+
+// 每个局面一个字节：0 和棋（或无效编号）；奇数 b 胜，b 步内吃王；偶数 b 负，b-2 步后终局
+const maxPlies = 253
+
+func encode(r Result) byte {
+	switch r.WDL {
+	case Win:
+		return byte(r.Plies)
+	case Loss:
+		return byte(r.Plies + 2)
+	}
+	return 0
+}
+
+func decode(b byte) Result {
+	switch {
+	case b == 0:
+		return Result{WDL: Draw}
+	case b&1 == 1:
+		return Result{WDL: Win, Plies: int(b)}
+	default:
+		return Result{WDL: Loss, Plies: int(b) - 2}
+	}
+}
+
+// Table 一个子力组合的残局库
+type Table struct {
+	Sig   Signature
+	Rules string // 生成时使用的规则名（RuleSet.Name）
+	data  []byte
+	lay   *layout
+}
+
+// Size 局面编号的个数（含无效编号）
+func (t *Table) Size() int { return len(t.data) }
+
+// Probe 查局面；子力组合不符或棋子不在可出现的格子上时 ok 为 false
+func (t *Table) Probe(pos *xionghan.Position) (Result, bool) {
+	sqs := make([]int, len(t.lay.slots))
+	if !t.lay.squaresOf(pos, sqs) {
+		return Result{}, false
+	}
+	idx, ok := t.lay.index(sqs, pos.SideToMove)
+	if !ok {
+		return Result{}, false
+	}
+	return decode(t.data[idx]), true
+}
+
+// Stats 按胜/和/负统计有效局面数
+func (t *Table) Stats() (wins, draws, losses int) {
+	sqs := make([]int, len(t.lay.slots))
+	for idx, b := range t.data {
+		if _, ok := t.lay.decode(idx, sqs); !ok {
+			continue
+		}
+		switch decode(b).WDL {
+		case Win:
+			wins++
+		case Loss:
+			losses++
+		default:
+			draws++
+		}
+	}
+	return wins, draws, losses
+}
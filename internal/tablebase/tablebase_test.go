@@ -0,0 +1,146 @@
+package tablebase
+
+import (
+	"bytes"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"xionghan/internal/xionghan"
+)
+
+func TestParseSignature(t *testing.T) {
+	sig, err := ParseSignature("efgve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sig.String(); got != "EFGvE" {
+		t.Fatalf("String() = %q", got)
+	}
+	if sig.Pieces() != 4 {
+		t.Fatalf("Pieces() = %d", sig.Pieces())
+	}
+	for _, bad := range []string{"EA", "AvE", "EEvE", "EXvE"} {
+		if _, err := ParseSignature(bad); err == nil {
+			t.Fatalf("%q should be rejected", bad)
+		}
+	}
+}
+
+// 每个有效局面的值都要与它的子局面一致（胜取最短、负取最长），且与文件往返一致
+func TestGeneratedTableIsConsistent(t *testing.T) {
+	sig, _ := ParseSignature("EAvE")
+	g := NewGenerator(nil)
+	tb, err := g.Generate(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Tables()) != 2 {
+		t.Fatalf("expected EAvE and its EvE sub-table, got %d tables", len(g.Tables()))
+	}
+	wins, draws, losses := tb.Stats()
+	t.Logf("EAvE: %d wins, %d draws, %d losses", wins, draws, losses)
+	if wins == 0 || losses == 0 {
+		t.Fatalf("rook vs king should have both wins and losses for the side to move")
+	}
+	checkTable(t, g, tb)
+
+	// 同兵种两个子：编号要求格子升序，交换两子得到的是同一个编号
+	sig2, _ := ParseSignature("EvEDD")
+	tb2, err := g.Generate(sig2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTable(t, g, tb2)
+
+	var buf bytes.Buffer
+	if _, err := tb.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ReadTable(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Sig.String() != tb.Sig.String() || !bytes.Equal(back.data, tb.data) {
+		t.Fatalf("table changed after a write/read round trip")
+	}
+}
+
+// 不同规则的同一子力组合存进同一目录，读回来两套都在，各按自己的规则查
+func TestSaveKeepsRuleSetsApart(t *testing.T) {
+	dir := t.TempDir()
+	sig, _ := ParseSignature("EAvE")
+	for _, rules := range []*xionghan.RuleSet{&xionghan.OnlineRules, &xionghan.TrainingRules} {
+		g := NewGenerator(rules)
+		if _, err := g.Generate(sig); err != nil {
+			t.Fatal(err)
+		}
+		for _, tb := range g.Tables() {
+			if err := tb.Save(dir); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	p, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 4 {
+		t.Fatalf("loaded %d tables, want EAvE and EvE for both rule sets", p.Len())
+	}
+	for _, rules := range []*xionghan.RuleSet{&xionghan.OnlineRules, &xionghan.TrainingRules} {
+		tb, err := LoadTable(filepath.Join(dir, FileName(rules.Name, sig)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tb.Rules != rules.Name || tb.Sig.String() != sig.String() {
+			t.Fatalf("%s: file holds %s/%s", rules.Name, tb.Rules, tb.Sig)
+		}
+		sqs := make([]int, len(tb.lay.slots))
+		side, ok := tb.lay.decode(0, sqs)
+		for idx := 1; !ok; idx++ {
+			side, ok = tb.lay.decode(idx, sqs)
+		}
+		pos := xionghan.NewPosition(tb.lay.board(sqs), side, rules)
+		if _, ok := p.Probe(pos); !ok {
+			t.Fatalf("%s: position %s not found after loading", rules.Name, pos.Encode())
+		}
+	}
+}
+
+func checkTable(t *testing.T, g *Generator, tb *Table) {
+	t.Helper()
+	p := NewProber()
+	for _, table := range g.Tables() {
+		p.Add(table)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	sqs := make([]int, len(tb.lay.slots))
+	checked := 0
+	for checked < 3000 {
+		idx := rng.Intn(tb.Size())
+		side, ok := tb.lay.decode(idx, sqs)
+		if !ok {
+			continue
+		}
+		checked++
+		pos := xionghan.NewPosition(tb.lay.board(sqs), side, nil)
+		got, ok := p.Probe(pos)
+		if !ok {
+			t.Fatalf("probe failed for %s", pos.Encode())
+		}
+		moves := pos.GenerateLegalMoves()
+		want := Result{WDL: Loss}
+		if len(moves) > 0 {
+			_, want, ok = p.BestMove(pos, moves)
+			if !ok {
+				t.Fatalf("no child could be probed: %s", pos.Encode())
+			}
+		}
+		if got != want {
+			t.Fatalf("%s: table says %v in %d, children say %v in %d", pos.Encode(), got.WDL, got.Plies, want.WDL, want.Plies)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package xionghan
+
+// AttackersTo 返回 bySide 一方能一步吃到 sq 上敌子的全部棋子（按 sq 上有对方棋子计算）。
+// 与 IsAttacked 不同，这里按走法生成的吃子规则逐一反查，不套用将军的约定：
+//   - 象、士、尉也算；兵、马、檑不论是否过了长城
+//   - 檑只吃落单的子，锋只能从锋站出发吃，尉只吃左右隔一格的子
+//
+// 不考虑吃完后王对脸。用于静态交换评估（SEE）。
+func (p *Position) AttackersTo(sq int, bySide Side) Bitboard {
+	var out Bitboard
+	if bySide != Red && bySide != Black {
+		return out
+	}
+	squares := &p.Board.Squares
+	rules := p.ActiveRules()
+	row, col := rowOf(sq), colOf(sq)
+
+	var scratch boardBits
+	bb := p.bitsOr(&scratch)
+	occ := bb.all()
+	pieces := &bb.pieces[bySide]
+
+	// 车：第一个阻挡子；炮：第二个阻挡子
+	rook := makePiece(bySide, PieceRook)
+	cannon := makePiece(bySide, PieceCannon)
+	if !pieces[PieceRook].Or(pieces[PieceCannon]).IsZero() {
+		for dir := 0; dir < 4; dir++ {
+			_, blk := rayAttacks(sq, dir, occ)
+			if blk < 0 {
+				continue
+			}
+			if squares[blk] == rook {
+				out.Set(blk)
+			}
+			if to := nearest(rayMasks[blk][dir].And(occ), dir); to >= 0 && squares[to] == cannon {
+				out.Set(to)
+			}
+		}
+	}
+
+	// 马：日字和直三，马腿为空
+	if knight := makePiece(bySide, PieceKnight); !pieces[PieceKnight].IsZero() {
+		for _, ka := range knightAttackers[sq] {
+			if squares[ka.From] == knight && allEmpty(squares, ka.Legs) {
+				out.Set(ka.From)
+			}
+		}
+	}
+
+	// 檑：sq 上的子落单（檑环上与 sq 相邻的两格为空）
+	if lei := makePiece(bySide, PieceLei); !pieces[PieceLei].IsZero() {
+		for _, t := range leiAttackers[sq] {
+			if squares[t.Pos] == lei && allEmpty(squares, t.Leg) {
+				out.Set(t.Pos)
+			}
+		}
+	}
+
+	// 锋：从锋站出发沿轨道，途中为空
+	if feng := makePiece(bySide, PieceFeng); !pieces[PieceFeng].IsZero() {
+		t := rules.fengTables()
+		for _, rail := range t.rails[sq] {
+			if squares[rail.From] == feng && t.stations[rail.From] && allEmpty(squares, rail.Between) {
+				out.Set(rail.From)
+			}
+		}
+	}
+
+	// 兵：前方一格总能吃（未过长城时也能吃第一步）；过了长城还能横吃
+	if pawn := makePiece(bySide, PiecePawn); !pieces[PiecePawn].IsZero() {
+		if r := row - pawnDir(bySide); onBoard(r, col) && squares[indexOf(r, col)] == pawn {
+			out.Set(indexOf(r, col))
+		}
+		if rules.pawnPassedWall(bySide, row) {
+			for _, dc := range []int{-1, +1} {
+				if onBoard(row, col+dc) && squares[indexOf(row, col+dc)] == pawn {
+					out.Set(indexOf(row, col+dc))
+				}
+			}
+		}
+	}
+
+	// 尉：同一行左右隔一格，中间为空
+	if wei := makePiece(bySide, PieceWei); !pieces[PieceWei].IsZero() {
+		for _, dc := range []int{-1, +1} {
+			if onBoard(row, col+2*dc) && squares[indexOf(row, col+2*dc)] == wei && squares[indexOf(row, col+dc)] == 0 {
+				out.Set(indexOf(row, col+2*dc))
+			}
+		}
+	}
+
+	// 相：田字，相眼为空，不过长城
+	if elephant := makePiece(bySide, PieceElephant); !pieces[PieceElephant].IsZero() && !rules.elephantCrossed(bySide, row) {
+		for _, d := range bishopDirs {
+			r, c := row-2*d[0], col-2*d[1]
+			if onBoard(r, c) && squares[indexOf(r, c)] == elephant && squares[indexOf(row-d[0], col-d[1])] == 0 {
+				out.Set(indexOf(r, c))
+			}
+		}
+	}
+
+	// 士、王：九宫内一步
+	if rules.inPalace(bySide, row, col) {
+		advisor := makePiece(bySide, PieceAdvisor)
+		for _, d := range bishopDirs {
+			if r, c := row-d[0], col-d[1]; onBoard(r, c) && squares[indexOf(r, c)] == advisor {
+				out.Set(indexOf(r, c))
+			}
+		}
+		king := makePiece(bySide, PieceKing)
+		for _, d := range rookDirs {
+			if r, c := row-d[0], col-d[1]; onBoard(r, c) && squares[indexOf(r, c)] == king {
+				out.Set(indexOf(r, c))
+			}
+		}
+	}
+	return out
+}
+
+func allEmpty(squares *[NumSquares]Piece, sqs []int) bool {
+	for _, sq := range sqs {
+		if squares[sq] != 0 {
+			return false
+		}
+	}
+	return true
+}
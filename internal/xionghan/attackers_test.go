@@ -0,0 +1,39 @@
+package xionghan
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// AttackersTo 与走法生成一致：能吃到 sq 的棋子 = 伪合法走法里终点为 sq 的起点
+func TestAttackersToMatchesMoveGeneration(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+
+	odd := OnlineRules
+	odd.Name = "odd"
+	odd.WallRow = 5
+	odd.FengStep = 2
+	odd.FengStart = 1
+
+	for _, rules := range []*RuleSet{&OnlineRules, &odd} {
+		for i := 0; i < 300; i++ {
+			p := randomBoard(rng, 8+rng.Intn(60))
+			p.Rules = rules
+			for _, side := range []Side{Red, Black} {
+				var want [NumSquares]Bitboard
+				for _, mv := range p.GeneratePseudoMovesForSide(side) {
+					want[mv.To].Set(mv.From)
+				}
+				for sq, pc := range p.Board.Squares {
+					if pc == 0 || pc.Side() == side {
+						continue
+					}
+					if got := p.AttackersTo(sq, side); got != want[sq] {
+						t.Fatalf("%s: AttackersTo(%s, %s) has %d pieces, move generation %d\n%s",
+							rules.Name, SquareName(sq), sideName(side), got.Count(), want[sq].Count(), p.Encode())
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package xionghan
+
+import "math/bits"
+
+// Bitboard 169 格的位集合，第 sq 位对应 Board.Squares[sq]
+type Bitboard [3]uint64
+
+// SquareBB 只含 sq 一格的位集合
+func SquareBB(sq int) Bitboard {
+	var b Bitboard
+	b.Set(sq)
+	return b
+}
+
+func (b *Bitboard) Set(sq int)   { b[sq>>6] |= 1 << uint(sq&63) }
+func (b *Bitboard) Clear(sq int) { b[sq>>6] &^= 1 << uint(sq&63) }
+
+func (b Bitboard) Has(sq int) bool { return b[sq>>6]&(1<<uint(sq&63)) != 0 }
+func (b Bitboard) IsZero() bool    { return b[0]|b[1]|b[2] == 0 }
+
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(b[0]) + bits.OnesCount64(b[1]) + bits.OnesCount64(b[2])
+}
+
+func (b Bitboard) And(o Bitboard) Bitboard { return Bitboard{b[0] & o[0], b[1] & o[1], b[2] & o[2]} }
+func (b Bitboard) Or(o Bitboard) Bitboard  { return Bitboard{b[0] | o[0], b[1] | o[1], b[2] | o[2]} }
+func (b Bitboard) AndNot(o Bitboard) Bitboard {
+	return Bitboard{b[0] &^ o[0], b[1] &^ o[1], b[2] &^ o[2]}
+}
+
+// LSB 编号最小的格子，空集返回 -1
+func (b Bitboard) LSB() int {
+	for i, w := range b {
+		if w != 0 {
+			return i<<6 + bits.TrailingZeros64(w)
+		}
+	}
+	return -1
+}
+
+// MSB 编号最大的格子，空集返回 -1
+func (b Bitboard) MSB() int {
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] != 0 {
+			return i<<6 + 63 - bits.LeadingZeros64(b[i])
+		}
+	}
+	return -1
+}
+
+// PopLSB 取出并清除编号最小的格子，空集返回 -1。用于按格子顺序遍历：
+//
+//	for b := occ; !b.IsZero(); {
+//		sq := b.PopLSB()
+//		...
+//	}
+func (b *Bitboard) PopLSB() int {
+	sq := b.LSB()
+	if sq >= 0 {
+		b.Clear(sq)
+	}
+	return sq
+}
+
+// ======================== 射线 ========================
+
+// rayMasks[sq][dir] 从 sq 沿 rookDirs / bishopDirs（dir 0-3 / 4-7）向外、不含 sq 的整条射线
+var rayMasks [NumSquares][8]Bitboard
+
+// rayPositive 该方向上格子编号是否递增（离起点最近的是最低位）
+var rayPositive [8]bool
+
+func init() {
+	dirs := append(append([][2]int{}, rookDirs...), bishopDirs...)
+	for i, d := range dirs {
+		rayPositive[i] = d[0]*Cols+d[1] > 0
+	}
+	for sq := 0; sq < NumSquares; sq++ {
+		row, col := rowOf(sq), colOf(sq)
+		for i, d := range dirs {
+			for r, c := row+d[0], col+d[1]; onBoard(r, c); r, c = r+d[0], c+d[1] {
+				rayMasks[sq][i].Set(indexOf(r, c))
+			}
+		}
+	}
+}
+
+// nearest 沿 dir 方向离起点最近的格子，空集返回 -1
+func nearest(b Bitboard, dir int) int {
+	if rayPositive[dir] {
+		return b.LSB()
+	}
+	return b.MSB()
+}
+
+// popNearest 取出并清除沿 dir 方向最近的格子，用于由近到远遍历
+func popNearest(b *Bitboard, dir int) int {
+	sq := nearest(*b, dir)
+	if sq >= 0 {
+		b.Clear(sq)
+	}
+	return sq
+}
+
+// rayAttacks 从 sq 沿 dir 直到第一个阻挡子（含）的格子，以及阻挡子的位置（没有为 -1）
+func rayAttacks(sq, dir int, occ Bitboard) (Bitboard, int) {
+	ray := rayMasks[sq][dir]
+	blk := nearest(ray.And(occ), dir)
+	if blk < 0 {
+		return ray, -1
+	}
+	return ray.AndNot(rayMasks[blk][dir]), blk
+}
+
+// ======================== 与棋盘同步的位棋盘 ========================
+
+// boardBits 按阵营和兵种划分的位棋盘
+type boardBits struct {
+	pieces [2][zobristPieceTypes]Bitboard // [side][PieceType]
+	occ    [2]Bitboard                    // [side] 该方全部棋子
+}
+
+func (bb *boardBits) build(b *Board) {
+	*bb = boardBits{}
+	for sq, pc := range b.Squares {
+		if pc != 0 {
+			bb.add(pc, sq)
+		}
+	}
+}
+
+func (bb *boardBits) add(pc Piece, sq int) {
+	side := pc.Side()
+	bb.pieces[side][pc.Type()].Set(sq)
+	bb.occ[side].Set(sq)
+}
+
+func (bb *boardBits) remove(pc Piece, sq int) {
+	side := pc.Side()
+	bb.pieces[side][pc.Type()].Clear(sq)
+	bb.occ[side].Clear(sq)
+}
+
+func (bb *boardBits) all() Bitboard {
+	return bb.occ[Red].Or(bb.occ[Black])
+}
+
+// bitsOr 返回与棋盘同步的位棋盘；未同步时在 scratch 上临时构建（不写回 p，p 可能正被并发读取）
+func (p *Position) bitsOr(scratch *boardBits) *boardBits {
+	if p.indexed {
+		return &p.bb
+	}
+	scratch.build(&p.Board)
+	return scratch
+}
+
+// syncIndex 按棋盘重建位棋盘和王的位置；构造局面或直接改动 Board 后调用
+func (p *Position) syncIndex() {
+	p.bb.build(&p.Board)
+	p.kingSq = p.scanKings()
+	p.indexed = true
+}
+
+// Occupied 返回 side 一方全部棋子的位集合，side 为 NoSide 时返回双方
+func (p *Position) Occupied(side Side) Bitboard {
+	var scratch boardBits
+	bb := p.bitsOr(&scratch)
+	if side != Red && side != Black {
+		return bb.all()
+	}
+	return bb.occ[side]
+}
+
+// PieceBitboard 返回 side 一方 pt 类棋子的位集合，可以当作棋子列表遍历
+func (p *Position) PieceBitboard(side Side, pt PieceType) Bitboard {
+	if side != Red && side != Black || pt <= PieceNone || pt > PieceWei {
+		return Bitboard{}
+	}
+	var scratch boardBits
+	return p.bitsOr(&scratch).pieces[side][pt]
+}
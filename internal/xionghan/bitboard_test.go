@@ -0,0 +1,127 @@
+package xionghan
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBitboardOps(t *testing.T) {
+	var b Bitboard
+	if b.LSB() != -1 || b.MSB() != -1 || b.PopLSB() != -1 {
+		t.Fatalf("empty bitboard should report -1")
+	}
+	sqs := []int{0, 63, 64, 127, 128, NumSquares - 1}
+	for _, sq := range sqs {
+		b.Set(sq)
+	}
+	if b.Count() != len(sqs) {
+		t.Fatalf("count: got %d want %d", b.Count(), len(sqs))
+	}
+	if b.LSB() != 0 || b.MSB() != NumSquares-1 {
+		t.Fatalf("lsb/msb: got %d/%d", b.LSB(), b.MSB())
+	}
+	for _, want := range sqs {
+		if got := b.PopLSB(); got != want {
+			t.Fatalf("pop: got %d want %d", got, want)
+		}
+	}
+	if !b.IsZero() {
+		t.Fatalf("bitboard not empty after popping every square")
+	}
+}
+
+func TestRayAttacksMatchBoardWalk(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	dirs := append(append([][2]int{}, rookDirs...), bishopDirs...)
+	for i := 0; i < 200; i++ {
+		p := randomBoard(rng, 10+rng.Intn(60))
+		occ := p.Occupied(NoSide)
+		for sq := 0; sq < NumSquares; sq++ {
+			for dir, d := range dirs {
+				var want Bitboard
+				wantBlk := -1
+				for r, c := rowOf(sq)+d[0], colOf(sq)+d[1]; onBoard(r, c); r, c = r+d[0], c+d[1] {
+					want.Set(indexOf(r, c))
+					if p.Board.Squares[indexOf(r, c)] != 0 {
+						wantBlk = indexOf(r, c)
+						break
+					}
+				}
+				got, blk := rayAttacks(sq, dir, occ)
+				if got != want || blk != wantBlk {
+					t.Fatalf("rayAttacks(%s, %d): blocker %d want %d", SquareName(sq), dir, blk, wantBlk)
+				}
+			}
+		}
+	}
+}
+
+func TestBitboardsFollowMakeUnmake(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	check := func(p *Position, label string) {
+		t.Helper()
+		var want boardBits
+		want.build(&p.Board)
+		if p.bb != want {
+			t.Fatalf("%s: bitboards out of sync with the board", label)
+		}
+		if p.TotalPieces() != want.all().Count() {
+			t.Fatalf("%s: TotalPieces %d, bitboards %d", label, p.TotalPieces(), want.all().Count())
+		}
+	}
+
+	p := NewInitialPosition()
+	check(p, "initial")
+	for ply := 0; ply < 200; ply++ {
+		moves := p.GenerateLegalMoves()
+		if len(moves) == 0 {
+			break
+		}
+		p.MakeMove(moves[rng.Intn(len(moves))])
+		check(p, "after make")
+	}
+	for p.UnmakeMove() {
+		check(p, "after unmake")
+	}
+
+	// 棋子列表：遍历位集合得到的格子与逐格扫描一致
+	for _, side := range []Side{Red, Black} {
+		for pt := PieceRook; pt <= PieceWei; pt++ {
+			var got []int
+			for b := p.PieceBitboard(side, pt); !b.IsZero(); {
+				got = append(got, b.PopLSB())
+			}
+			var want []int
+			for sq, pc := range p.Board.Squares {
+				if pc == makePiece(side, pt) {
+					want = append(want, sq)
+				}
+			}
+			if len(got) != len(want) {
+				t.Fatalf("%s %d: piece list %v, board has %v", sideName(side), pt, got, want)
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Fatalf("%s %d: piece list %v, board has %v", sideName(side), pt, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestLiteralPositionMatchesIndexed(t *testing.T) {
+	rng := rand.New(rand.NewSource(21))
+	for i := 0; i < 50; i++ {
+		lit := randomBoard(rng, 10+rng.Intn(50))
+		idx := NewPosition(lit.Board, lit.SideToMove, nil)
+		a, b := lit.GenerateLegalMoves(), idx.GenerateLegalMoves()
+		if len(a) != len(b) {
+			t.Fatalf("literal position has %d moves, indexed %d", len(a), len(b))
+		}
+		for j := range a {
+			if a[j] != b[j] {
+				t.Fatalf("move %d differs: %+v vs %+v", j, a[j], b[j])
+			}
+		}
+	}
+}
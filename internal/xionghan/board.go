@@ -10,7 +10,7 @@ const (
 	Cols       = 13
 	NumSquares = Rows * Cols
 
-	WallRow = 6 // 中间“长城”，0-based 第 7 行
+	WallRow = 6 // 默认规则的“长城”，0-based 第 7 行；具体对局以 RuleSet.WallRow 为准
 )
 
 func indexOf(row, col int) int { return row*Cols + col }
@@ -42,32 +42,6 @@ func pawnDir(side Side) int {
 	return 0
 }
 
-// 是否已经“过长城”
-func pawnPassedWall(side Side, row int) bool {
-	if side == Red {
-		return row < WallRow
-	}
-	if side == Black {
-		return row > WallRow
-	}
-	return false
-}
-
-// 是否在九宫
-func inPalace(side Side, row, col int) bool {
-	midCol := Cols / 2 // 6
-	if col < midCol-1 || col > midCol+1 {
-		return false
-	}
-	if side == Black {
-		return row >= 1 && row <= 3
-	}
-	if side == Red {
-		return row >= Rows-4 && row <= Rows-2 // 9..11
-	}
-	return false
-}
-
 var letterToPieceType = map[rune]PieceType{
 	'a': PieceRook,     // 车 chariot
 	'b': PieceKnight,   // 马 horse
@@ -81,6 +55,17 @@ var letterToPieceType = map[rune]PieceType{
 	'j': PieceWei,      // 卫 wei
 }
 
+// PieceTypeFromLetter FEN 字母（不分大小写）对应的兵种
+func PieceTypeFromLetter(ch rune) (PieceType, bool) {
+	pt, ok := letterToPieceType[unicode.ToLower(ch)]
+	return pt, ok
+}
+
+// Letter 兵种的 FEN 字母（大写，即红方写法）
+func (pt PieceType) Letter() rune {
+	return pieceToChar(makePiece(Red, pt))
+}
+
 func pieceToChar(p Piece) rune {
 	if p == 0 {
 		return '.'
@@ -156,9 +141,25 @@ func parseInitialBoard() Board {
 
 func NewInitialPosition() *Position {
 	pos := &Position{
-		Board:      parseInitialBoard(),
-		SideToMove: Red, // 红先
+		Board:          parseInitialBoard(),
+		SideToMove:     Red, // 红先
+		FullmoveNumber: 1,
+	}
+	pos.Hash = pos.CalculateHash()
+	pos.syncIndex()
+	return pos
+}
+
+// NewPosition 用现成的棋盘构造局面（不校验），并建好位棋盘。
+// 直接写 &Position{Board: b} 也可以用，只是每次查询都要重新扫描棋盘。
+func NewPosition(b Board, side Side, rules *RuleSet) *Position {
+	pos := &Position{
+		Board:          b,
+		SideToMove:     side,
+		FullmoveNumber: 1,
+		Rules:          rules,
 	}
 	pos.Hash = pos.CalculateHash()
+	pos.syncIndex()
 	return pos
 }
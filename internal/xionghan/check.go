@@ -1,55 +1,178 @@
 package xionghan
 
-// IsAttacked 判断 sq 这个格子是否被 bySide 这一方攻击。
-// 采用走法模拟：只要对方任何一个棋子能合法地“走到”这个位置，就说明该位置被攻击。
-func (p *Position) IsAttacked(sq int, bySide Side) bool {
-	// VCF 优化：在判断攻击时，忽略无法将军的子力（象、士、卫）
-	for s := 0; s < NumSquares; s++ {
-		pc := p.Board.Squares[s]
-		if pc == 0 || pc.Side() != bySide {
-			continue
+// ======================== 反向攻击表 ========================
+
+// knightAttack 能一步跳到某格的马的位置，以及需要为空的马腿（日字一个，直三两个）
+type knightAttack struct {
+	From int
+	Legs []int
+}
+
+var knightAttackers [NumSquares][]knightAttack
+
+func init() {
+	initAttackTables()
+}
+
+func initAttackTables() {
+	for sq := 0; sq < NumSquares; sq++ {
+		row, col := rowOf(sq), colOf(sq)
+
+		// 马：把 genKnightMoves 的走法反过来
+		for _, m := range knightLegMoves {
+			fr, fc := row-m.Dr, col-m.Dc
+			if !onBoard(fr, fc) {
+				continue
+			}
+			knightAttackers[sq] = append(knightAttackers[sq], knightAttack{
+				From: indexOf(fr, fc),
+				Legs: []int{indexOf(fr+m.Br, fc+m.Bc)},
+			})
+		}
+		for _, d := range rookDirs {
+			fr, fc := row-3*d[0], col-3*d[1]
+			if !onBoard(fr, fc) {
+				continue
+			}
+			knightAttackers[sq] = append(knightAttackers[sq], knightAttack{
+				From: indexOf(fr, fc),
+				Legs: []int{indexOf(fr+d[0], fc+d[1]), indexOf(fr+2*d[0], fc+2*d[1])},
+			})
 		}
+	}
+}
 
-		pt := pc.Type()
-		// 象、士、卫 无法将军，直接跳过其走法生成
-		if pt == PieceElephant || pt == PieceAdvisor || pt == PieceWei {
-			continue
+// IsAttacked 判断 sq 这个格子是否被 bySide 这一方攻击，即对方是否有棋子能一步走到 sq。
+// 从 sq 反向查表，不生成走法：车炮檑用位棋盘射线找阻挡子，马、檑吃子、锋、兵查反向表。
+// 与原先的走法模拟保持同样的约定：
+//   - 象、士、尉无法将军，不算攻击；兵、马、檑在己方半场（未过长城）时不算
+//   - sq 为空时按“走到”算（炮、檑滑行即可），有敌子时按“吃到”算
+func (p *Position) IsAttacked(sq int, bySide Side) bool {
+	if bySide != Red && bySide != Black {
+		return false
+	}
+	squares := &p.Board.Squares
+	target := squares[sq]
+	if target != 0 && target.Side() == bySide {
+		return false
+	}
+	empty := target == 0
+	rules := p.ActiveRules()
+	row, col := rowOf(sq), colOf(sq)
+
+	var scratch boardBits
+	bb := p.bitsOr(&scratch)
+	occ := bb.all()
+	pieces := &bb.pieces[bySide]
+
+	rook := makePiece(bySide, PieceRook)
+	cannon := makePiece(bySide, PieceCannon)
+	king := makePiece(bySide, PieceKing)
+	lei := makePiece(bySide, PieceLei)
+
+	// 1. 直线：车；炮（空格滑到，有子隔一子吃）；檑滑行；王一步
+	if !pieces[PieceRook].Or(pieces[PieceCannon]).Or(pieces[PieceLei]).Or(pieces[PieceKing]).IsZero() {
+		for dir := 0; dir < 4; dir++ {
+			_, blk := rayAttacks(sq, dir, occ)
+			if blk < 0 {
+				continue
+			}
+			pc := squares[blk]
+			if pc == rook {
+				return true
+			}
+			// 王只走一步：阻挡子就是射线上的第一格
+			if pc == king && blk == nearest(rayMasks[sq][dir], dir) && rules.inPalace(bySide, row, col) {
+				return true
+			}
+			if empty {
+				if pc == cannon || (pc == lei && rules.pawnPassedWall(bySide, rowOf(blk))) {
+					return true
+				}
+				continue
+			}
+			if to := nearest(rayMasks[blk][dir].And(occ), dir); to >= 0 && squares[to] == cannon {
+				return true
+			}
 		}
+	}
 
-		// 优化：兵、马、檑 只有在对方半场（过长城）才可能将军，在自己半场直接跳过
-		if pt == PiecePawn || pt == PieceKnight || pt == PieceLei {
-			r := s / Cols
-			isOpponentHalf := false
-			if bySide == Red {
-				isOpponentHalf = r < WallRow // 红方在上方（0-5行）为对方半场
-			} else {
-				isOpponentHalf = r > WallRow // 黑方在下方（7-12行）为对方半场
+	// 2. 斜线：檑滑行到空格；或吃 sq 上的落单棋子（环上两侧邻格为空）
+	if leis := pieces[PieceLei]; !leis.IsZero() {
+		if empty {
+			for dir := 4; dir < 8; dir++ {
+				if _, blk := rayAttacks(sq, dir, occ); blk >= 0 && squares[blk] == lei && rules.pawnPassedWall(bySide, rowOf(blk)) {
+					return true
+				}
+			}
+		} else {
+			for _, t := range leiAttackers[sq] {
+				if squares[t.Pos] != lei || !rules.pawnPassedWall(bySide, rowOf(t.Pos)) {
+					continue
+				}
+				lone := true
+				for _, leg := range t.Leg {
+					if squares[leg] != 0 {
+						lone = false
+						break
+					}
+				}
+				if lone {
+					return true
+				}
 			}
-			if !isOpponentHalf {
+		}
+	}
+
+	// 3. 马：日字和直三，马腿为空
+	if !pieces[PieceKnight].IsZero() {
+		knight := makePiece(bySide, PieceKnight)
+		for _, ka := range knightAttackers[sq] {
+			if squares[ka.From] != knight || !rules.pawnPassedWall(bySide, rowOf(ka.From)) {
 				continue
 			}
+			blocked := false
+			for _, leg := range ka.Legs {
+				if squares[leg] != 0 {
+					blocked = true
+					break
+				}
+			}
+			if !blocked {
+				return true
+			}
 		}
+	}
 
-		var moves []Move
-		switch pt {
-		case PieceRook:
-			genRookMoves(p, s, &moves)
-		case PieceCannon:
-			genCannonMoves(p, s, &moves)
-		case PieceKnight:
-			genKnightMoves(p, s, &moves)
-		case PieceKing:
-			genKingMoves(p, s, &moves)
-		case PiecePawn:
-			genPawnMoves(p, s, &moves)
-		case PieceLei:
-			genLeiMoves(p, s, &moves)
-		case PieceFeng:
-			genFengMoves(p, s, &moves)
+	// 4. 兵：过了长城的兵前进一步或横走一步
+	if !pieces[PiecePawn].IsZero() {
+		pawn := makePiece(bySide, PiecePawn)
+		if r := row - pawnDir(bySide); onBoard(r, col) && rules.pawnPassedWall(bySide, r) && squares[indexOf(r, col)] == pawn {
+			return true
+		}
+		if rules.pawnPassedWall(bySide, row) {
+			if (col > 0 && squares[sq-1] == pawn) || (col < Cols-1 && squares[sq+1] == pawn) {
+				return true
+			}
 		}
+	}
 
-		for _, mv := range moves {
-			if mv.To == sq {
+	// 5. 锋：沿轨道反查，途中为空；吃子只能从锋站出发
+	if !pieces[PieceFeng].IsZero() {
+		feng := makePiece(bySide, PieceFeng)
+		t := rules.fengTables()
+		for _, rail := range t.rails[sq] {
+			if squares[rail.From] != feng || (!empty && !t.stations[rail.From]) {
+				continue
+			}
+			clear := true
+			for _, b := range rail.Between {
+				if squares[b] != 0 {
+					clear = false
+					break
+				}
+			}
+			if clear {
 				return true
 			}
 		}
@@ -59,13 +182,7 @@ func (p *Position) IsAttacked(sq int, bySide Side) bool {
 
 // IsInCheck 判断 side 这一方的王是否被将军
 func (p *Position) IsInCheck(side Side) bool {
-	kingSq := -1
-	for s, pc := range p.Board.Squares {
-		if pc != 0 && pc.Side() == side && pc.Type() == PieceKing {
-			kingSq = s
-			break
-		}
-	}
+	kingSq := p.KingSquare(side)
 	if kingSq == -1 {
 		return false
 	}
@@ -83,7 +200,7 @@ func (p *Position) IsAttackedByPawn(sq int, bySide Side) bool {
 		return targetPc != 0 && targetPc.Side() == bySide && targetPc.Type() == PiecePawn
 	}
 	if checkP(r-pawnMoveDir, c) { return true }
-	if pawnPassedWall(bySide, r) {
+	if p.ActiveRules().pawnPassedWall(bySide, r) {
 		if (c > 0 && checkP(r, c-1)) || (c < Cols-1 && checkP(r, c+1)) { return true }
 	}
 	return false
@@ -0,0 +1,133 @@
+package xionghan
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// isAttackedByMoveGen 原先的实现：生成对方每个棋子的走法，看能否走到 sq。
+// 作为参照，检验查表版 IsAttacked。
+func isAttackedByMoveGen(p *Position, sq int, bySide Side) bool {
+	rules := p.ActiveRules()
+	occ := p.Occupied(NoSide)
+	for s := 0; s < NumSquares; s++ {
+		pc := p.Board.Squares[s]
+		if pc == 0 || pc.Side() != bySide {
+			continue
+		}
+		pt := pc.Type()
+		if pt == PieceElephant || pt == PieceAdvisor || pt == PieceWei {
+			continue
+		}
+		if pt == PiecePawn || pt == PieceKnight || pt == PieceLei {
+			if !rules.pawnPassedWall(bySide, s/Cols) {
+				continue
+			}
+		}
+
+		var moves []Move
+		switch pt {
+		case PieceRook:
+			genRookMoves(p, s, occ, &moves)
+		case PieceCannon:
+			genCannonMoves(p, s, occ, &moves)
+		case PieceKnight:
+			genKnightMoves(p, s, &moves)
+		case PieceKing:
+			genKingMoves(p, s, &moves)
+		case PiecePawn:
+			genPawnMoves(p, s, &moves)
+		case PieceLei:
+			genLeiMoves(p, s, occ, &moves)
+		case PieceFeng:
+			genFengMoves(p, s, &moves)
+		}
+		for _, mv := range moves {
+			if mv.To == sq {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// randomBoard 随机撒子，不要求局面合法，覆盖过/未过长城、锋站内外等情况
+func randomBoard(rng *rand.Rand, pieces int) *Position {
+	p := &Position{SideToMove: Side(rng.Intn(2))}
+	for i := 0; i < pieces; i++ {
+		pt := PieceType(1 + rng.Intn(int(PieceWei)))
+		p.Board.Squares[rng.Intn(NumSquares)] = makePiece(Side(rng.Intn(2)), pt)
+	}
+	return p
+}
+
+func compareAttacks(t *testing.T, p *Position, label string) {
+	t.Helper()
+	for sq := 0; sq < NumSquares; sq++ {
+		for _, side := range []Side{Red, Black} {
+			got := p.IsAttacked(sq, side)
+			want := isAttackedByMoveGen(p, sq, side)
+			if got != want {
+				t.Fatalf("%s: IsAttacked(%s, %s) = %v, move generation says %v\n%s",
+					label, SquareName(sq), sideName(side), got, want, p.Encode())
+			}
+		}
+	}
+}
+
+func TestIsAttackedMatchesMoveGeneration(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+
+	odd := OnlineRules
+	odd.Name = "odd"
+	odd.WallRow = 5
+	odd.FengStep = 2
+	odd.FengStart = 1
+
+	for _, rules := range []*RuleSet{&OnlineRules, &odd} {
+		for i := 0; i < 300; i++ {
+			p := randomBoard(rng, 8+rng.Intn(60))
+			p.Rules = rules
+			compareAttacks(t, p, rules.Name+" random board")
+		}
+	}
+
+	// 随机对局中的局面
+	for game := 0; game < 5; game++ {
+		p := NewInitialPosition()
+		for ply := 0; ply < 120; ply++ {
+			compareAttacks(t, p, "random game")
+			moves := p.GenerateLegalMoves()
+			if len(moves) == 0 || !p.KingExists(Red) || !p.KingExists(Black) {
+				break
+			}
+			p.MakeMove(moves[rng.Intn(len(moves))])
+		}
+	}
+}
+
+func TestKingSquareTracksMakeUnmake(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	p := NewInitialPosition()
+	for ply := 0; ply < 200; ply++ {
+		moves := p.GenerateLegalMoves()
+		if len(moves) == 0 {
+			break
+		}
+		p.MakeMove(moves[rng.Intn(len(moves))])
+		if got, want := p.kingSq, p.scanKings(); got != want {
+			t.Fatalf("ply %d: king squares %v, board has %v", ply, got, want)
+		}
+	}
+	for p.UnmakeMove() {
+		if got, want := p.kingSq, p.scanKings(); got != want {
+			t.Fatalf("after unmake: king squares %v, board has %v", got, want)
+		}
+	}
+
+	// 直接构造的局面没有缓存，按需扫描
+	lit := &Position{Board: p.Board}
+	if lit.KingSquare(Red) != p.KingSquare(Red) || lit.KingSquare(Black) != p.KingSquare(Black) {
+		t.Fatalf("KingSquare on a literal position disagrees with the tracked one")
+	}
+}
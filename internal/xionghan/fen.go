@@ -2,11 +2,14 @@ package xionghan
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
 )
 
-// 简单 FEN-like：13行用“/”隔开，空位用数字压缩；空格后 w/b 表示先后
+// 简单 FEN-like：13行用“/”隔开，空位用数字压缩（10~13 个空位写成 ':' ~ '='）；
+// 之后依次是行棋方 w/b、未吃子半回合数、回合数
 func (p *Position) Encode() string {
 	var sb strings.Builder
 	for r := 0; r < Rows; r++ {
@@ -37,64 +40,179 @@ func (p *Position) Encode() string {
 	} else {
 		sb.WriteByte('b')
 	}
+	fullmove := p.FullmoveNumber
+	if fullmove < 1 {
+		fullmove = 1
+	}
+	fmt.Fprintf(&sb, " %d %d", p.HalfmoveClock, fullmove)
 	return sb.String()
 }
 
 var ErrInvalidFEN = errors.New("invalid FEN")
 
+// FENError 描述 FEN 中的具体错误；Row/Col 从 1 开始，对应棋盘第几行第几列，
+// 与具体格子无关的错误（如行棋方字段）为 0。errors.Is(err, ErrInvalidFEN) 成立。
+type FENError struct {
+	Row, Col int
+	Msg      string
+}
+
+func (e *FENError) Error() string {
+	if e.Row > 0 && e.Col > 0 {
+		return fmt.Sprintf("invalid FEN: row %d col %d: %s", e.Row, e.Col, e.Msg)
+	}
+	if e.Row > 0 {
+		return fmt.Sprintf("invalid FEN: row %d: %s", e.Row, e.Msg)
+	}
+	return "invalid FEN: " + e.Msg
+}
+
+func (e *FENError) Unwrap() error { return ErrInvalidFEN }
+
+func fenErrorAt(sq int, format string, args ...any) *FENError {
+	return &FENError{Row: rowOf(sq) + 1, Col: colOf(sq) + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// maxPieceCount 每方每种棋子的最多个数：没有升变，所以不会超过开局数量
+var maxPieceCount = func() (m [PieceWei + 1]int) {
+	b := parseInitialBoard()
+	for _, pc := range b.Squares {
+		if pc > 0 {
+			m[pc.Type()]++
+		}
+	}
+	return m
+}()
+
+// DecodePosition 按默认规则解析并校验 FEN。
+// 格式：棋盘 行棋方(w/b) [未吃子半回合数 [回合数]]。
 func DecodePosition(fen string) (*Position, error) {
-	parts := strings.Split(fen, " ")
+	return DecodePositionWithRules(fen, nil)
+}
+
+// DecodePositionWithRules 按指定规则解析并校验 FEN（九宫、长城、王对脸）；
+// rules 为 nil 时用 OnlineRules，返回的局面会带上 rules。
+func DecodePositionWithRules(fen string, rules *RuleSet) (*Position, error) {
+	parts := strings.Fields(fen)
 	if len(parts) < 2 {
-		return nil, ErrInvalidFEN
+		return nil, &FENError{Msg: "missing side to move"}
+	}
+	if len(parts) > 4 {
+		return nil, &FENError{Msg: fmt.Sprintf("unexpected field %q", parts[4])}
 	}
 	rows := strings.Split(parts[0], "/")
 	if len(rows) != Rows {
-		return nil, ErrInvalidFEN
+		return nil, &FENError{Msg: fmt.Sprintf("got %d rows, want %d", len(rows), Rows)}
 	}
 	var b Board
 	for r := 0; r < Rows; r++ {
-		row := rows[r]
 		c := 0
-		for _, ch := range row {
+		for _, ch := range rows[r] {
 			if c >= Cols {
-				return nil, ErrInvalidFEN
+				return nil, &FENError{Row: r + 1, Msg: fmt.Sprintf("more than %d columns", Cols)}
 			}
-			if ch >= '1' && ch <= '9' {
-				n := int(ch - '0')
-				c += n
+			// 连续数字相加；':' ~ '=' 表示 10~13 个空位（Encode 的写法）
+			if ch >= '1' && ch <= '=' {
+				c += int(ch - '0')
 				continue
 			}
 			if ch == '.' {
 				c++
 				continue
 			}
-			isUpper := unicode.IsUpper(ch)
-			base := unicode.ToLower(ch)
-			pt, ok := letterToPieceType[base]
+			pt, ok := letterToPieceType[unicode.ToLower(ch)]
 			if !ok {
-				return nil, ErrInvalidFEN
+				return nil, &FENError{Row: r + 1, Col: c + 1, Msg: fmt.Sprintf("unknown piece %q", ch)}
 			}
 			side := Black
-			if isUpper {
+			if unicode.IsUpper(ch) {
 				side = Red
 			}
 			b.Squares[indexOf(r, c)] = makePiece(side, pt)
 			c++
 		}
 		if c != Cols {
-			return nil, ErrInvalidFEN
+			return nil, &FENError{Row: r + 1, Msg: fmt.Sprintf("got %d columns, want %d", c, Cols)}
 		}
 	}
+
 	var stm Side
-	if parts[1] == "w" {
+	switch parts[1] {
+	case "w":
 		stm = Red
-	} else {
+	case "b":
 		stm = Black
+	default:
+		return nil, &FENError{Msg: fmt.Sprintf("side to move must be w or b, got %q", parts[1])}
 	}
 	pos := &Position{
-		Board:      b,
-		SideToMove: stm,
+		Board:          b,
+		SideToMove:     stm,
+		FullmoveNumber: 1,
+		Rules:          rules,
+	}
+	if len(parts) > 2 {
+		n, err := strconv.Atoi(parts[2])
+		if err != nil || n < 0 {
+			return nil, &FENError{Msg: fmt.Sprintf("bad halfmove clock %q", parts[2])}
+		}
+		pos.HalfmoveClock = n
+	}
+	if len(parts) > 3 {
+		n, err := strconv.Atoi(parts[3])
+		if err != nil || n < 1 {
+			return nil, &FENError{Msg: fmt.Sprintf("bad fullmove number %q", parts[3])}
+		}
+		pos.FullmoveNumber = n
+	}
+	pos.syncIndex()
+	if err := validateBoard(&pos.Board, stm, pos.ActiveRules()); err != nil {
+		return nil, err
+	}
+	if pos.ActiveRules().KingsFacingIllegal && pos.kingsFace() {
+		return nil, &FENError{Msg: "kings face each other"}
 	}
 	pos.Hash = pos.CalculateHash()
 	return pos, nil
 }
+
+// validateBoard 检查王、士、相的位置和各子数量。
+// 吃王即终局，所以轮到走的一方可以没有王（已结束的对局）；刚走完的一方必须有王。
+func validateBoard(b *Board, stm Side, rules *RuleSet) error {
+	var counts [2][PieceWei + 1]int
+	for sq, pc := range b.Squares {
+		if pc == 0 {
+			continue
+		}
+		side, pt := pc.Side(), pc.Type()
+		counts[side][pt]++
+		if counts[side][pt] > maxPieceCount[pt] {
+			if pt == PieceKing {
+				return fenErrorAt(sq, "%s has more than one king", sideName(side))
+			}
+			return fenErrorAt(sq, "%s has more than %d %q", sideName(side), maxPieceCount[pt], pieceToChar(pc))
+		}
+		row, col := rowOf(sq), colOf(sq)
+		switch pt {
+		case PieceKing, PieceAdvisor:
+			if !rules.inPalace(side, row, col) {
+				return fenErrorAt(sq, "%q outside the palace", pieceToChar(pc))
+			}
+		case PieceElephant:
+			if rules.elephantCrossed(side, row) {
+				return fenErrorAt(sq, "%q across the wall", pieceToChar(pc))
+			}
+		}
+	}
+	if moved := opposite(stm); counts[moved][PieceKing] == 0 {
+		return &FENError{Msg: sideName(moved) + " has no king"}
+	}
+	return nil
+}
+
+func sideName(side Side) string {
+	if side == Red {
+		return "red"
+	}
+	return "black"
+}
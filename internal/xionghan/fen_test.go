@@ -0,0 +1,129 @@
+package xionghan
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// initialRows 初始局面的 13 行（全部用 '.' 表示空位）
+func initialRows() []string {
+	return strings.Split(strings.TrimSpace(initialBoardString), "\n")
+}
+
+// editFEN 在初始局面上修改若干格后拼成 FEN
+func editFEN(stm string, edits map[[2]int]byte) string {
+	rows := initialRows()
+	for rc, ch := range edits {
+		row := []byte(rows[rc[0]])
+		row[rc[1]] = ch
+		rows[rc[0]] = string(row)
+	}
+	return strings.Join(rows, "/") + " " + stm
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	pos := NewInitialPosition()
+	for ply := 0; ply < 60; ply++ {
+		fen := pos.Encode()
+		got, err := DecodePosition(fen)
+		if err != nil {
+			t.Fatalf("ply %d: decode %q: %v", ply, fen, err)
+		}
+		if got.Board != pos.Board || got.SideToMove != pos.SideToMove || got.Hash != pos.Hash ||
+			got.HalfmoveClock != pos.HalfmoveClock || got.FullmoveNumber != pos.FullmoveNumber {
+			t.Fatalf("ply %d: round trip mismatch for %q", ply, fen)
+		}
+		moves := pos.GenerateLegalMoves()
+		if len(moves) == 0 || pos.Outcome(nil).IsOver() {
+			break
+		}
+		pos, _ = pos.ApplyMove(moves[rng.Intn(len(moves))])
+	}
+	if pos.FullmoveNumber < 2 {
+		t.Fatalf("fullmove number not advanced: %d", pos.FullmoveNumber)
+	}
+}
+
+func TestDecodeOptionalCounters(t *testing.T) {
+	base := strings.Join(initialRows(), "/")
+	pos, err := DecodePosition(base + " b 17 42")
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if pos.SideToMove != Black || pos.HalfmoveClock != 17 || pos.FullmoveNumber != 42 {
+		t.Fatalf("got side=%d halfmove=%d fullmove=%d", pos.SideToMove, pos.HalfmoveClock, pos.FullmoveNumber)
+	}
+	if !strings.HasSuffix(pos.Encode(), " b 17 42") {
+		t.Fatalf("encode lost counters: %q", pos.Encode())
+	}
+	// "=" 和 "94" 都表示 13 个空位
+	compact := strings.Replace(base, ".............", "=", 1)
+	compact = strings.Replace(compact, ".............", "94", 1)
+	if got, err := DecodePosition(compact + " w"); err != nil || got.Board != NewInitialPosition().Board {
+		t.Fatalf("compact empty runs: %v", err)
+	}
+}
+
+// 吃王结束的对局：轮到走的一方没有王，局面仍能解析，判对方吃王获胜
+func TestDecodeFinishedPosition(t *testing.T) {
+	pos, err := DecodePosition(editFEN("b", map[[2]int]byte{{1, 6}: '.'}))
+	if err != nil {
+		t.Fatalf("finished game rejected: %v", err)
+	}
+	if got := pos.Outcome(nil); got.Status != StatusRedWin || got.Reason != ReasonKingCaptured {
+		t.Fatalf("outcome = %+v, want red wins by king capture", got)
+	}
+	if _, err := DecodePosition(pos.Encode()); err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	base := strings.Join(initialRows(), "/")
+	tests := []struct {
+		name     string
+		fen      string
+		row, col int
+	}{
+		{"missing side", base, 0, 0},
+		{"bad side", base + " r", 0, 0},
+		{"bad halfmove", base + " w -1", 0, 0},
+		{"bad fullmove", base + " w 0 0", 0, 0},
+		{"extra field", base + " w 0 1 x", 0, 0},
+		{"too few rows", strings.Join(initialRows()[1:], "/") + " w", 0, 0},
+		{"short row", strings.Replace(base, "/.............", "/............", 1) + " w", 3, 0},
+		{"long row", strings.Replace(base, "/.............", "/..............", 1) + " w", 3, 0},
+		{"unknown piece", editFEN("w", map[[2]int]byte{{6, 4}: 'x'}), 7, 5},
+		{"no black king", editFEN("w", map[[2]int]byte{{1, 6}: '.'}), 0, 0},
+		{"no red king", editFEN("b", map[[2]int]byte{{11, 6}: '.'}), 0, 0},
+		// 数量超限时报告扫描顺序中多出来的那一格
+		{"second red king", editFEN("w", map[[2]int]byte{{10, 6}: 'E'}), 12, 7},
+		{"king outside palace", editFEN("w", map[[2]int]byte{{1, 6}: '.', {2, 4}: 'e'}), 3, 5},
+		{"advisor outside palace", editFEN("w", map[[2]int]byte{{11, 7}: '.', {10, 8}: 'D'}), 11, 9},
+		{"red elephant across wall", editFEN("w", map[[2]int]byte{{11, 4}: '.', {5, 4}: 'C'}), 6, 5},
+		{"black elephant across wall", editFEN("w", map[[2]int]byte{{1, 4}: '.', {7, 4}: 'c'}), 8, 5},
+		{"too many pawns", editFEN("w", map[[2]int]byte{{7, 5}: 'G'}), 9, 11},
+		{"kings facing", editFEN("w", map[[2]int]byte{{4, 6}: '.', {8, 6}: '.'}), 0, 0},
+	}
+	for _, tc := range tests {
+		_, err := DecodePosition(tc.fen)
+		if err == nil {
+			t.Errorf("%s: expected error", tc.name)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidFEN) {
+			t.Errorf("%s: error %v does not wrap ErrInvalidFEN", tc.name, err)
+		}
+		var fe *FENError
+		if !errors.As(err, &fe) {
+			t.Errorf("%s: error %v is not a *FENError", tc.name, err)
+			continue
+		}
+		if fe.Row != tc.row || fe.Col != tc.col {
+			t.Errorf("%s: got row %d col %d (%v), want row %d col %d", tc.name, fe.Row, fe.Col, err, tc.row, tc.col)
+		}
+	}
+}
@@ -1,22 +1,12 @@
 package xionghan
 
-var (
-	fengStations  [NumSquares]bool
-	fengRoad      [NumSquares]bool
-	fengMoveTable [NumSquares][][]int // [from][dir] -> 一条轨道路径
-)
-
-func init() {
-	initFengTables()
-}
-
-func initFengTables() {
-	const fengStep = 3
-	const startPos = 0 // 先用左上角，后面你可以根据规则改
+// buildFengTables 按锋站间距和起点站生成锋站、轨道和走法路径表
+func buildFengTables(fengStep, startPos int) *fengTables {
+	t := &fengTables{}
 
 	// 1. 锋站：对角线每次跳 fengStep
 	var q []int
-	fengStations[startPos] = true
+	t.stations[startPos] = true
 	q = append(q, startPos)
 	for qi := 0; qi < len(q); qi++ {
 		now := q[qi]
@@ -28,8 +18,8 @@ func initFengTables() {
 				continue
 			}
 			to := indexOf(r2, c2)
-			if !fengStations[to] {
-				fengStations[to] = true
+			if !t.stations[to] {
+				t.stations[to] = true
 				q = append(q, to)
 			}
 		}
@@ -37,10 +27,10 @@ func initFengTables() {
 
 	// 2. 轨道：以每个锋站为中心，向四个对角方向延伸 fengStep-1 步
 	for sq := 0; sq < NumSquares; sq++ {
-		if !fengStations[sq] {
+		if !t.stations[sq] {
 			continue
 		}
-		fengRoad[sq] = true
+		t.road[sq] = true
 		r, c := rowOf(sq), colOf(sq)
 		for _, d := range bishopDirs {
 			for step := 1; step < fengStep; step++ {
@@ -49,14 +39,14 @@ func initFengTables() {
 				if !onBoard(r2, c2) {
 					break
 				}
-				fengRoad[indexOf(r2, c2)] = true
+				t.road[indexOf(r2, c2)] = true
 			}
 		}
 	}
 
 	// 3. 每个格子的轨道路径预计算
 	for sq := 0; sq < NumSquares; sq++ {
-		if !fengRoad[sq] {
+		if !t.road[sq] {
 			continue
 		}
 		r, c := rowOf(sq), colOf(sq)
@@ -66,11 +56,11 @@ func initFengTables() {
 			r2, c2 := r+d[0], c+d[1]
 			for onBoard(r2, c2) {
 				to := indexOf(r2, c2)
-				if !fengRoad[to] {
+				if !t.road[to] {
 					break
 				}
 				line = append(line, to)
-				if fengStations[to] {
+				if t.stations[to] {
 					break
 				}
 				r2 += d[0]
@@ -80,18 +70,29 @@ func initFengTables() {
 				dirs = append(dirs, line)
 			}
 		}
-		fengMoveTable[sq] = dirs
+		t.moves[sq] = dirs
+	}
+
+	// 4. 反查表：哪些格子上的锋能沿轨道走到 sq
+	for from := 0; from < NumSquares; from++ {
+		for _, line := range t.moves[from] {
+			for i, to := range line {
+				t.rails[to] = append(t.rails[to], fengRail{From: from, Between: line[:i]})
+			}
+		}
 	}
+	return t
 }
 
 // 锋：只能走轨道；走子不越子、不越锋站；吃子只有“从锋站出发”时可以吃到路径上的第一个敌子
 func genFengMoves(p *Position, from int, moves *[]Move) {
-	if !fengRoad[from] {
+	t := p.ActiveRules().fengTables()
+	if !t.road[from] {
 		return
 	}
 	side := p.Board.Squares[from].Side()
-	canAttack := fengStations[from]
-	for _, line := range fengMoveTable[from] {
+	canAttack := t.stations[from]
+	for _, line := range t.moves[from] {
 		for _, to := range line {
 			dst := p.Board.Squares[to]
 			if dst == 0 {
@@ -102,7 +103,7 @@ func genFengMoves(p *Position, from int, moves *[]Move) {
 				}
 				break
 			}
-			if fengStations[to] {
+			if t.stations[to] {
 				break
 			}
 		}
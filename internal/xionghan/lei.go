@@ -7,6 +7,10 @@ type leiTarget struct {
 
 var leiAdj [NumSquares][]leiTarget
 
+// leiAttackers[sq] 能吃 sq 上落单棋子的檑的位置（leiAdj 的反查表）；
+// Leg 是该檑环上与 sq 相邻、必须为空的格子
+var leiAttackers [NumSquares][]leiTarget
+
 func init() {
 	initLeiAdj()
 }
@@ -36,28 +40,31 @@ func initLeiAdj() {
 		}
 		leiAdj[sq] = arr
 	}
+	for sq := 0; sq < NumSquares; sq++ {
+		for _, t := range leiAdj[sq] {
+			leiAttackers[t.Pos] = append(leiAttackers[t.Pos], leiTarget{Pos: sq, Leg: t.Leg})
+		}
+	}
 }
 
 // 檑：走=八方向像皇后（只走空格，不吃）；吃=只吃周围8格“落单棋子”
-func genLeiMoves(p *Position, from int, moves *[]Move) {
-	row, col := rowOf(from), colOf(from)
-	side := p.Board.Squares[from].Side()
-
+func genLeiMoves(p *Position, from int, occ Bitboard, moves *[]Move) {
 	// 1. 走子：8 方向任意步，只能落空格
-	for _, d := range append(rookDirs, bishopDirs...) {
-		r, c := row+d[0], col+d[1]
-		for onBoard(r, c) {
-			to := indexOf(r, c)
-			if p.Board.Squares[to] != 0 {
-				break // 不可吃子、不可越子
-			}
-			*moves = append(*moves, Move{From: from, To: to})
-			r += d[0]
-			c += d[1]
+	for dir := 0; dir < 8; dir++ {
+		att, _ := rayAttacks(from, dir, occ)
+		quiet := att.AndNot(occ) // 不可吃子、不可越子
+		for !quiet.IsZero() {
+			*moves = append(*moves, Move{From: from, To: popNearest(&quiet, dir)})
 		}
 	}
 
-	// 2. 吃子：周围环上“落单棋子”
+	// 2. 吃子
+	genLeiCaptures(p, from, moves)
+}
+
+// genLeiCaptures 檑吃子：周围环上“落单棋子”
+func genLeiCaptures(p *Position, from int, moves *[]Move) {
+	side := p.Board.Squares[from].Side()
 	for _, t := range leiAdj[from] {
 		pc := p.Board.Squares[t.Pos]
 		if pc == 0 || pc.Side() == side {
@@ -0,0 +1,149 @@
+package xionghan
+
+// undoRecord 记录一步走子前的状态，UnmakeMove 据此还原局面
+type undoRecord struct {
+	move     Move
+	moved    Piece
+	captured Piece
+	hash     uint64
+	halfmove int
+	fullmove int
+	null     bool // 空着：只交换了走子方
+}
+
+// MakeMove 就地走子，并把撤销信息压入撤销栈。
+// 与 ApplyMove 不同，这里不复制棋盘；必须与 UnmakeMove 成对调用。
+func (p *Position) MakeMove(m Move) bool {
+	if !p.canMove(m) {
+		return false
+	}
+	p.undo = append(p.undo, p.doMove(m))
+	return true
+}
+
+// UnmakeMove 撤销最近一次 MakeMove，还原被吃的子和 Zobrist 哈希。
+func (p *Position) UnmakeMove() bool {
+	n := len(p.undo)
+	if n == 0 {
+		return false
+	}
+	u := p.undo[n-1]
+	p.undo = p.undo[:n-1]
+	if u.null {
+		p.SideToMove = opposite(p.SideToMove)
+		p.Hash = u.hash
+		p.HalfmoveClock = u.halfmove
+		p.FullmoveNumber = u.fullmove
+		return true
+	}
+	p.undoMove(u)
+	return true
+}
+
+// MakeNullMove 就地走一步空着（只交换走子方，用于空着剪枝），用 UnmakeMove 撤销。
+func (p *Position) MakeNullMove() {
+	u := undoRecord{
+		hash:     p.EnsureHash(),
+		halfmove: p.HalfmoveClock,
+		fullmove: p.FullmoveNumber,
+		null:     true,
+	}
+	if p.SideToMove == Black {
+		p.FullmoveNumber++
+	}
+	p.SideToMove = opposite(p.SideToMove)
+	p.HalfmoveClock++
+	p.Hash = u.hash ^ zobristSide
+	p.undo = append(p.undo, u)
+}
+
+// Ply 返回撤销栈的深度（即当前已就地走了几步）
+func (p *Position) Ply() int {
+	return len(p.undo)
+}
+
+// Clone 复制局面，副本有独立的撤销栈，可以交给别的 goroutine 就地走子。
+func (p *Position) Clone() *Position {
+	np := *p
+	np.undo = nil
+	return &np
+}
+
+func (p *Position) canMove(m Move) bool {
+	if m.From < 0 || m.From >= NumSquares || m.To < 0 || m.To >= NumSquares {
+		return false
+	}
+	pc := p.Board.Squares[m.From]
+	return pc != 0 && pc.Side() == p.SideToMove
+}
+
+// doMove 不做合法性检查、不入栈，返回还原所需的信息
+func (p *Position) doMove(m Move) undoRecord {
+	pc := p.Board.Squares[m.From]
+	captured := p.Board.Squares[m.To]
+	u := undoRecord{
+		move:     m,
+		moved:    pc,
+		captured: captured,
+		hash:     p.EnsureHash(),
+		halfmove: p.HalfmoveClock,
+		fullmove: p.FullmoveNumber,
+	}
+
+	if !p.indexed {
+		p.syncIndex()
+	}
+	p.Board.Squares[m.To] = pc
+	p.Board.Squares[m.From] = 0
+	if captured != 0 {
+		p.bb.remove(captured, m.To)
+	}
+	p.bb.remove(pc, m.From)
+	p.bb.add(pc, m.To)
+	if pc.Type() == PieceKing {
+		p.kingSq[pc.Side()] = m.To
+	}
+	if captured.Type() == PieceKing {
+		p.kingSq[captured.Side()] = -1
+	}
+	if p.SideToMove == Black {
+		p.FullmoveNumber++
+	}
+	p.SideToMove = opposite(p.SideToMove)
+	if captured != 0 {
+		p.HalfmoveClock = 0
+	} else {
+		p.HalfmoveClock++
+	}
+
+	// 增量 Zobrist：移除 from 的子、移除被吃子（若有）、加入 to 的子、切换走子方。
+	h := u.hash
+	h ^= pieceHashKey(pc, m.From)
+	if captured != 0 {
+		h ^= pieceHashKey(captured, m.To)
+	}
+	h ^= pieceHashKey(pc, m.To)
+	h ^= zobristSide
+	p.Hash = h
+	return u
+}
+
+func (p *Position) undoMove(u undoRecord) {
+	p.Board.Squares[u.move.From] = u.moved
+	p.Board.Squares[u.move.To] = u.captured
+	p.bb.remove(u.moved, u.move.To)
+	p.bb.add(u.moved, u.move.From)
+	if u.captured != 0 {
+		p.bb.add(u.captured, u.move.To)
+	}
+	if u.moved.Type() == PieceKing {
+		p.kingSq[u.moved.Side()] = u.move.From
+	}
+	if u.captured.Type() == PieceKing {
+		p.kingSq[u.captured.Side()] = u.move.To
+	}
+	p.SideToMove = opposite(p.SideToMove)
+	p.Hash = u.hash
+	p.HalfmoveClock = u.halfmove
+	p.FullmoveNumber = u.fullmove
+}
@@ -0,0 +1,87 @@
+package xionghan
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestMakeUnmakeRestoresPosition(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	pos := NewInitialPosition()
+	orig := *pos
+
+	var played []Move
+	for ply := 0; ply < 80; ply++ {
+		moves := pos.GenerateLegalMoves()
+		if len(moves) == 0 || !pos.KingExists(Red) || !pos.KingExists(Black) {
+			break
+		}
+		mv := moves[rng.Intn(len(moves))]
+
+		want, ok := pos.ApplyMove(mv)
+		if !ok {
+			t.Fatalf("apply move failed at ply %d: %+v", ply, mv)
+		}
+		if !pos.MakeMove(mv) {
+			t.Fatalf("make move failed at ply %d: %+v", ply, mv)
+		}
+		if pos.Board != want.Board || pos.SideToMove != want.SideToMove || pos.Hash != want.Hash {
+			t.Fatalf("make move differs from apply move at ply %d: %+v", ply, mv)
+		}
+		if pos.Hash != pos.CalculateHash() {
+			t.Fatalf("hash mismatch at ply %d: got=%d want=%d", ply, pos.Hash, pos.CalculateHash())
+		}
+		played = append(played, mv)
+	}
+	if pos.Ply() != len(played) {
+		t.Fatalf("undo stack depth: got=%d want=%d", pos.Ply(), len(played))
+	}
+
+	for range played {
+		if !pos.UnmakeMove() {
+			t.Fatalf("unmake failed")
+		}
+	}
+	if pos.UnmakeMove() {
+		t.Fatalf("unmake on empty stack should fail")
+	}
+	if pos.Board != orig.Board || pos.SideToMove != orig.SideToMove || pos.Hash != orig.Hash {
+		t.Fatalf("position not restored after unmaking %d moves", len(played))
+	}
+}
+
+func TestGenerateLegalMovesDoesNotMutate(t *testing.T) {
+	pos := NewInitialPosition()
+	before := *pos
+	_ = pos.GenerateLegalMoves()
+	if pos.Board != before.Board || pos.Hash != before.Hash || pos.Ply() != 0 {
+		t.Fatalf("GenerateLegalMoves changed the receiver")
+	}
+}
+
+func TestNullMove(t *testing.T) {
+	pos := NewInitialPosition()
+	mv := pos.GenerateLegalMoves()[0]
+	pos.MakeMove(mv)
+	before := *pos
+
+	pos.MakeNullMove()
+	if pos.SideToMove != Red || pos.Board != before.Board {
+		t.Fatalf("null move should only switch the side to move")
+	}
+	if pos.Hash != pos.CalculateHash() {
+		t.Fatalf("hash mismatch after null move: got=%d want=%d", pos.Hash, pos.CalculateHash())
+	}
+	if pos.Ply() != 2 {
+		t.Fatalf("null move should be on the undo stack, ply=%d", pos.Ply())
+	}
+	// 空着之后照常走子和撤销
+	reply := pos.GenerateLegalMoves()[0]
+	pos.MakeMove(reply)
+	pos.UnmakeMove()
+	pos.UnmakeMove()
+	if pos.Board != before.Board || pos.SideToMove != before.SideToMove || pos.Hash != before.Hash ||
+		pos.HalfmoveClock != before.HalfmoveClock || pos.FullmoveNumber != before.FullmoveNumber {
+		t.Fatalf("position not restored after unmaking the null move")
+	}
+}
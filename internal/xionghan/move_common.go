@@ -1,64 +1,38 @@
 package xionghan
 
-// 车：横竖随便走
-func genRookMoves(p *Position, from int, moves *[]Move) {
-	row, col := rowOf(from), colOf(from)
-	pc_from := p.Board.Squares[from]
-	side := pc_from.Side()
-	for _, d := range rookDirs {
-		r, c := row+d[0], col+d[1]
-		for onBoard(r, c) {
-			to := indexOf(r, c)
-			pc := p.Board.Squares[to]
-			if pc == 0 {
-				*moves = append(*moves, Move{From: from, To: to})
-			} else {
-				if pc.Side() != side {
-					*moves = append(*moves, Move{From: from, To: to})
-				}
-				break
-			}
-			r += d[0]
-			c += d[1]
+// 车：横竖随便走。occ 为双方全部棋子，按方向由近到远生成
+func genRookMoves(p *Position, from int, occ Bitboard, moves *[]Move) {
+	side := p.Board.Squares[from].Side()
+	for dir := 0; dir < 4; dir++ {
+		att, blk := rayAttacks(from, dir, occ)
+		if blk >= 0 && p.Board.Squares[blk].Side() == side {
+			att.Clear(blk)
+		}
+		for att := att; !att.IsZero(); {
+			*moves = append(*moves, Move{From: from, To: popNearest(&att, dir)})
 		}
 	}
 }
 
 // 炮：车走法 + 隔一子吃
-func genCannonMoves(p *Position, from int, moves *[]Move) {
-	row, col := rowOf(from), colOf(from)
-	pc_from := p.Board.Squares[from]
-	side := pc_from.Side()
-	for _, d := range rookDirs {
-		r, c := row+d[0], col+d[1]
+func genCannonMoves(p *Position, from int, occ Bitboard, moves *[]Move) {
+	side := p.Board.Squares[from].Side()
+	for dir := 0; dir < 4; dir++ {
+		att, screen := rayAttacks(from, dir, occ)
 
 		// 走子阶段
-		for onBoard(r, c) {
-			to := indexOf(r, c)
-			pc := p.Board.Squares[to]
-			if pc == 0 {
-				*moves = append(*moves, Move{From: from, To: to})
-				r += d[0]
-				c += d[1]
-				continue
-			}
-			r += d[0]
-			c += d[1]
-			break
+		quiet := att.AndNot(occ)
+		for !quiet.IsZero() {
+			*moves = append(*moves, Move{From: from, To: popNearest(&quiet, dir)})
 		}
 
-		// 吃子阶段
-		for onBoard(r, c) {
-			to := indexOf(r, c)
-			pc := p.Board.Squares[to]
-			if pc != 0 {
-				if pc.Side() != side {
-					*moves = append(*moves, Move{From: from, To: to})
-				}
-				break
-			}
-			r += d[0]
-			c += d[1]
+		// 吃子阶段：炮架后面的第一个子
+		if screen < 0 {
+			continue
+		}
+		to := nearest(rayMasks[screen][dir].And(occ), dir)
+		if to >= 0 && p.Board.Squares[to].Side() != side {
+			*moves = append(*moves, Move{From: from, To: to})
 		}
 	}
 }
@@ -68,6 +42,7 @@ func genElephantMoves(p *Position, from int, moves *[]Move) {
 	row, col := rowOf(from), colOf(from)
 	pc := p.Board.Squares[from]
 	side := pc.Side()
+	rules := p.ActiveRules()
 	for _, d := range bishopDirs {
 		r := row + 2*d[0]
 		c := col + 2*d[1]
@@ -75,8 +50,7 @@ func genElephantMoves(p *Position, from int, moves *[]Move) {
 		mc := col + d[1]
 		if !onBoard(r, c) { continue }
 		if p.Board.Squares[indexOf(mr, mc)] != 0 { continue }
-		if side == Red && r < WallRow { continue }
-		if side == Black && r > WallRow { continue }
+		if rules.elephantCrossed(side, r) { continue }
 		dst := p.Board.Squares[indexOf(r, c)]
 		if dst == 0 || dst.Side() != side {
 			*moves = append(*moves, Move{From: from, To: indexOf(r, c)})
@@ -89,10 +63,11 @@ func genAdvisorMoves(p *Position, from int, moves *[]Move) {
 	row, col := rowOf(from), colOf(from)
 	pc := p.Board.Squares[from]
 	side := pc.Side()
+	rules := p.ActiveRules()
 	for _, d := range bishopDirs {
 		r := row + d[0]
 		c := col + d[1]
-		if !onBoard(r, c) || !inPalace(side, r, c) { continue }
+		if !onBoard(r, c) || !rules.inPalace(side, r, c) { continue }
 		dst := p.Board.Squares[indexOf(r, c)]
 		if dst == 0 || dst.Side() != side {
 			*moves = append(*moves, Move{From: from, To: indexOf(r, c)})
@@ -105,10 +80,11 @@ func genKingMoves(p *Position, from int, moves *[]Move) {
 	row, col := rowOf(from), colOf(from)
 	pc := p.Board.Squares[from]
 	side := pc.Side()
+	rules := p.ActiveRules()
 	for _, d := range rookDirs {
 		r := row + d[0]
 		c := col + d[1]
-		if !onBoard(r, c) || !inPalace(side, r, c) { continue }
+		if !onBoard(r, c) || !rules.inPalace(side, r, c) { continue }
 		dst := p.Board.Squares[indexOf(r, c)]
 		if dst == 0 || dst.Side() != side {
 			*moves = append(*moves, Move{From: from, To: indexOf(r, c)})
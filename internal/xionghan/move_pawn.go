@@ -10,7 +10,8 @@ func genPawnMoves(p *Position, from int, moves *[]Move) {
 	dir := pawnDir(side)
 
 	// 先判断当前格子是不是已经“过长城”
-	passed := pawnPassedWall(side, row)
+	rules := p.ActiveRules()
+	passed := rules.pawnPassedWall(side, row)
 
 	if passed {
 		// ===== 已过长城：左右 + 前一格 =====
@@ -47,7 +48,7 @@ func genPawnMoves(p *Position, from int, moves *[]Move) {
 		ray = append(ray, to)
 
 		// 一旦到达敌境（pawnPassedWall==true），就把这一格作为最后一个候选，然后停
-		if pawnPassedWall(side, r) {
+		if rules.pawnPassedWall(side, r) {
 			break
 		}
 	}
@@ -0,0 +1,274 @@
+package xionghan
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrBadNotation   = errors.New("bad move notation")
+	ErrIllegalMove   = errors.New("illegal move")
+	ErrAmbiguousMove = errors.New("ambiguous move")
+)
+
+// Notation 着法记法：把 Move 与文字互相转换
+type Notation interface {
+	// FormatMove 把当前局面 pos 下的一步着法写成文字
+	FormatMove(pos *Position, m Move) string
+	// ParseMove 在当前局面 pos 下解析文字，返回一步合法着法
+	ParseMove(pos *Position, s string) (Move, error)
+}
+
+// ======================== 坐标记法 ========================
+
+// CoordinateNotation 坐标记法，如 "a12-a9"。
+// 列 a-m 从左到右，行号 = 13 - row（红方底线为 1，黑方底线为 13）。
+type CoordinateNotation struct{}
+
+// SquareName 返回格子的坐标名，如 0 -> "a13"
+func SquareName(sq int) string {
+	return string(rune('a'+colOf(sq))) + strconv.Itoa(Rows-rowOf(sq))
+}
+
+// ParseSquare 解析坐标名，如 "a13" -> 0
+func ParseSquare(s string) (int, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	col := int(s[0]) - 'a'
+	rank, err := strconv.Atoi(s[1:])
+	if err != nil || col < 0 || col >= Cols || rank < 1 || rank > Rows {
+		return 0, false
+	}
+	return indexOf(Rows-rank, col), true
+}
+
+// FormatMove 坐标记法不依赖局面，pos 可以为 nil
+func (CoordinateNotation) FormatMove(_ *Position, m Move) string {
+	return SquareName(m.From) + "-" + SquareName(m.To)
+}
+
+// ParseMove 接受 "a12-a9" 或 "a12xa9"；pos 为 nil 时只检查格式
+func (CoordinateNotation) ParseMove(pos *Position, s string) (Move, error) {
+	s = strings.TrimSpace(s)
+	sep := strings.IndexAny(s, "-x")
+	if sep < 0 {
+		return Move{}, fmt.Errorf("%w: %q", ErrBadNotation, s)
+	}
+	from, ok1 := ParseSquare(s[:sep])
+	to, ok2 := ParseSquare(s[sep+1:])
+	if !ok1 || !ok2 {
+		return Move{}, fmt.Errorf("%w: %q", ErrBadNotation, s)
+	}
+	m := Move{From: from, To: to}
+	if pos != nil && !pos.IsLegalMove(m) {
+		return Move{}, fmt.Errorf("%w: %s", ErrIllegalMove, s)
+	}
+	return m, nil
+}
+
+// ======================== 中文记法 ========================
+
+// ChineseNotation 传统中文记法，如 "炮二平五"、"前卒进1"。
+//
+//   - 棋子：车 马 炮 相 士 皇 卒 檑 锋 尉
+//   - 纵线：各自从己方右手边数起；红方用中文数字（一…十三），黑方用阿拉伯数字
+//   - 动作：进 / 退 / 平。车炮皇卒尉直线进退写步数；马相士锋写到达的纵线；
+//     檑直走写步数，斜走用 斜进 / 斜退 加到达的纵线
+//   - 同一纵线上有多个同种棋子时，用 前/后（两个）、前/中/后（三个）
+//     或 一二三四五（更多，从前往后）代替纵线；若另有纵线也叠子，再补写纵线
+type ChineseNotation struct{}
+
+var chinesePieceNames = [...]string{
+	PieceRook:     "车",
+	PieceKnight:   "马",
+	PieceCannon:   "炮",
+	PieceElephant: "相",
+	PieceAdvisor:  "士",
+	PieceKing:     "皇",
+	PiecePawn:     "卒",
+	PieceLei:      "檑",
+	PieceFeng:     "锋",
+	PieceWei:      "尉",
+}
+
+// 解析时接受的异体字
+var chinesePieceAliases = strings.NewReplacer(
+	"車", "车", "俥", "车", "馬", "马", "傌", "马", "砲", "炮",
+	"象", "相", "都", "相", "仕", "士", "氏", "士", "单", "皇", "帅", "皇", "将", "皇",
+	"兵", "卒", "卫", "尉", "衛", "尉", "鋒", "锋",
+)
+
+var chineseDigits = [...]string{"", "一", "二", "三", "四", "五", "六", "七", "八", "九", "十", "十一", "十二", "十三"}
+
+// fileOf 纵线编号（1..13），从该方右手边数起
+func fileOf(side Side, col int) int {
+	if side == Red {
+		return Cols - col
+	}
+	return col + 1
+}
+
+// isAdvance 对 side 来说 from -> to 是否向前
+func isAdvance(side Side, fromRow, toRow int) bool {
+	if side == Red {
+		return toRow < fromRow
+	}
+	return toRow > fromRow
+}
+
+func chineseNumber(n int, chinese bool) string {
+	if chinese && n > 0 && n < len(chineseDigits) {
+		return chineseDigits[n]
+	}
+	return strconv.Itoa(n)
+}
+
+// FormatMove 红方用中文数字，黑方用阿拉伯数字
+func (ChineseNotation) FormatMove(pos *Position, m Move) string {
+	pc := pos.Board.Squares[m.From]
+	return formatChinese(pos, m, pc.Side() == Red, true)
+}
+
+// formatChinese 生成中文记法；withRank=false 时省略前/后，用于宽松匹配
+func formatChinese(pos *Position, m Move, chineseNums, withRank bool) string {
+	pc := pos.Board.Squares[m.From]
+	if pc == 0 {
+		return ""
+	}
+	side, pt := pc.Side(), pc.Type()
+	fromRow, fromCol := rowOf(m.From), colOf(m.From)
+	toRow, toCol := rowOf(m.To), colOf(m.To)
+	num := func(n int) string { return chineseNumber(n, chineseNums) }
+
+	var sb strings.Builder
+	peers := sameFilePeers(pos, pc, fromCol)
+	if withRank && len(peers) > 1 {
+		idx := 0
+		for i, sq := range peers {
+			if sq == m.From {
+				idx = i
+			}
+		}
+		sb.WriteString(rankLabel(idx, len(peers)))
+		sb.WriteString(chinesePieceNames[pt])
+		if stackedOnOtherFile(pos, pc, fromCol) {
+			sb.WriteString(num(fileOf(side, fromCol)))
+		}
+	} else {
+		sb.WriteString(chinesePieceNames[pt])
+		sb.WriteString(num(fileOf(side, fromCol)))
+	}
+
+	if fromRow == toRow {
+		sb.WriteString("平")
+		sb.WriteString(num(fileOf(side, toCol)))
+		return sb.String()
+	}
+	oblique := isObliquePiece(pt)
+	if pt == PieceLei && fromCol != toCol {
+		sb.WriteString("斜")
+	}
+	if isAdvance(side, fromRow, toRow) {
+		sb.WriteString("进")
+	} else {
+		sb.WriteString("退")
+	}
+	if fromCol == toCol && !oblique {
+		dr := toRow - fromRow
+		if dr < 0 {
+			dr = -dr
+		}
+		sb.WriteString(num(dr))
+	} else {
+		sb.WriteString(num(fileOf(side, toCol)))
+	}
+	return sb.String()
+}
+
+// isObliquePiece 斜走 / 跳走的棋子，进退一律写到达的纵线。
+// 马的直三也按纵线写，和日字走法不会重名。
+func isObliquePiece(pt PieceType) bool {
+	switch pt {
+	case PieceKnight, PieceElephant, PieceAdvisor, PieceFeng:
+		return true
+	}
+	return false
+}
+
+// sameFilePeers 同一纵线上与 pc 相同的棋子，按从前到后排序
+func sameFilePeers(pos *Position, pc Piece, col int) []int {
+	var out []int
+	for r := 0; r < Rows; r++ {
+		if sq := indexOf(r, col); pos.Board.Squares[sq] == pc {
+			out = append(out, sq)
+		}
+	}
+	if pc.Side() == Black {
+		sort.Sort(sort.Reverse(sort.IntSlice(out)))
+	}
+	return out
+}
+
+func stackedOnOtherFile(pos *Position, pc Piece, col int) bool {
+	for c := 0; c < Cols; c++ {
+		if c != col && len(sameFilePeers(pos, pc, c)) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func rankLabel(idx, n int) string {
+	switch {
+	case n == 2:
+		return [...]string{"前", "后"}[idx]
+	case n == 3:
+		return [...]string{"前", "中", "后"}[idx]
+	default:
+		return chineseDigits[idx+1]
+	}
+}
+
+// ParseMove 红黑两种数字写法都接受；省略前/后且有歧义时返回 ErrAmbiguousMove
+func (ChineseNotation) ParseMove(pos *Position, s string) (Move, error) {
+	want := chinesePieceAliases.Replace(strings.Join(strings.Fields(s), ""))
+	if want == "" {
+		return Move{}, fmt.Errorf("%w: %q", ErrBadNotation, s)
+	}
+	legal := pos.GenerateLegalMoves()
+	for _, m := range legal {
+		if formatChinese(pos, m, true, true) == want || formatChinese(pos, m, false, true) == want {
+			return m, nil
+		}
+	}
+
+	// 宽松匹配：允许省略前/后，但要唯一
+	var found []Move
+	for _, m := range legal {
+		if formatChinese(pos, m, true, false) == want || formatChinese(pos, m, false, false) == want {
+			found = append(found, m)
+		}
+	}
+	switch len(found) {
+	case 1:
+		return found[0], nil
+	case 0:
+		return Move{}, fmt.Errorf("%w: %s", ErrIllegalMove, s)
+	default:
+		return Move{}, fmt.Errorf("%w: %s", ErrAmbiguousMove, s)
+	}
+}
+
+// IsLegalMove 判断 m 是否是当前局面的合法着法（只比较起止格）
+func (p *Position) IsLegalMove(m Move) bool {
+	for _, lm := range p.GenerateLegalMoves() {
+		if lm.From == m.From && lm.To == m.To {
+			return true
+		}
+	}
+	return false
+}
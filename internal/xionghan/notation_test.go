@@ -0,0 +1,103 @@
+package xionghan
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestNotationRoundTrip(t *testing.T) {
+	notations := []Notation{CoordinateNotation{}, ChineseNotation{}}
+	rng := rand.New(rand.NewSource(11))
+	for game := 0; game < 3; game++ {
+		pos := NewInitialPosition()
+		for ply := 0; ply < 80; ply++ {
+			moves := pos.GenerateLegalMoves()
+			if len(moves) == 0 || pos.Outcome(nil).IsOver() {
+				break
+			}
+			for _, n := range notations {
+				seen := make(map[string]Move, len(moves))
+				for _, m := range moves {
+					s := n.FormatMove(pos, m)
+					if prev, dup := seen[s]; dup {
+						t.Fatalf("%T: %q used for %+v and %+v", n, s, prev, m)
+					}
+					seen[s] = m
+					got, err := n.ParseMove(pos, s)
+					if err != nil || got != m {
+						t.Fatalf("%T: parse %q = %+v, %v; want %+v", n, s, got, err, m)
+					}
+				}
+			}
+			pos, _ = pos.ApplyMove(moves[rng.Intn(len(moves))])
+		}
+	}
+}
+
+func TestChineseNotation(t *testing.T) {
+	pos := NewInitialPosition()
+	n := ChineseNotation{}
+	cases := []struct {
+		move Move
+		want string
+	}{
+		// 红炮 (9,1) 平到中路 (9,6)
+		{Move{From: indexOf(9, 1), To: indexOf(9, 6)}, "炮十二平七"},
+		// 红卒 (8,2) 进一
+		{Move{From: indexOf(8, 2), To: indexOf(7, 2)}, "卒十一进一"},
+		// 红马 (11,3) 跳到 (9,2)
+		{Move{From: indexOf(11, 3), To: indexOf(9, 2)}, "马十进十一"},
+	}
+	for _, tc := range cases {
+		if got := n.FormatMove(pos, tc.move); got != tc.want {
+			t.Errorf("format %+v: got %q want %q", tc.move, got, tc.want)
+		}
+	}
+
+	black := NewInitialPosition()
+	black.SideToMove = Black
+	black.Hash = black.CalculateHash()
+	// 黑卒 (4,2) 进 1，黑方纵线从 col 0 数起
+	if got := n.FormatMove(black, Move{From: indexOf(4, 2), To: indexOf(5, 2)}); got != "卒3进1" {
+		t.Errorf("black pawn: got %q", got)
+	}
+	if m, err := n.ParseMove(black, "兵三进一"); err != nil || m != (Move{From: indexOf(4, 2), To: indexOf(5, 2)}) {
+		t.Errorf("parse with alias and chinese digits: %+v, %v", m, err)
+	}
+}
+
+func TestChineseNotationSameFile(t *testing.T) {
+	pos := newTestPosition(Red, map[int]Piece{
+		indexOf(10, 6): makePiece(Red, PieceKing),
+		indexOf(1, 5):  makePiece(Black, PieceKing),
+		indexOf(8, 0):  makePiece(Red, PieceRook),
+		indexOf(11, 0): makePiece(Red, PieceRook),
+	})
+	n := ChineseNotation{}
+	front := Move{From: indexOf(8, 0), To: indexOf(7, 0)}
+	back := Move{From: indexOf(11, 0), To: indexOf(10, 0)}
+	if got := n.FormatMove(pos, front); got != "前车进一" {
+		t.Errorf("front rook: got %q", got)
+	}
+	if got := n.FormatMove(pos, back); got != "后车进一" {
+		t.Errorf("back rook: got %q", got)
+	}
+	if _, err := n.ParseMove(pos, "车十三进一"); !errors.Is(err, ErrAmbiguousMove) {
+		t.Errorf("expected ambiguity error, got %v", err)
+	}
+	if m, err := n.ParseMove(pos, "车十三进五"); err != nil || m != (Move{From: indexOf(8, 0), To: indexOf(3, 0)}) {
+		t.Errorf("unique move without rank: %+v, %v", m, err)
+	}
+	if _, err := n.ParseMove(pos, "车十三进十三"); !errors.Is(err, ErrIllegalMove) {
+		t.Errorf("expected illegal move error, got %v", err)
+	}
+
+	c := CoordinateNotation{}
+	if got := c.FormatMove(nil, front); got != "a5-a6" {
+		t.Errorf("coordinate: got %q", got)
+	}
+	if _, err := c.ParseMove(pos, "a5-b6"); !errors.Is(err, ErrIllegalMove) {
+		t.Errorf("coordinate illegal: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package xionghan
+
+// DefaultRepetitionBanCount 同一将军局面出现到第几次算长将（判负 / 禁手）
+const DefaultRepetitionBanCount = 3
+
+// DefaultNoCaptureLimit 连续这么多半回合未吃子判和；网页对局、bridge 和自对弈统一用它
+const DefaultNoCaptureLimit = 120
+
+// GameStatus 对局状态
+type GameStatus int8
+
+const (
+	StatusOngoing GameStatus = iota
+	StatusRedWin
+	StatusBlackWin
+	StatusDraw
+)
+
+func (s GameStatus) String() string {
+	switch s {
+	case StatusRedWin:
+		return "red_win"
+	case StatusBlackWin:
+		return "black_win"
+	case StatusDraw:
+		return "draw"
+	default:
+		return "ongoing"
+	}
+}
+
+// ResultReason 终局原因
+type ResultReason int8
+
+const (
+	ReasonNone           ResultReason = iota
+	ReasonKingCaptured                // 王被吃
+	ReasonNoLegalMoves                // 轮到的一方无合法走法（困毙）
+	ReasonPerpetualCheck              // 长将禁手：重复将军的一方判负
+	ReasonMoveLimit                   // 连续未吃子步数达到上限，判和
+)
+
+func (r ResultReason) String() string {
+	switch r {
+	case ReasonKingCaptured:
+		return "king_captured"
+	case ReasonNoLegalMoves:
+		return "no_legal_moves"
+	case ReasonPerpetualCheck:
+		return "perpetual_check"
+	case ReasonMoveLimit:
+		return "move_limit"
+	default:
+		return ""
+	}
+}
+
+// GameResult 终局判定结果；Winner 在未结束或和棋时为 NoSide
+type GameResult struct {
+	Status GameStatus
+	Winner Side
+	Reason ResultReason
+}
+
+// IsOver 对局是否已经结束
+func (r GameResult) IsOver() bool {
+	return r.Status != StatusOngoing
+}
+
+// GameHistory 判定终局需要的对局历史；长将次数、步数上限等规则参数取自 Position.Rules
+type GameHistory struct {
+	// HashCount 各局面出现的次数（包含当前局面）；为 nil 时不判长将
+	HashCount map[uint64]int
+	// NoCaptureLimit 连续未吃子的半回合数达到该值判和，<=0 表示不限制
+	NoCaptureLimit int
+}
+
+func ongoingResult() GameResult {
+	return GameResult{Status: StatusOngoing, Winner: NoSide}
+}
+
+func winResult(winner Side, reason ResultReason) GameResult {
+	status := StatusRedWin
+	if winner == Black {
+		status = StatusBlackWin
+	}
+	return GameResult{Status: status, Winner: winner, Reason: reason}
+}
+
+// Outcome 判定当前局面（轮到 SideToMove 走）是否终局。
+// 依次检查：王被吃、长将、未吃子步数上限、总步数上限、无合法走法。history 可以为 nil。
+func (p *Position) Outcome(history *GameHistory) GameResult {
+	rules := p.ActiveRules()
+	side := p.SideToMove
+	opp := opposite(side)
+
+	// 1. 王被吃
+	if !p.KingExists(side) {
+		return winResult(opp, ReasonKingCaptured)
+	}
+	if !p.KingExists(opp) {
+		return winResult(side, ReasonKingCaptured)
+	}
+
+	// 2. 长将：上一步形成将军，且该局面已重复到禁手次数，走这步的一方判负
+	if history != nil && history.HashCount != nil && rules.RepetitionActive(p) {
+		if history.HashCount[p.EnsureHash()] >= rules.RepetitionBanCount && p.IsInCheck(side) {
+			return winResult(side, ReasonPerpetualCheck)
+		}
+	}
+
+	// 3. 未吃子步数上限
+	if history != nil && history.NoCaptureLimit > 0 && p.HalfmoveClock >= history.NoCaptureLimit {
+		return GameResult{Status: StatusDraw, Winner: NoSide, Reason: ReasonMoveLimit}
+	}
+
+	// 4. 无合法走法：轮到的一方判负
+	if len(p.GenerateLegalMoves()) == 0 {
+		return winResult(opp, ReasonNoLegalMoves)
+	}
+	return ongoingResult()
+}
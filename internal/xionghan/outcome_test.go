@@ -0,0 +1,108 @@
+package xionghan
+
+import "testing"
+
+func newTestPosition(stm Side, pieces map[int]Piece) *Position {
+	var b Board
+	for sq, pc := range pieces {
+		b.Squares[sq] = pc
+	}
+	p := &Position{Board: b, SideToMove: stm}
+	p.Hash = p.CalculateHash()
+	return p
+}
+
+func TestOutcome(t *testing.T) {
+	checkPos := newTestPosition(Black, map[int]Piece{
+		indexOf(1, 6):  makePiece(Black, PieceKing),
+		indexOf(5, 6):  makePiece(Red, PieceRook),
+		indexOf(10, 5): makePiece(Red, PieceKing),
+	})
+	limitPos := NewInitialPosition()
+	limitPos.HalfmoveClock = 120
+
+	tests := []struct {
+		name    string
+		pos     *Position
+		history *GameHistory
+		want    GameResult
+	}{
+		{
+			name: "initial",
+			pos:  NewInitialPosition(),
+			want: GameResult{Status: StatusOngoing, Winner: NoSide},
+		},
+		{
+			name: "king captured",
+			pos: newTestPosition(Black, map[int]Piece{
+				indexOf(10, 6): makePiece(Red, PieceKing),
+				indexOf(2, 2):  makePiece(Black, PieceRook),
+			}),
+			want: GameResult{Status: StatusRedWin, Winner: Red, Reason: ReasonKingCaptured},
+		},
+		{
+			// 黑王被自己的锋挡住，横走又会与红王照面
+			name: "no legal moves",
+			pos: newTestPosition(Black, map[int]Piece{
+				indexOf(3, 5): makePiece(Black, PieceKing),
+				indexOf(2, 5): makePiece(Black, PieceFeng),
+				indexOf(9, 6): makePiece(Red, PieceKing),
+			}),
+			want: GameResult{Status: StatusRedWin, Winner: Red, Reason: ReasonNoLegalMoves},
+		},
+		{
+			name:    "check repeated twice",
+			pos:     checkPos,
+			history: &GameHistory{HashCount: map[uint64]int{checkPos.Hash: 2}},
+			want:    GameResult{Status: StatusOngoing, Winner: NoSide},
+		},
+		{
+			name:    "perpetual check",
+			pos:     checkPos,
+			history: &GameHistory{HashCount: map[uint64]int{checkPos.Hash: 3}},
+			want:    GameResult{Status: StatusBlackWin, Winner: Black, Reason: ReasonPerpetualCheck},
+		},
+		{
+			name:    "no capture limit",
+			pos:     limitPos,
+			history: &GameHistory{NoCaptureLimit: 120},
+			want:    GameResult{Status: StatusDraw, Winner: NoSide, Reason: ReasonMoveLimit},
+		},
+		{
+			name:    "below no capture limit",
+			pos:     limitPos,
+			history: &GameHistory{NoCaptureLimit: 121},
+			want:    GameResult{Status: StatusOngoing, Winner: NoSide},
+		},
+	}
+	for _, tc := range tests {
+		if got := tc.pos.Outcome(tc.history); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestHalfmoveClock(t *testing.T) {
+	pos := newTestPosition(Red, map[int]Piece{
+		indexOf(10, 6): makePiece(Red, PieceKing),
+		indexOf(7, 0):  makePiece(Red, PieceRook),
+		indexOf(2, 0):  makePiece(Black, PieceRook),
+		indexOf(1, 6):  makePiece(Black, PieceKing),
+	})
+	pos.HalfmoveClock = 5
+
+	pos.MakeMove(Move{From: indexOf(7, 0), To: indexOf(7, 1)})
+	if pos.HalfmoveClock != 6 {
+		t.Fatalf("quiet move: clock=%d want 6", pos.HalfmoveClock)
+	}
+	pos.MakeMove(Move{From: indexOf(2, 0), To: indexOf(2, 1)})
+	pos.MakeMove(Move{From: indexOf(7, 1), To: indexOf(2, 1)})
+	if pos.HalfmoveClock != 0 {
+		t.Fatalf("capture: clock=%d want 0", pos.HalfmoveClock)
+	}
+	for pos.UnmakeMove() {
+	}
+	if pos.HalfmoveClock != 5 {
+		t.Fatalf("after unmake: clock=%d want 5", pos.HalfmoveClock)
+	}
+}
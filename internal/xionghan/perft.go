@@ -0,0 +1,94 @@
+package xionghan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Perft 统计从当前局面出发 depth 层内的叶子节点数，用来校验走法生成。
+// 走法取 GenerateLegalMoves()（纯规则，不含 AI 启发式）；
+// 轮到的一方已经没有王（王被吃）视为终局，不再展开。
+func (p *Position) Perft(depth int) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+	if !p.KingExists(p.SideToMove) {
+		return 0
+	}
+	moves := p.GenerateLegalMoves()
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+	var nodes uint64
+	for _, mv := range moves {
+		if !p.MakeMove(mv) {
+			continue
+		}
+		nodes += p.Perft(depth - 1)
+		p.UnmakeMove()
+	}
+	return nodes
+}
+
+// DivideEntry 是 Divide 的一行：根节点的一步棋及其子树的叶子数
+type DivideEntry struct {
+	Move  Move
+	Nodes uint64
+}
+
+// Divide 按根节点走法拆分 Perft 结果，便于和参考实现逐步对比定位差异。
+func (p *Position) Divide(depth int) []DivideEntry {
+	if depth <= 0 || !p.KingExists(p.SideToMove) {
+		return nil
+	}
+	moves := p.GenerateLegalMoves()
+	out := make([]DivideEntry, 0, len(moves))
+	for _, mv := range moves {
+		if !p.MakeMove(mv) {
+			continue
+		}
+		out = append(out, DivideEntry{Move: mv, Nodes: p.Perft(depth - 1)})
+		p.UnmakeMove()
+	}
+	return out
+}
+
+// PerftCase 是参考表中的一行：一个 FEN 及其各深度的期望节点数
+type PerftCase struct {
+	FEN   string
+	Nodes map[int]uint64
+}
+
+// ParsePerftSuite 读取参考表：每行 "FEN ;D1 n ;D2 n ..."，# 开头为注释。
+func ParsePerftSuite(r io.Reader) ([]PerftCase, error) {
+	var out []PerftCase
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		pc := PerftCase{
+			FEN:   strings.TrimSpace(fields[0]),
+			Nodes: make(map[int]uint64, len(fields)-1),
+		}
+		for _, f := range fields[1:] {
+			var depth int
+			var nodes uint64
+			if _, err := fmt.Sscanf(strings.TrimSpace(f), "D%d %d", &depth, &nodes); err != nil {
+				return nil, fmt.Errorf("perft suite line %d: bad field %q", lineNo, f)
+			}
+			pc.Nodes[depth] = nodes
+		}
+		out = append(out, pc)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
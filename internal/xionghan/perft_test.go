@@ -0,0 +1,63 @@
+package xionghan
+
+import (
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestPerftSuite(t *testing.T) {
+	f, err := os.Open("testdata/perft.txt")
+	if err != nil {
+		t.Fatalf("open suite: %v", err)
+	}
+	defer f.Close()
+	cases, err := ParsePerftSuite(f)
+	if err != nil {
+		t.Fatalf("parse suite: %v", err)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("empty perft suite")
+	}
+
+	for _, tc := range cases {
+		pos, err := DecodePosition(tc.FEN)
+		if err != nil {
+			t.Fatalf("decode %q: %v", tc.FEN, err)
+		}
+		depths := make([]int, 0, len(tc.Nodes))
+		for d := range tc.Nodes {
+			depths = append(depths, d)
+		}
+		sort.Ints(depths)
+		for _, d := range depths {
+			if testing.Short() && d > 2 {
+				continue
+			}
+			if got := pos.Perft(d); got != tc.Nodes[d] {
+				t.Errorf("perft(%d) %q: got=%d want=%d", d, tc.FEN, got, tc.Nodes[d])
+			}
+		}
+	}
+}
+
+func TestDivideSumsToPerft(t *testing.T) {
+	pos := NewInitialPosition()
+	var sum uint64
+	for _, e := range pos.Divide(2) {
+		sum += e.Nodes
+	}
+	if want := pos.Perft(2); sum != want {
+		t.Fatalf("divide sum=%d perft=%d", sum, want)
+	}
+	if pos.Ply() != 0 || pos.Hash != pos.CalculateHash() {
+		t.Fatalf("perft did not restore the position")
+	}
+}
+
+func BenchmarkPerft3(b *testing.B) {
+	pos := NewInitialPosition()
+	for i := 0; i < b.N; i++ {
+		pos.Perft(3)
+	}
+}
@@ -5,23 +5,31 @@ const (
 	BoardCols = 13
 )
 
-func (p *Position) kingsFace() bool {
-	redKing := -1
-	blackKing := -1
+// KingSquare 返回 side 的王所在格，没有王时返回 -1
+func (p *Position) KingSquare(side Side) int {
+	if side != Red && side != Black {
+		return -1
+	}
+	if p.indexed {
+		return p.kingSq[side]
+	}
+	// 不在这里写缓存：p 可能正被别的 goroutine 读取
+	return p.scanKings()[side]
+}
 
+func (p *Position) scanKings() [2]int {
+	kings := [2]int{-1, -1}
 	for sq, pc := range p.Board.Squares {
-		if pc == 0 {
-			continue
-		}
-		if pc.Type() != PieceKing {
-			continue
-		}
-		if pc.Side() == Red {
-			redKing = sq
-		} else {
-			blackKing = sq
+		if pc != 0 && pc.Type() == PieceKing && kings[pc.Side()] == -1 {
+			kings[pc.Side()] = sq
 		}
 	}
+	return kings
+}
+
+func (p *Position) kingsFace() bool {
+	redKing := p.KingSquare(Red)
+	blackKing := p.KingSquare(Black)
 
 	if redKing == -1 || blackKing == -1 {
 		// 有一方王已经没了：对局终结，但不存在“对脸”问题
@@ -50,15 +58,13 @@ func (p *Position) kingsFace() bool {
 }
 
 func (p *Position) KingExists(side Side) bool {
-	for _, pc := range p.Board.Squares {
-		if pc != 0 && pc.Type() == PieceKing && pc.Side() == side {
-			return true
-		}
-	}
-	return false
+	return p.KingSquare(side) != -1
 }
 
 func (p *Position) TotalPieces() int {
+	if p.indexed {
+		return p.bb.occ[Red].Count() + p.bb.occ[Black].Count()
+	}
 	count := 0
 	for _, pc := range p.Board.Squares {
 		if pc != 0 {
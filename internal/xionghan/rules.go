@@ -0,0 +1,186 @@
+package xionghan
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RuleSet 一套规则参数。走法生成、将军判定、FEN 校验和终局判定都从
+// Position.Rules 读取；Rules 为 nil 时使用 OnlineRules。
+type RuleSet struct {
+	Name string
+
+	WallRow int // 长城所在行：兵过此行后可横走，相不能越过
+
+	// 锋站：从 FengStart 出发沿对角线每 FengStep 格一站，轨道为站与站之间的斜线
+	FengStep  int
+	FengStart int
+
+	PalaceCols [2]int    // 九宫列范围 [lo, hi]
+	PalaceRows [2][2]int // 九宫行范围，按 Side 索引：[Red]{lo, hi}, [Black]{lo, hi}
+
+	KingsFacingIllegal bool // 两王同列且中间无子是否非法
+
+	// 长将：同一将军局面重复到 RepetitionBanCount 次时禁手 / 判负，0 表示没有长将规则；
+	// RepetitionPieceThreshold > 0 时只在盘面总子数少于该值时生效
+	RepetitionBanCount       int
+	RepetitionPieceThreshold int
+}
+
+const (
+	defaultFengStep  = 3
+	defaultFengStart = 0
+)
+
+// OnlineRules xionghan.online 的对局规则（也是默认规则）
+var OnlineRules = RuleSet{
+	Name:                     "online",
+	WallRow:                  WallRow,
+	FengStep:                 defaultFengStep,
+	FengStart:                defaultFengStart,
+	PalaceCols:               [2]int{5, 7},
+	PalaceRows:               [2][2]int{Red: {9, 11}, Black: {1, 3}},
+	KingsFacingIllegal:       true,
+	RepetitionBanCount:       DefaultRepetitionBanCount,
+	RepetitionPieceThreshold: 40,
+}
+
+// TrainingRules KataGomo 训练使用的规则：没有长将规则（步数上限由训练端控制）
+var TrainingRules = RuleSet{
+	Name:               "training",
+	WallRow:            WallRow,
+	FengStep:           defaultFengStep,
+	FengStart:          defaultFengStart,
+	PalaceCols:         [2]int{5, 7},
+	PalaceRows:         [2][2]int{Red: {9, 11}, Black: {1, 3}},
+	KingsFacingIllegal: true,
+}
+
+// RulesByName 按名字查找内置规则
+func RulesByName(name string) (*RuleSet, bool) {
+	switch name {
+	case OnlineRules.Name:
+		return &OnlineRules, true
+	case TrainingRules.Name:
+		return &TrainingRules, true
+	}
+	return nil, false
+}
+
+// Validate 检查规则参数是否落在棋盘内
+func (r *RuleSet) Validate() error {
+	if r.WallRow < 0 || r.WallRow >= Rows {
+		return fmt.Errorf("rules %q: wall row %d out of board", r.Name, r.WallRow)
+	}
+	if r.FengStep < 1 {
+		return fmt.Errorf("rules %q: feng step must be positive, got %d", r.Name, r.FengStep)
+	}
+	if r.FengStart < 0 || r.FengStart >= NumSquares {
+		return fmt.Errorf("rules %q: feng start %d out of board", r.Name, r.FengStart)
+	}
+	if r.PalaceCols[0] < 0 || r.PalaceCols[1] >= Cols || r.PalaceCols[0] > r.PalaceCols[1] {
+		return fmt.Errorf("rules %q: bad palace columns %v", r.Name, r.PalaceCols)
+	}
+	for _, side := range []Side{Red, Black} {
+		rows := r.PalaceRows[side]
+		if rows[0] < 0 || rows[1] >= Rows || rows[0] > rows[1] {
+			return fmt.Errorf("rules %q: bad %s palace rows %v", r.Name, sideName(side), rows)
+		}
+	}
+	return nil
+}
+
+// ActiveRules 返回局面使用的规则
+func (p *Position) ActiveRules() *RuleSet {
+	if p.Rules != nil {
+		return p.Rules
+	}
+	return &OnlineRules
+}
+
+// RepetitionActive 当前局面是否适用长将规则
+func (r *RuleSet) RepetitionActive(p *Position) bool {
+	if r.RepetitionBanCount <= 0 {
+		return false
+	}
+	return r.RepetitionPieceThreshold <= 0 || p.TotalPieces() < r.RepetitionPieceThreshold
+}
+
+// pawnPassedWall 是否已经“过长城”
+func (r *RuleSet) pawnPassedWall(side Side, row int) bool {
+	if side == Red {
+		return row < r.WallRow
+	}
+	if side == Black {
+		return row > r.WallRow
+	}
+	return false
+}
+
+// elephantCrossed 相是否越过了长城
+func (r *RuleSet) elephantCrossed(side Side, row int) bool {
+	if side == Red {
+		return row < r.WallRow
+	}
+	return row > r.WallRow
+}
+
+// inPalace 是否在九宫
+func (r *RuleSet) inPalace(side Side, row, col int) bool {
+	if side != Red && side != Black {
+		return false
+	}
+	if col < r.PalaceCols[0] || col > r.PalaceCols[1] {
+		return false
+	}
+	rows := r.PalaceRows[side]
+	return row >= rows[0] && row <= rows[1]
+}
+
+// CanStand side 一方的 pt 能否出现在 sq：王、士只在九宫，相不过长城，锋只在轨道上，其余不限。
+// 残局库按它枚举局面。
+func (r *RuleSet) CanStand(side Side, pt PieceType, sq int) bool {
+	row, col := rowOf(sq), colOf(sq)
+	switch pt {
+	case PieceKing, PieceAdvisor:
+		return r.inPalace(side, row, col)
+	case PieceElephant:
+		return !r.elephantCrossed(side, row)
+	case PieceFeng:
+		return r.fengTables().road[sq]
+	}
+	return true
+}
+
+// ======================== 锋站表 ========================
+
+type fengTables struct {
+	stations [NumSquares]bool
+	road     [NumSquares]bool
+	moves    [NumSquares][][]int // [from][dir] -> 一条轨道路径
+	rails    [NumSquares][]fengRail
+}
+
+// fengRail 能沿轨道一步走到某格的锋的位置，以及途中必须为空的格子
+type fengRail struct {
+	From    int
+	Between []int
+}
+
+var (
+	defaultFengTables = buildFengTables(defaultFengStep, defaultFengStart)
+	fengTableCache    sync.Map // [2]int{step, start} -> *fengTables
+)
+
+// fengTables 返回该规则的锋站表；默认参数直接用预建表，其余按参数缓存
+func (r *RuleSet) fengTables() *fengTables {
+	if r.FengStep == defaultFengStep && r.FengStart == defaultFengStart {
+		return defaultFengTables
+	}
+	key := [2]int{r.FengStep, r.FengStart}
+	if t, ok := fengTableCache.Load(key); ok {
+		return t.(*fengTables)
+	}
+	t, _ := fengTableCache.LoadOrStore(key, buildFengTables(r.FengStep, r.FengStart))
+	return t.(*fengTables)
+}
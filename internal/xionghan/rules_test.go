@@ -0,0 +1,111 @@
+package xionghan
+
+import "testing"
+
+func TestPresetRulesValid(t *testing.T) {
+	for _, name := range []string{"online", "training"} {
+		r, ok := RulesByName(name)
+		if !ok {
+			t.Fatalf("preset %q not found", name)
+		}
+		if err := r.Validate(); err != nil {
+			t.Fatalf("preset %q: %v", name, err)
+		}
+	}
+	bad := OnlineRules
+	bad.FengStep = 0
+	if bad.Validate() == nil {
+		t.Fatalf("feng step 0 should be rejected")
+	}
+	bad = OnlineRules
+	bad.PalaceCols = [2]int{11, 13}
+	if bad.Validate() == nil {
+		t.Fatalf("palace outside the board should be rejected")
+	}
+}
+
+func TestFengStationsFollowRules(t *testing.T) {
+	pos := newTestPosition(Red, map[int]Piece{
+		indexOf(10, 6): makePiece(Red, PieceKing),
+		indexOf(1, 5):  makePiece(Black, PieceKing),
+		indexOf(12, 1): makePiece(Red, PieceFeng),
+	})
+	countFeng := func() int {
+		n := 0
+		for _, m := range pos.GenerateLegalMoves() {
+			if m.From == indexOf(12, 1) {
+				n++
+			}
+		}
+		return n
+	}
+	// 默认锋站从 (0,0) 出发，(12,1) 不在轨道上
+	if n := countFeng(); n != 0 {
+		t.Fatalf("default rules: feng off the road has %d moves", n)
+	}
+	variant := OnlineRules
+	variant.Name = "feng-b13"
+	variant.FengStart = indexOf(0, 1)
+	pos.Rules = &variant
+	if n := countFeng(); n == 0 {
+		t.Fatalf("variant rules: feng on a station should move")
+	}
+}
+
+func TestKingsFacingRule(t *testing.T) {
+	pieces := map[int]Piece{
+		indexOf(10, 6): makePiece(Red, PieceKing),
+		indexOf(1, 5):  makePiece(Black, PieceKing),
+	}
+	facing := Move{From: indexOf(1, 5), To: indexOf(1, 6)}
+
+	pos := newTestPosition(Black, pieces)
+	if pos.IsLegalMove(facing) {
+		t.Fatalf("online rules: facing move should be illegal")
+	}
+	variant := OnlineRules
+	variant.KingsFacingIllegal = false
+	pos.Rules = &variant
+	if !pos.IsLegalMove(facing) {
+		t.Fatalf("variant rules: facing move should be legal")
+	}
+
+	fen := "94/6e6/94/94/94/94/94/94/94/94/6E6/94/94 w"
+	if _, err := DecodePosition(fen); err == nil {
+		t.Fatalf("online rules should reject facing kings")
+	}
+	if _, err := DecodePositionWithRules(fen, &variant); err != nil {
+		t.Fatalf("variant rules: %v", err)
+	}
+}
+
+func TestRulesRepetitionActive(t *testing.T) {
+	// 子力多于阈值时不判长将；训练规则不判长将
+	full := NewInitialPosition()
+	if OnlineRules.RepetitionActive(full) {
+		t.Fatalf("repetition rule should be off with %d pieces", full.TotalPieces())
+	}
+	sparse := newTestPosition(Red, map[int]Piece{
+		indexOf(10, 6): makePiece(Red, PieceKing),
+		indexOf(1, 5):  makePiece(Black, PieceKing),
+	})
+	if !OnlineRules.RepetitionActive(sparse) || TrainingRules.RepetitionActive(sparse) {
+		t.Fatalf("repetition activity does not follow the presets")
+	}
+}
+
+func TestInitialPiecesCanStand(t *testing.T) {
+	pos := NewInitialPosition()
+	for _, rules := range []*RuleSet{&OnlineRules, &TrainingRules} {
+		for sq, pc := range pos.Board.Squares {
+			if pc != 0 && !rules.CanStand(pc.Side(), pc.Type(), sq) {
+				t.Fatalf("%s: %c on %s should be allowed", rules.Name, pieceToChar(pc), SquareName(sq))
+			}
+		}
+	}
+	for pt := PieceRook; pt <= PieceWei; pt++ {
+		if got, ok := PieceTypeFromLetter(pt.Letter()); !ok || got != pt {
+			t.Fatalf("letter %c maps back to %d", pt.Letter(), got)
+		}
+	}
+}
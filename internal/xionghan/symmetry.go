@@ -0,0 +1,121 @@
+package xionghan
+
+// Symmetry 棋盘对称变换。两个基本变换可以组合，且都是自逆的：
+//   - SymMirrorLR   左右镜像（列 c -> 12-c）
+//   - SymFlipColors 红黑互换：上下翻转（行 r -> 12-r），棋子换色，轮走方换边
+//
+// 默认规则（长城、九宫、锋站）在这两种变换下都不变；自定义 RuleSet 不一定对称。
+type Symmetry uint8
+
+const (
+	SymIdentity   Symmetry = 0
+	SymMirrorLR   Symmetry = 1
+	SymFlipColors Symmetry = 2
+	SymMirrorFlip          = SymMirrorLR | SymFlipColors
+)
+
+// AllSymmetries 全部四种对称变换
+var AllSymmetries = [...]Symmetry{SymIdentity, SymMirrorLR, SymFlipColors, SymMirrorFlip}
+
+// Square 变换一个格子
+func (s Symmetry) Square(sq int) int {
+	row, col := rowOf(sq), colOf(sq)
+	if s&SymMirrorLR != 0 {
+		col = Cols - 1 - col
+	}
+	if s&SymFlipColors != 0 {
+		row = Rows - 1 - row
+	}
+	return indexOf(row, col)
+}
+
+// Piece 变换一个棋子（红黑互换时换色）
+func (s Symmetry) Piece(pc Piece) Piece {
+	if s&SymFlipColors != 0 {
+		return -pc
+	}
+	return pc
+}
+
+// Side 变换一方
+func (s Symmetry) Side(side Side) Side {
+	if s&SymFlipColors != 0 {
+		return opposite(side)
+	}
+	return side
+}
+
+// Move 变换一步着法（Score 保留）
+func (s Symmetry) Move(m Move) Move {
+	m.From = s.Square(m.From)
+	m.To = s.Square(m.To)
+	return m
+}
+
+// Inverse 逆变换；这几种变换都是自逆的
+func (s Symmetry) Inverse() Symmetry { return s }
+
+// MirrorLR 左右镜像着法
+func (m Move) MirrorLR() Move { return SymMirrorLR.Move(m) }
+
+// FlipColors 红黑互换后的着法
+func (m Move) FlipColors() Move { return SymFlipColors.Move(m) }
+
+// Transform 返回变换后的新局面，哈希重新计算，撤销栈为空
+func (p *Position) Transform(s Symmetry) *Position {
+	np := &Position{
+		SideToMove:     s.Side(p.SideToMove),
+		HalfmoveClock:  p.HalfmoveClock,
+		FullmoveNumber: p.FullmoveNumber,
+		Rules:          p.Rules,
+	}
+	for sq, pc := range p.Board.Squares {
+		if pc != 0 {
+			np.Board.Squares[s.Square(sq)] = s.Piece(pc)
+		}
+	}
+	np.Hash = np.CalculateHash()
+	np.syncIndex()
+	return np
+}
+
+// MirrorLR 左右镜像后的局面
+func (p *Position) MirrorLR() *Position { return p.Transform(SymMirrorLR) }
+
+// FlipColors 红黑互换后的局面（上下翻转、棋子换色、轮走方换边）
+func (p *Position) FlipColors() *Position { return p.Transform(SymFlipColors) }
+
+// SymmetricHashes 一次扫描算出四种变换后局面的 Zobrist 哈希，按 AllSymmetries 的顺序；
+// 与 p.Transform(s).CalculateHash() 相同
+func (p *Position) SymmetricHashes() [len(AllSymmetries)]uint64 {
+	initZobrist()
+	var hs [len(AllSymmetries)]uint64
+	for sq, pc := range p.Board.Squares {
+		if pc == 0 {
+			continue
+		}
+		for i, s := range AllSymmetries {
+			hs[i] ^= pieceHashKey(s.Piece(pc), s.Square(sq))
+		}
+	}
+	for i, s := range AllSymmetries {
+		if s.Side(p.SideToMove) == Black {
+			hs[i] ^= zobristSide
+		}
+	}
+	return hs
+}
+
+// CanonicalHash 四种对称局面中最小的哈希，以及得到它的变换：
+// p.Transform(sym) 就是代表局面。开局库、局面库、NN 缓存可以据此合并对称局面；
+// 注意红黑互换后胜负视角也要互换。
+func (p *Position) CanonicalHash() (uint64, Symmetry) {
+	hs := p.SymmetricHashes()
+	best, sym := hs[0], SymIdentity
+	for i, h := range hs {
+		if h < best {
+			best, sym = h, AllSymmetries[i]
+		}
+	}
+	return best, sym
+}
@@ -0,0 +1,118 @@
+package xionghan
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestInitialPositionIsSymmetric(t *testing.T) {
+	pos := NewInitialPosition()
+	if m := pos.MirrorLR(); m.Board != pos.Board || m.Hash != pos.Hash {
+		t.Fatalf("initial position should be left-right symmetric")
+	}
+	f := pos.FlipColors()
+	if f.Board != pos.Board || f.SideToMove != Black {
+		t.Fatalf("initial position with colors flipped should be the same board with black to move")
+	}
+}
+
+func TestTransformsAreInvolutions(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < 100; i++ {
+		p := randomBoard(rng, 10+rng.Intn(50))
+		for _, s := range AllSymmetries {
+			q := p.Transform(s)
+			if q.Hash != q.CalculateHash() {
+				t.Fatalf("%d: transformed hash is not the Zobrist hash of the result", s)
+			}
+			back := q.Transform(s.Inverse())
+			if back.Board != p.Board || back.SideToMove != p.SideToMove {
+				t.Fatalf("%d: transforming twice does not restore the position", s)
+			}
+			for sq := 0; sq < NumSquares; sq++ {
+				if s.Square(s.Square(sq)) != sq {
+					t.Fatalf("%d: square transform is not an involution at %d", s, sq)
+				}
+			}
+		}
+	}
+}
+
+func sortedMoves(ms []Move) []Move {
+	out := make([]Move, len(ms))
+	for i, m := range ms {
+		out[i] = Move{From: m.From, To: m.To}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].From != out[j].From {
+			return out[i].From < out[j].From
+		}
+		return out[i].To < out[j].To
+	})
+	return out
+}
+
+// 对称变换与走法生成可交换：变换后局面的合法着法 = 原着法逐一变换
+func TestLegalMovesCommuteWithSymmetry(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	for game := 0; game < 4; game++ {
+		p := NewInitialPosition()
+		for ply := 0; ply < 100; ply++ {
+			moves := p.GenerateLegalMoves()
+			if len(moves) == 0 || !p.KingExists(Red) || !p.KingExists(Black) {
+				break
+			}
+			for _, s := range AllSymmetries[1:] {
+				want := make([]Move, len(moves))
+				for i, m := range moves {
+					want[i] = s.Move(m)
+				}
+				got := sortedMoves(p.Transform(s).GenerateLegalMoves())
+				want = sortedMoves(want)
+				if len(got) != len(want) {
+					t.Fatalf("sym %d ply %d: %d moves, want %d\n%s", s, ply, len(got), len(want), p.Encode())
+				}
+				for i := range got {
+					if got[i] != want[i] {
+						t.Fatalf("sym %d ply %d: move %+v, want %+v\n%s", s, ply, got[i], want[i], p.Encode())
+					}
+				}
+				if p.IsInCheck(p.SideToMove) != p.Transform(s).IsInCheck(s.Side(p.SideToMove)) {
+					t.Fatalf("sym %d ply %d: check status differs", s, ply)
+				}
+			}
+			p.MakeMove(moves[rng.Intn(len(moves))])
+		}
+	}
+}
+
+func TestCanonicalHashMergesSymmetricPositions(t *testing.T) {
+	rng := rand.New(rand.NewSource(12))
+	for i := 0; i < 100; i++ {
+		p := randomBoard(rng, 10+rng.Intn(50))
+		want, sym := p.CanonicalHash()
+		if got := p.Transform(sym).Hash; got != want {
+			t.Fatalf("representative hash %d, canonical %d", got, want)
+		}
+		hs := p.SymmetricHashes()
+		for j, s := range AllSymmetries {
+			if hs[j] != p.Transform(s).Hash {
+				t.Fatalf("SymmetricHashes[%d] disagrees with Transform", j)
+			}
+			if h, _ := p.Transform(s).CanonicalHash(); h != want {
+				t.Fatalf("sym %d: canonical hash %d, want %d", s, h, want)
+			}
+		}
+	}
+}
+
+func TestMoveTransforms(t *testing.T) {
+	m := Move{From: indexOf(12, 2), To: indexOf(9, 2)}
+	if got := m.MirrorLR(); got.From != indexOf(12, 10) || got.To != indexOf(9, 10) {
+		t.Fatalf("MirrorLR: got %+v", got)
+	}
+	if got := m.FlipColors(); got.From != indexOf(0, 2) || got.To != indexOf(3, 2) {
+		t.Fatalf("FlipColors: got %+v", got)
+	}
+}
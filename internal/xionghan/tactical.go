@@ -0,0 +1,151 @@
+package xionghan
+
+import "sync"
+
+// ======================== 战术走法：吃子 / 将军 ========================
+
+// GenerateCaptures 只生成吃子的合法走法（含吃王）。
+// 车、炮、檑只看射线上的阻挡子和环上的落单子，不生成走子步。
+func (p *Position) GenerateCaptures() []Move {
+	side := p.SideToMove
+	if side != Red && side != Black {
+		return nil
+	}
+	var scratch boardBits
+	bb := p.bitsOr(&scratch)
+	occ := bb.all()
+	enemy := bb.occ[opposite(side)]
+
+	moves := make([]Move, 0, 32)
+	var tmp []Move
+	for own := bb.occ[side]; !own.IsZero(); {
+		sq := own.PopLSB()
+		switch p.Board.Squares[sq].Type() {
+		case PieceRook:
+			for dir := 0; dir < 4; dir++ {
+				if _, blk := rayAttacks(sq, dir, occ); blk >= 0 && enemy.Has(blk) {
+					moves = append(moves, Move{From: sq, To: blk})
+				}
+			}
+		case PieceCannon:
+			for dir := 0; dir < 4; dir++ {
+				_, screen := rayAttacks(sq, dir, occ)
+				if screen < 0 {
+					continue
+				}
+				if to := nearest(rayMasks[screen][dir].And(occ), dir); to >= 0 && enemy.Has(to) {
+					moves = append(moves, Move{From: sq, To: to})
+				}
+			}
+		case PieceLei:
+			genLeiCaptures(p, sq, &moves)
+		default:
+			// 其余棋子走法很少，生成后挑出落在敌子上的
+			tmp = tmp[:0]
+			p.genStepMoves(sq, &tmp)
+			for _, mv := range tmp {
+				if enemy.Has(mv.To) {
+					moves = append(moves, mv)
+				}
+			}
+		}
+	}
+	return p.filterLegal(moves)
+}
+
+// genStepMoves 非滑行棋子的走法
+func (p *Position) genStepMoves(sq int, moves *[]Move) {
+	switch p.Board.Squares[sq].Type() {
+	case PieceKnight:
+		genKnightMoves(p, sq, moves)
+	case PieceElephant:
+		genElephantMoves(p, sq, moves)
+	case PieceAdvisor:
+		genAdvisorMoves(p, sq, moves)
+	case PieceKing:
+		genKingMoves(p, sq, moves)
+	case PiecePawn:
+		genPawnMoves(p, sq, moves)
+	case PieceFeng:
+		genFengMoves(p, sq, moves)
+	case PieceWei:
+		genWeiMoves(p, sq, moves)
+	}
+}
+
+// checkZone[k] 走子后可能让 k 上的王被将军的格子（与规则无关的部分）：
+//   - k 所在的行和列：车炮直接将军，或让开/填上车炮的线路（炮架）
+//   - k 周围一圈：兵、檑、王的攻击位，也是檑吃子的“落单”邻格
+//   - 能跳到 k 的马的位置（马腿都在 k 周围一圈或同一行列上，已包含在前两项里）
+//
+// 锋的轨道随规则变化，在 GenerateChecks 里另外加上。
+// 一步走法的起点和终点都不在区域内时，不可能改变 k 是否被攻击。
+var (
+	checkZone     [NumSquares]Bitboard
+	checkZoneOnce sync.Once
+)
+
+func initCheckZone() {
+	for k := 0; k < NumSquares; k++ {
+		var z Bitboard
+		for dir := 0; dir < 4; dir++ {
+			z = z.Or(rayMasks[k][dir])
+		}
+		row, col := rowOf(k), colOf(k)
+		for _, d := range leiRingDirs {
+			if onBoard(row+d[0], col+d[1]) {
+				z.Set(indexOf(row+d[0], col+d[1]))
+			}
+		}
+		for _, ka := range knightAttackers[k] {
+			z.Set(ka.From)
+		}
+		checkZone[k] = z
+	}
+}
+
+// GenerateChecks 只生成走完后将军对方的合法走法，包括闪将（移开炮架、车前的子、马腿、
+// 檑旁的邻子）和填子成炮架；直接吃王的走法也算在内。
+// 先用 checkZone 排除不可能将军的走法，剩下的在副本上走一步，用 IsAttacked 确认。
+func (p *Position) GenerateChecks() []Move {
+	side := p.SideToMove
+	if side != Red && side != Black {
+		return nil
+	}
+	k := p.KingSquare(opposite(side))
+	if k < 0 {
+		return nil
+	}
+	checkZoneOnce.Do(initCheckZone)
+	zone := checkZone[k]
+	for _, r := range p.ActiveRules().fengTables().rails[k] {
+		zone.Set(r.From)
+		for _, sq := range r.Between {
+			zone.Set(sq)
+		}
+	}
+	if p.IsAttacked(k, side) {
+		// 对方走完还被将着（只有不合常规的局面会这样）：没挡住的走法都算，不能按区域排除
+		zone = Bitboard{^uint64(0), ^uint64(0), ^uint64(0)}
+	}
+
+	moves := p.GenerateLegalMoves()
+	out := moves[:0]
+	np := *p // 在副本上试走，不改动 p
+	for _, mv := range moves {
+		if target := p.Board.Squares[mv.To]; target != 0 && target.Type() == PieceKing {
+			out = append(out, mv)
+			continue
+		}
+		if !zone.Has(mv.From) && !zone.Has(mv.To) {
+			continue
+		}
+		u := np.doMove(mv)
+		check := np.IsAttacked(k, side)
+		np.undoMove(u)
+		if check {
+			out = append(out, mv)
+		}
+	}
+	return out
+}
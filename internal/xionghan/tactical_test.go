@@ -0,0 +1,123 @@
+package xionghan
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// 参照：合法走法里挑吃子的 / 走完后对方被将军（或王被吃）的
+func bruteTactical(p *Position) (captures, checks []Move) {
+	opp := opposite(p.SideToMove)
+	for _, mv := range p.GenerateLegalMoves() {
+		target := p.Board.Squares[mv.To]
+		if target != 0 && target.Side() == opp {
+			captures = append(captures, mv)
+		}
+		np, _ := p.ApplyMove(mv)
+		if target != 0 && target.Type() == PieceKing || np.IsInCheck(opp) {
+			checks = append(checks, mv)
+		}
+	}
+	return captures, checks
+}
+
+func compareTactical(t *testing.T, p *Position, label string) {
+	t.Helper()
+	wantCaps, wantChecks := bruteTactical(p)
+	for _, c := range []struct {
+		name      string
+		got, want []Move
+	}{
+		{"captures", p.GenerateCaptures(), wantCaps},
+		{"checks", p.GenerateChecks(), wantChecks},
+	} {
+		got, want := sortedMoves(c.got), sortedMoves(c.want)
+		if len(got) != len(want) {
+			t.Fatalf("%s: %d %s, want %d\n%v\n%v\n%s", label, len(got), c.name, len(want), got, want, p.Encode())
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%s: %s differ at %d: %+v vs %+v\n%s", label, c.name, i, got[i], want[i], p.Encode())
+			}
+		}
+	}
+}
+
+func TestTacticalMovesMatchBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(17))
+
+	odd := OnlineRules
+	odd.Name = "odd"
+	odd.WallRow = 5
+	odd.FengStep = 2
+	odd.FengStart = 1
+
+	for _, rules := range []*RuleSet{&OnlineRules, &odd} {
+		for i := 0; i < 300; i++ {
+			p := randomBoard(rng, 8+rng.Intn(60))
+			p.Rules = rules
+			compareTactical(t, p, rules.Name+" random board")
+		}
+	}
+
+	for game := 0; game < 5; game++ {
+		p := NewInitialPosition()
+		for ply := 0; ply < 120; ply++ {
+			compareTactical(t, p, "random game")
+			moves := p.GenerateLegalMoves()
+			if len(moves) == 0 || !p.KingExists(Red) || !p.KingExists(Black) {
+				break
+			}
+			p.MakeMove(moves[rng.Intn(len(moves))])
+		}
+	}
+}
+
+// 闪将：移开炮架、让开马腿都算将军
+func TestGenerateChecksFindsDiscoveredChecks(t *testing.T) {
+	bk := indexOf(1, 6)
+	cases := []struct {
+		name   string
+		pieces map[int]Piece
+		move   Move
+	}{
+		{
+			// 炮 (8,6) 与黑王之间有两个子，挪走一个就成了炮架将军
+			name: "cannon screen",
+			pieces: map[int]Piece{
+				bk:             makePiece(Black, PieceKing),
+				indexOf(8, 6):  makePiece(Red, PieceCannon),
+				indexOf(6, 6):  makePiece(Red, PieceRook),
+				indexOf(4, 6):  makePiece(Red, PieceWei),
+				indexOf(11, 5): makePiece(Red, PieceKing),
+			},
+			move: Move{From: indexOf(6, 6), To: indexOf(6, 5)},
+		},
+		{
+			// 马 (3,7) 的马腿 (2,7) 被自己的子堵住，让开后马跳 (1,6)
+			name: "knight leg",
+			pieces: map[int]Piece{
+				bk:             makePiece(Black, PieceKing),
+				indexOf(3, 7):  makePiece(Red, PieceKnight),
+				indexOf(2, 7):  makePiece(Red, PieceRook),
+				indexOf(11, 5): makePiece(Red, PieceKing),
+			},
+			move: Move{From: indexOf(2, 7), To: indexOf(2, 12)},
+		},
+	}
+	for _, c := range cases {
+		p := newTestPosition(Red, c.pieces)
+		if p.IsInCheck(Black) {
+			t.Fatalf("%s: black should not start in check", c.name)
+		}
+		found := false
+		for _, mv := range p.GenerateChecks() {
+			if mv.From == c.move.From && mv.To == c.move.To {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("%s: discovered check %s-%s not generated", c.name, SquareName(c.move.From), SquareName(c.move.To))
+		}
+	}
+}
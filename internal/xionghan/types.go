@@ -68,4 +68,20 @@ type Position struct {
 	Board      Board
 	SideToMove Side
 	Hash       uint64
+
+	// HalfmoveClock 自上次吃子以来走过的半回合数（用于未吃子步数限制）
+	HalfmoveClock int
+	// FullmoveNumber 回合数，从 1 开始，黑方走完后加一
+	FullmoveNumber int
+
+	// Rules 对局规则；nil 表示 OnlineRules
+	Rules *RuleSet
+
+	undo []undoRecord // MakeMove/UnmakeMove 的撤销栈
+
+	// 与 Board 同步的位棋盘和双方王所在格（没有王为 -1），由 doMove/undoMove 增量维护；
+	// indexed 为 false 时（如直接构造的 Position）按需扫描棋盘
+	bb      boardBits
+	kingSq  [2]int
+	indexed bool
 }
@@ -24,7 +24,7 @@ func TestHashInitializedFromInitialAndFEN(t *testing.T) {
 func TestApplyMoveHashIncrementalMatchesFullRecompute(t *testing.T) {
 	pos := NewInitialPosition()
 	for ply := 0; ply < 24; ply++ {
-		moves := pos.GenerateLegalMoves(true)
+		moves := pos.GenerateLegalMoves()
 		if len(moves) == 0 {
 			return
 		}
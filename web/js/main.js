@@ -9,6 +9,12 @@ function sqToRC(sq) {
     return { r: Math.floor(sq / COLS), c: sq % COLS };
 }
 
+// 坐标名，和棋谱坐标记法一致：列 a-m，红方底线为 1
+function sqName(sq) {
+    const { r, c } = sqToRC(sq);
+    return String.fromCharCode(97 + c) + (ROWS - r);
+}
+
 function rcToSq(r, c) {
     return r * COLS + c;
 }
@@ -44,6 +50,7 @@ let selectedSq = null;
 let movesFromSelected = []; // 当前选中棋子对应的所有可走棋（子集）
 let currentFen = ""; // ✅ 当前局面的 FEN 字符串（直接用后端给的）
 let lastMove = null; // 上一步走子 {from, to}
+let gameResult = null; // 后端判定的终局结果 { status, winner, reason }
 let moveCount = 0; // 当前对局累计步数（人类+AI）
 
 
@@ -164,7 +171,24 @@ function pieceSprite(info) {
 
 
 // ====== 渲染 ======
+const RESULT_REASON_TEXT = {
+    king_captured: "吃王",
+    no_legal_moves: "困毙",
+    perpetual_check: "长将判负",
+    move_limit: "未吃子步数到限",
+};
+
 function isGameOver() {
+    const statusEl = document.getElementById("gameStatus");
+    if (gameResult && gameResult.status && gameResult.status !== "ongoing") {
+        const why = RESULT_REASON_TEXT[gameResult.reason] || "";
+        let text = "和棋 (Draw)";
+        if (gameResult.status === "red_win") text = "红方胜 (Red Wins)";
+        if (gameResult.status === "black_win") text = "黑方胜 (Black Wins)";
+        if (statusEl) statusEl.innerText = why ? `${text} - ${why}` : text;
+        return true;
+    }
+
     let redKing = false;
     let blackKing = false;
     for (let i = 0; i < board.length; i++) {
@@ -173,8 +197,7 @@ function isGameOver() {
             else if (board[i].side === 1) blackKing = true;
         }
     }
-    
-    const statusEl = document.getElementById("gameStatus");
+
     if (!redKing) {
         if (statusEl) statusEl.innerText = "黑方胜 (Black Wins)";
         return true;
@@ -276,10 +299,9 @@ function addCircle(layer,x,y,r,color,width,opacity=1){
 
 
 function expandFen(fen) {
-    // 仅转换棋盘部分，不动行棋方
-    const parts = fen.split(" ");
+    // 仅转换棋盘部分，行棋方和步数字段原样保留
+    const parts = fen.trim().split(/\s+/);
     const rows = parts[0].split("/");
-    const stm = parts[1];
 
     const expandedRows = rows.map(row => {
         let out = "";
@@ -299,7 +321,7 @@ function expandFen(fen) {
         return out;
     });
 
-    return expandedRows.join("/") + " " + stm;
+    return [expandedRows.join("/"), ...parts.slice(1)].join(" ");
 }
 
 
@@ -417,6 +439,7 @@ async function tryResumeGame() {
 
         const data = await res.json();
         gameId = savedId;
+        gameResult = data;
         legalMoves = data.legal_moves || [];
         updateBoardFromFen(data.position);
         selectedSq = null;
@@ -447,6 +470,7 @@ async function newGame() {
 
         setSessionCookie("xionghan_game_id", gameId);
 
+        gameResult = null;
         legalMoves = data.legal_moves || [];
         updateBoardFromFen(data.position);
         selectedSq = null;
@@ -489,7 +513,8 @@ async function playMove(mv) {
             return;
         }
         const data = await res.json();
-        // 后端 PlayResponse：{ position, to_move, legal_moves, status }
+        // 后端 PlayResponse：{ position, to_move, legal_moves, status, winner, reason }
+        gameResult = data;
         legalMoves = data.legal_moves || [];
         updateBoardFromFen(data.position);
         selectedSq = null;
@@ -499,7 +524,6 @@ async function playMove(mv) {
         saveMoveCountToSession();
         updateMoveCountUI();
         renderBoard(mv);
-        // 你可以看 data.status 判断是否将死/和棋
     } catch (e) {
         console.error("play error", e);
     }
@@ -532,7 +556,9 @@ async function requestAiMove() {
                 max_depth: 2,
                 time_ms: 10000, // 给 MCTS 稍微多一点时间
                 use_mcts: useMcts,
-                mcts_simulations: mctsSims
+                mcts_simulations: mctsSims,
+                stream: true,
+                ponder: true
             })
         });
 
@@ -552,8 +578,17 @@ async function requestAiMove() {
             return;
         }
 
-        const data = await res.json();
-        
+        // 搜索进度（info 事件）边搜边显示，result 事件是最终结果
+        let data = null;
+        await readEventStream(res, (event, payload) => {
+            if (event === "info") updateUiStats(payload);
+            else if (event === "result") data = payload;
+        });
+        if (!data) {
+            console.error("ai_move: stream ended without a result");
+            return;
+        }
+
         // 更新胜率和搜索信息
         updateUiStats(data);
 
@@ -572,12 +607,65 @@ async function requestAiMove() {
     }
 }
 
+// 读取 SSE 响应（text/event-stream），每个事件回调 onEvent(event, JSON 数据)
+async function readEventStream(res, onEvent) {
+    const reader = res.body.getReader();
+    const decoder = new TextDecoder();
+    let buf = "";
+    for (;;) {
+        const { value, done } = await reader.read();
+        if (done) break;
+        buf += decoder.decode(value, { stream: true });
+        let sep;
+        while ((sep = buf.indexOf("\n\n")) >= 0) {
+            const block = buf.slice(0, sep);
+            buf = buf.slice(sep + 2);
+            let event = "message";
+            let dataText = "";
+            for (const line of block.split("\n")) {
+                if (line.startsWith("event: ")) event = line.slice(7);
+                else if (line.startsWith("data: ")) dataText += line.slice(6);
+            }
+            if (dataText) onEvent(event, JSON.parse(dataText));
+        }
+    }
+}
+
+// 下载当前对局的文本棋谱
+async function exportRecord() {
+    if (!gameId) return;
+    try {
+        const res = await fetch("/api/record", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ game_id: gameId })
+        });
+        if (!res.ok) {
+            console.error("record failed", res.status);
+            return;
+        }
+        const blob = new Blob([await res.text()], { type: "text/plain" });
+        const a = document.createElement("a");
+        a.href = URL.createObjectURL(blob);
+        a.download = `xionghan-${gameId}.txt`;
+        a.click();
+        URL.revokeObjectURL(a.href);
+    } catch (e) {
+        console.error("record error", e);
+    }
+}
+
 function updateUiStats(data) {
     if (data.win_prob !== undefined) {
         const winPct = (data.win_prob * 100).toFixed(1);
         const textEl = document.getElementById("winProbText");
         const barEl = document.getElementById("winBarFill");
-        if (textEl) textEl.innerText = winPct + "%";
+        if (textEl) {
+            // mate_in：AI 已证明 N 步内吃王（负数为 N 步内被吃王）
+            const mate = data.mate_in > 0 ? ` (必胜 ${data.mate_in} 步)`
+                : data.mate_in < 0 ? ` (必败 ${-data.mate_in} 步)` : "";
+            textEl.innerText = winPct + "%" + mate;
+        }
         if (barEl) barEl.style.width = winPct + "%";
     }
     
@@ -586,6 +674,15 @@ function updateUiStats(data) {
         const infoEl = document.getElementById("searchInfo");
         if (infoEl) infoEl.innerText = `${data.depth} / ${nodesStr}`;
     }
+
+    if (data.pv !== undefined) {
+        const pvEl = document.getElementById("pvText");
+        if (pvEl) {
+            pvEl.innerText = data.pv && data.pv.length > 0
+                ? `${data.pv.map(m => `${sqName(m.from)}-${sqName(m.to)}`).join(" ")} (${data.pv.length}, ${data.pv_score})`
+                : "-";
+        }
+    }
 }
 
 
@@ -605,5 +702,8 @@ document.addEventListener("DOMContentLoaded", () => {
         });
     }
 
+    const btnExport = document.getElementById("btnExportRecord");
+    if (btnExport) btnExport.addEventListener("click", exportRecord);
+
     tryResumeGame();
 });